@@ -21,10 +21,17 @@ type ChangeSetViewOpts struct {
 	ctldiff.TextDiffViewOpts
 }
 
+// ChangeNotes provides notes (e.g. preflight findings)
+// shown next to resources in the diff of changes
+type ChangeNotes interface {
+	Notes(res ctlres.Resource) []string
+}
+
 type ChangeSetView struct {
 	changeViews []ChangeView
 	maskRules   []ctlconf.DiffMaskRule
 	opts        ChangeSetViewOpts
+	notes       ChangeNotes
 
 	changesView *ChangesView
 }
@@ -32,7 +39,12 @@ type ChangeSetView struct {
 func NewChangeSetView(changeViews []ChangeView,
 	maskRules []ctlconf.DiffMaskRule, opts ChangeSetViewOpts) *ChangeSetView {
 
-	return &ChangeSetView{changeViews, maskRules, opts, nil}
+	return &ChangeSetView{changeViews, maskRules, opts, nil, nil}
+}
+
+// SetNotes configures notes printed below headers of changes
+func (v *ChangeSetView) SetNotes(notes ChangeNotes) {
+	v.notes = notes
 }
 
 func (v *ChangeSetView) Print(ui ui.UI) {
@@ -43,6 +55,7 @@ func (v *ChangeSetView) Print(ui ui.UI) {
 		for _, view := range v.changeViews {
 			textDiffView := ctldiff.NewTextDiffView(view.ConfigurableTextDiff(), v.maskRules, v.opts.TextDiffViewOpts)
 			ui.BeginLinef("@@ %s %s @@\n", applyOpCodeUI[view.ApplyOp()], view.Resource().Description())
+			v.printNotes(ui, view.Resource())
 			ui.PrintBlock([]byte(textDiffView.String()))
 		}
 	}
@@ -54,6 +67,15 @@ func (v *ChangeSetView) Print(ui ui.UI) {
 	}
 }
 
+func (v *ChangeSetView) printNotes(ui ui.UI, res ctlres.Resource) {
+	if v.notes == nil {
+		return
+	}
+	for _, note := range v.notes.Notes(res) {
+		ui.BeginLinef("@@ note: %s\n", note)
+	}
+}
+
 func (v *ChangeSetView) Summary() string {
 	return v.changesView.Summary() // assumes Print was used before
 }
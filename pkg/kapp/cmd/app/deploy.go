@@ -4,7 +4,6 @@
 package app
 
 import (
-	"context"
 	"fmt"
 	"io/fs"
 	"os"
@@ -51,6 +50,7 @@ type DeployOptions struct {
 	DeployFlags         DeployFlags
 	ResourceTypesFlags  ResourceTypesFlags
 	LabelFlags          LabelFlags
+	PreflightFlags      PreflightFlags
 
 	PreflightChecks *preflight.Registry
 
@@ -95,6 +95,7 @@ func NewDeployCmd(o *DeployOptions, flagsFactory cmdcore.FlagsFactory) *cobra.Co
 	o.ResourceTypesFlags.Set(cmd)
 	o.LabelFlags.Set(cmd)
 	o.PrevAppFlags.Set(cmd)
+	o.PreflightFlags.Set(cmd)
 	o.PreflightChecks.AddFlags(cmd.Flags())
 
 	return cmd
@@ -168,8 +169,8 @@ func (o *DeployOptions) Run() error {
 		return err
 	}
 
-	clusterChangeSet, clusterChangesGraph, hasNoChanges, changeSummary, err :=
-		o.calculateAndPresentChanges(existingResources, newResources, conf, supportObjs)
+	clusterChangeSet, clusterChanges, clusterChangesGraph, err :=
+		o.calculateChanges(existingResources, newResources, conf, supportObjs)
 	if err != nil {
 		if o.DiffFlags.UI && clusterChangesGraph != nil {
 			return o.presentDiffUI(clusterChangesGraph)
@@ -177,16 +178,66 @@ func (o *DeployOptions) Run() error {
 		return err
 	}
 
-	// Validate new resources _after_ presenting changes to make it easier to see big picture
-	err = prep.ValidateResources(newResources)
-	if err != nil {
-		return err
+	hasNoChanges := len(clusterChanges) == 0
+
+	// Preflight checks run before changes are presented so that
+	// their findings are shown next to resources in the diff
+	runPreflightChecks := o.PreflightChecks != nil && !hasNoChanges && !o.DiffFlags.Run && !o.DiffFlags.UI
+
+	var preflightFindings []preflight.Finding
+	var preflightErr error
+	var preflightRendererOpts preflight.HumanRendererOpts
+	var changeNotes ctlcap.ChangeNotes
+
+	// Validate new resources before running preflight checks against them, but
+	// report errors _after_ presenting changes to make it easier to see big picture
+	validationErr := prep.ValidateResources(newResources)
+
+	if runPreflightChecks && validationErr == nil {
+		preflightRendererOpts, err = o.PreflightFlags.HumanRendererOpts(o.PreflightChecks.SeverityLabels())
+		if err != nil {
+			return err
+		}
+
+		err = o.PreflightFlags.ConfigureDiscovery(o.depsFactory)
+		if err != nil {
+			return err
+		}
+
+		// Decision log stays open for post-apply checks
+		closeDecisionLog, err := o.PreflightFlags.ConfigureDecisionLog(o.PreflightChecks)
+		if err != nil {
+			return err
+		}
+		defer closeDecisionLog()
+
+		err = o.PreflightFlags.DumpGraph(clusterChangesGraph)
+		if err != nil {
+			return err
+		}
+
+		preflightFindings, preflightErr = o.PreflightChecks.Run(o.PreflightFlags.Context(), clusterChangesGraph)
+		changeNotes = preflight.NewDiffNotes(preflightFindings, preflightRendererOpts)
+	}
+
+	changeSummary := o.presentChanges(clusterChanges, conf, changeNotes)
+
+	if validationErr != nil {
+		return validationErr
 	}
 
 	if o.DiffFlags.UI {
 		return o.presentDiffUI(clusterChangesGraph)
 	}
 
+	// Preflight checks do not run when there is nothing to apply
+	if hasNoChanges && !o.DiffFlags.Run && o.PreflightChecks != nil {
+		err = o.PreflightFlags.CheckChanges(clusterChangesGraph)
+		if err != nil {
+			return err
+		}
+	}
+
 	if o.DiffFlags.Run || hasNoChanges {
 		o.writeAppMetadataToFile(app)
 
@@ -199,16 +250,45 @@ func (o *DeployOptions) Run() error {
 		return nil
 	}
 
-	if o.PreflightChecks != nil {
-		err = o.PreflightChecks.Run(context.Background(), clusterChangesGraph)
+	// Preflight confirmation replaces generic one to avoid asking twice
+	var confirmed bool
+
+	if runPreflightChecks {
+		promptSeverity, err := o.PreflightFlags.PromptSeverity()
 		if err != nil {
-			return fmt.Errorf("preflight checks failed: %w", err)
+			return err
+		}
+
+		// Output is empty when there are no (visible) findings unless only summary is shown
+		if output := preflight.NewHumanRenderer(preflightRendererOpts).Render(preflightFindings); len(output) > 0 {
+			o.ui.PrintBlock([]byte(output + "\n"))
+		}
+		if o.PreflightFlags.Timings {
+			PreflightStatsView{Stats: o.PreflightChecks.Stats()}.Print(o.ui)
+		}
+		if score := o.PreflightChecks.Score(); score.MaxScore != nil {
+			PreflightScoreView{Score: score}.Print(o.ui)
+		}
+		// Audit runs after enabled checks so that their stats are not affected
+		if o.PreflightFlags.Audit {
+			results, auditErr := o.PreflightChecks.Audit(o.PreflightFlags.Context(), clusterChangesGraph)
+			PreflightAuditView{Results: results, Err: auditErr}.Print(o.ui)
+		}
+		if preflightErr != nil {
+			return fmt.Errorf("preflight checks failed: %w", preflightErr)
+		}
+
+		confirmed, err = o.PreflightFlags.ConfirmFindings(preflightFindings, promptSeverity, o.ui)
+		if err != nil {
+			return err
 		}
 	}
 
-	err = o.ui.AskForConfirmation()
-	if err != nil {
-		return err
+	if !confirmed {
+		err = o.ui.AskForConfirmation()
+		if err != nil {
+			return err
+		}
 	}
 
 	// Track newly added GVs and GKs
@@ -255,12 +335,42 @@ func (o *DeployOptions) Run() error {
 		return err
 	}
 
+	// Avoid recording empty post-apply decisions when no check needs it
+	if o.PreflightChecks != nil && o.PreflightChecks.HasPhaseChecks(preflight.PhasePostApply) {
+		err = o.runPostApplyPreflightChecks(clusterChangesGraph)
+		if err != nil {
+			return err
+		}
+	}
+
 	if o.ApplyFlags.ExitStatus {
 		return DeployApplyExitStatus{hasNoChanges}
 	}
 	return nil
 }
 
+// runPostApplyPreflightChecks re-runs preflight checks registered
+// for post-apply phase. Since changes are already applied, failures
+// are reported as an error without reverting them.
+func (o *DeployOptions) runPostApplyPreflightChecks(graph *ctldgraph.ChangeGraph) error {
+	rendererOpts, err := o.PreflightFlags.HumanRendererOpts(o.PreflightChecks.SeverityLabels())
+	if err != nil {
+		return err
+	}
+
+	findings, err := o.PreflightChecks.RunPhase(o.PreflightFlags.Context(), graph, preflight.PhasePostApply)
+	if output := preflight.NewHumanRenderer(rendererOpts).Render(findings); len(output) > 0 {
+		o.ui.PrintBlock([]byte(output + "\n"))
+	}
+	if o.PreflightFlags.Timings && len(o.PreflightChecks.Stats()) > 0 {
+		PreflightStatsView{Stats: o.PreflightChecks.Stats()}.Print(o.ui)
+	}
+	if err != nil {
+		return fmt.Errorf("post-apply preflight checks failed (changes were applied and were not reverted): %w", err)
+	}
+	return nil
+}
+
 func (o *DeployOptions) newAndUsedGKs(newGKs []schema.GroupKind, app ctlapp.App) ([]schema.GroupKind, error) {
 	if o.DeployFlags.DisableGKScoping {
 		return []schema.GroupKind{}, nil
@@ -415,9 +525,9 @@ func (o *DeployOptions) existingResources(newResources []ctlres.Resource,
 	return resourceFilter.Apply(existingResources), o.existingPodResources(existingResources), nil
 }
 
-func (o *DeployOptions) calculateAndPresentChanges(existingResources,
+func (o *DeployOptions) calculateChanges(existingResources,
 	newResources []ctlres.Resource, conf ctlconf.Conf, supportObjs FactorySupportObjs) (
-	ctlcap.ClusterChangeSet, *ctldgraph.ChangeGraph, bool, string, error) {
+	ctlcap.ClusterChangeSet, []*ctlcap.ClusterChange, *ctldgraph.ChangeGraph, error) {
 
 	var clusterChangeSet ctlcap.ClusterChangeSet
 
@@ -427,19 +537,19 @@ func (o *DeployOptions) calculateAndPresentChanges(existingResources,
 
 		err := ctldiff.NewRenewableResources(existingResources, newResources).Prepare()
 		if err != nil {
-			return clusterChangeSet, nil, false, "", err
+			return clusterChangeSet, nil, nil, err
 		}
 
 		changes, err := ctldiff.NewChangeSetWithVersionedRs(
 			existingResources, newResources, conf.TemplateRules(),
 			o.DiffFlags.ChangeSetOpts, changeFactory).Calculate()
 		if err != nil {
-			return clusterChangeSet, nil, false, "", err
+			return clusterChangeSet, nil, nil, err
 		}
 
 		diffFilter, err := o.DiffFlags.DiffFilter()
 		if err != nil {
-			return clusterChangeSet, nil, false, "", err
+			return clusterChangeSet, nil, nil, err
 		}
 
 		changes = diffFilter.Apply(changes)
@@ -462,20 +572,26 @@ func (o *DeployOptions) calculateAndPresentChanges(existingResources,
 	clusterChanges, clusterChangesGraph, err := clusterChangeSet.Calculate()
 	if err != nil {
 		// Return graph for inspection
-		return clusterChangeSet, clusterChangesGraph, false, "", err
+		return clusterChangeSet, nil, clusterChangesGraph, err
 	}
 
-	var changesSummary string
+	return clusterChangeSet, clusterChanges, clusterChangesGraph, nil
+}
+
+// presentChanges prints cluster changes (with notes, if any)
+// and returns their summary
+func (o *DeployOptions) presentChanges(clusterChanges []*ctlcap.ClusterChange,
+	conf ctlconf.Conf, notes ctlcap.ChangeNotes) string {
 
-	{ // Present cluster changes in UI
-		changeViews := ctlcap.ClusterChangesAsChangeViews(clusterChanges)
-		changeSetView := ctlcap.NewChangeSetView(
-			changeViews, conf.DiffMaskRules(), o.DiffFlags.ChangeSetViewOpts)
-		changeSetView.Print(o.ui)
-		changesSummary = changeSetView.Summary()
+	changeViews := ctlcap.ClusterChangesAsChangeViews(clusterChanges)
+	changeSetView := ctlcap.NewChangeSetView(
+		changeViews, conf.DiffMaskRules(), o.DiffFlags.ChangeSetViewOpts)
+	if notes != nil {
+		changeSetView.SetNotes(notes)
 	}
+	changeSetView.Print(o.ui)
 
-	return clusterChangeSet, clusterChangesGraph, (len(clusterChanges) == 0), changesSummary, err
+	return changeSetView.Summary()
 }
 
 func (o *DeployOptions) existingPodResources(existingResources []ctlres.Resource) []ctlres.Resource {
@@ -0,0 +1,87 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"fmt"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	uitable "github.com/cppforlife/go-cli-ui/ui/table"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+)
+
+type PreflightAuditView struct {
+	Results []preflight.AuditResult
+	// Err is returned by Audit if checks could not be audited
+	Err error
+}
+
+func (v PreflightAuditView) Print(ui ui.UI) {
+	table := uitable.Table{
+		Title:   "Preflight audit",
+		Content: "preflight checks",
+
+		Header: []uitable.Header{
+			uitable.NewHeader("Check"),
+			uitable.NewHeader("Enabled"),
+			uitable.NewHeader("Result"),
+			uitable.NewHeader("Errors"),
+			uitable.NewHeader("Warnings"),
+			uitable.NewHeader("Info"),
+			uitable.NewHeader("Note"),
+		},
+	}
+
+	var numFailing int
+
+	for _, result := range v.Results {
+		var numErrors, numWarnings, numInfos int
+		for _, finding := range result.Findings {
+			switch finding.Severity {
+			case preflight.SeverityError:
+				numErrors++
+			case preflight.SeverityInfo:
+				numInfos++
+			default:
+				numWarnings++
+			}
+		}
+
+		status := result.Status()
+		if status == preflight.CheckStatusFailed || status == preflight.CheckStatusError {
+			numFailing++
+		}
+
+		table.Rows = append(table.Rows, []uitable.Value{
+			uitable.NewValueString(result.Name),
+			uitable.NewValueBool(result.Enabled),
+			uitable.NewValueString(status),
+			uitable.NewValueInt(numErrors),
+			uitable.NewValueInt(numWarnings),
+			uitable.NewValueInt(numInfos),
+			uitable.NewValueString(v.note(result)),
+		})
+	}
+
+	table.Notes = []string{
+		fmt.Sprintf("%d of %d check(s) would fail if enabled", numFailing, len(v.Results)),
+		"Audit results do not affect the outcome of enabled preflight checks",
+	}
+	if v.Err != nil {
+		table.Notes = append(table.Notes, fmt.Sprintf("Audit was incomplete: %s", v.Err))
+	}
+
+	ui.PrintTable(table)
+}
+
+func (v PreflightAuditView) note(result preflight.AuditResult) string {
+	switch {
+	case result.Skipped:
+		return fmt.Sprintf("Skipped: %s", result.SkipReason)
+	case result.Err != nil:
+		return result.Err.Error()
+	default:
+		return ""
+	}
+}
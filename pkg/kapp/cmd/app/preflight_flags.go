@@ -0,0 +1,177 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/spf13/cobra"
+	cmdcore "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/core"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	"golang.org/x/term"
+)
+
+type PreflightFlags struct {
+	Color          string
+	Timings        bool
+	SummaryOnly    bool
+	Quiet          bool
+	DiscoveryFile  string
+	DumpGraphFile  string
+	Prompt         string
+	RequireChanges bool
+	Audit          bool
+
+	DecisionLog       string
+	DecisionLogRedact []string
+}
+
+func (s *PreflightFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&s.Color, "preflight-color", string(preflight.ColorModeAuto),
+		"Set color output of preflight check results (auto, always, never); auto honors NO_COLOR")
+	cmd.Flags().BoolVar(&s.SummaryOnly, "preflight-summary-only", false,
+		"Only show number of preflight check findings by severity instead of individual findings")
+	cmd.Flags().BoolVar(&s.Quiet, "preflight-quiet", false, "Do not show informational preflight check findings")
+	cmd.Flags().StringVar(&s.DiscoveryFile, "preflight-discovery-file", "",
+		"Use API resources listed in a file (output of 'kubectl api-resources') instead of cluster discovery for preflight checks")
+	cmd.Flags().StringVar(&s.DumpGraphFile, "preflight-dump-graph", "",
+		"Write change graph evaluated by preflight checks to a file (YAML) for debugging")
+	cmd.Flags().StringVar(&s.Prompt, "preflight-prompt", "",
+		"Ask for confirmation when preflight checks report findings of at least this severity (info, warning, error) and stdin is a terminal")
+	cmd.Flags().BoolVar(&s.RequireChanges, "preflight-require-changes", false,
+		"Fail preflight checks when there are no changes to apply (usually caused by wrong paths or templates rendering no resources)")
+	cmd.Flags().BoolVar(&s.Timings, "preflight-timings", false, "Show duration and number of API calls of each preflight check")
+	cmd.Flags().BoolVar(&s.Audit, "preflight-audit", false,
+		"Also run all preflight checks (including disabled ones) and show whether they would pass; does not affect enabled preflight checks")
+	cmd.Flags().StringVar(&s.DecisionLog, "preflight-decision-log", "",
+		"Append a JSON line recording actor, time, config and outcome of each preflight run to a file ('-' for stdout); "+
+			"actor is taken from KAPP_PREFLIGHT_ACTOR or the current user")
+	cmd.Flags().StringSliceVar(&s.DecisionLogRedact, "preflight-decision-log-redact", nil,
+		"Keys of preflight config and deploy context whose values are not recorded in the decision log (can be specified multiple times)")
+}
+
+// ConfigureDecisionLog configures registry to write decision records to the
+// decision log if one was specified. Returned function closes the log file.
+func (s *PreflightFlags) ConfigureDecisionLog(registry *preflight.Registry) (func(), error) {
+	opts := preflight.DecisionLogOpts{RedactConfigKeys: s.DecisionLogRedact}
+
+	switch s.DecisionLog {
+	case "":
+		return func() {}, nil
+
+	case "-":
+		registry.SetDecisionLog(os.Stdout, opts)
+		return func() {}, nil
+
+	default:
+		file, err := os.OpenFile(s.DecisionLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("Opening preflight decision log: %w", err)
+		}
+		registry.SetDecisionLog(file, opts)
+		return func() { file.Close() }, nil
+	}
+}
+
+// Context returns context for running preflight checks
+// that identifies actor recorded in the decision log
+func (s *PreflightFlags) Context() context.Context {
+	actor := os.Getenv("KAPP_PREFLIGHT_ACTOR")
+	if len(actor) == 0 {
+		if currentUser, err := user.Current(); err == nil {
+			actor = currentUser.Username
+		}
+	}
+	return preflight.ContextWithActor(context.Background(), actor)
+}
+
+// ConfigureDiscovery configures depsFactory to use
+// discovery file if one was specified
+func (s *PreflightFlags) ConfigureDiscovery(depsFactory cmdcore.DepsFactory) error {
+	if len(s.DiscoveryFile) == 0 {
+		return nil
+	}
+
+	source, err := cmdcore.NewFileDiscoverySource(s.DiscoveryFile)
+	if err != nil {
+		return err
+	}
+
+	depsFactory.ConfigureDiscoverySource(source)
+	return nil
+}
+
+// CheckChanges returns an error if changes are required
+// but change graph does not include any changes
+func (s *PreflightFlags) CheckChanges(changeGraph *ctldgraph.ChangeGraph) error {
+	if s.RequireChanges && len(changeGraph.All()) == 0 {
+		return fmt.Errorf("preflight checks failed: change set is empty, there is nothing to apply (--preflight-require-changes)")
+	}
+	return nil
+}
+
+// DumpGraph writes change graph to dump graph file if one was specified
+func (s *PreflightFlags) DumpGraph(changeGraph *ctldgraph.ChangeGraph) error {
+	if len(s.DumpGraphFile) == 0 {
+		return nil
+	}
+	return preflight.WriteGraphDump(s.DumpGraphFile, changeGraph)
+}
+
+// PromptSeverity returns severity of findings that require
+// confirmation, or empty severity if prompting is not enabled
+func (s *PreflightFlags) PromptSeverity() (preflight.Severity, error) {
+	if len(s.Prompt) == 0 {
+		return "", nil
+	}
+	severity, err := preflight.NewSeverity(s.Prompt)
+	if err != nil {
+		return "", fmt.Errorf("Parsing --preflight-prompt: %w", err)
+	}
+	return severity, nil
+}
+
+// ConfirmFindings asks user to confirm continuation if there are findings of
+// at least prompt severity. Returns true if user was asked and confirmed.
+// Non-interactive runs (no terminal or --yes) are never asked.
+func (s *PreflightFlags) ConfirmFindings(findings []preflight.Finding, severity preflight.Severity, ui ui.UI) (bool, error) {
+	if len(severity) == 0 || !ui.IsInteractive() || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false, nil
+	}
+
+	count := preflight.CountAtLeast(findings, severity)
+	if count == 0 {
+		return false, nil
+	}
+
+	ui.PrintLinef("Preflight checks reported %d finding(s) with severity %s or higher", count, severity)
+
+	err := ui.AskForConfirmation()
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *PreflightFlags) HumanRendererOpts(severityLabels preflight.SeverityLabels) (preflight.HumanRendererOpts, error) {
+	colorMode, err := preflight.NewColorMode(s.Color)
+	if err != nil {
+		return preflight.HumanRendererOpts{}, err
+	}
+
+	opts := preflight.HumanRendererOpts{Color: colorMode, SummaryOnly: s.SummaryOnly,
+		Quiet: s.Quiet, SeverityLabels: severityLabels}
+
+	// Only wrap when writing to a terminal so that logs keep full lines
+	if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+		opts.Width = width
+	}
+
+	return opts, nil
+}
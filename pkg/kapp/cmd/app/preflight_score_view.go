@@ -0,0 +1,52 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	uitable "github.com/cppforlife/go-cli-ui/ui/table"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+)
+
+type PreflightScoreView struct {
+	Score preflight.Score
+}
+
+func (v PreflightScoreView) Print(ui ui.UI) {
+	table := uitable.Table{
+		Title:   "Preflight score",
+		Content: "preflight checks",
+
+		Header: []uitable.Header{
+			uitable.NewHeader("Check"),
+			uitable.NewHeader("Findings"),
+			uitable.NewHeader("Weight"),
+			uitable.NewHeader("Score"),
+		},
+	}
+
+	for _, checkScore := range v.Score.Checks {
+		table.Rows = append(table.Rows, []uitable.Value{
+			uitable.NewValueString(checkScore.Name),
+			uitable.NewValueInt(checkScore.Findings),
+			uitable.NewValueString(formatScore(checkScore.Weight)),
+			uitable.NewValueString(formatScore(checkScore.Score)),
+		})
+	}
+
+	total := fmt.Sprintf("Total: %s", formatScore(v.Score.Total))
+	if v.Score.MaxScore != nil {
+		total += fmt.Sprintf(" (maximum %s)", formatScore(*v.Score.MaxScore))
+	}
+	table.Notes = []string{total}
+
+	ui.PrintTable(table)
+}
+
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'g', -1, 64)
+}
@@ -0,0 +1,64 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	uitable "github.com/cppforlife/go-cli-ui/ui/table"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+)
+
+type PreflightStatsView struct {
+	Stats []preflight.CheckStats
+}
+
+func (v PreflightStatsView) Print(ui ui.UI) {
+	table := uitable.Table{
+		Title:   "Preflight check timings",
+		Content: "preflight checks",
+
+		Header: []uitable.Header{
+			uitable.NewHeader("Check"),
+			uitable.NewHeader("Duration"),
+			uitable.NewHeader("API calls"),
+			uitable.NewHeader("API calls by request"),
+			uitable.NewHeader("Note"),
+		},
+	}
+
+	var totalDuration time.Duration
+	var totalCalls int
+
+	for _, stats := range v.Stats {
+		var calls []string
+		for _, call := range stats.APICalls.Sorted() {
+			calls = append(calls, fmt.Sprintf("%s: %d", call, stats.APICalls[call]))
+		}
+
+		table.Rows = append(table.Rows, []uitable.Value{
+			uitable.NewValueString(stats.Name),
+			uitable.NewValueString(stats.Duration.Round(time.Millisecond).String()),
+			uitable.NewValueInt(stats.APICalls.Total()),
+			uitable.NewValueStrings(calls),
+			uitable.NewValueString(v.note(stats)),
+		})
+
+		totalDuration += stats.Duration
+		totalCalls += stats.APICalls.Total()
+	}
+
+	table.Notes = []string{fmt.Sprintf("Total: %s, %d API call(s)", totalDuration.Round(time.Millisecond), totalCalls)}
+
+	ui.PrintTable(table)
+}
+
+func (v PreflightStatsView) note(stats preflight.CheckStats) string {
+	if stats.Skipped {
+		return fmt.Sprintf("Skipped: %s", stats.SkipReason)
+	}
+	return ""
+}
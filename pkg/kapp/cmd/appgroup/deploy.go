@@ -36,6 +36,7 @@ type DeployAppFlags struct {
 	DeleteApplyFlags    cmdapp.ApplyFlags
 	DeployFlags         cmdapp.DeployFlags
 	LabelFlags          cmdapp.LabelFlags
+	PreflightFlags      cmdapp.PreflightFlags
 }
 
 func NewDeployOptions(ui ui.UI, depsFactory cmdcore.DepsFactory, logger logger.Logger, preflights *preflight.Registry) *DeployOptions {
@@ -58,6 +59,7 @@ func NewDeployCmd(o *DeployOptions, flagsFactory cmdcore.FlagsFactory) *cobra.Co
 	o.AppFlags.DeleteApplyFlags.SetWithDefaults("delete", cmdapp.ApplyFlagsDeleteDefaults, cmd)
 	o.AppFlags.DeployFlags.Set(cmd)
 	o.AppFlags.LabelFlags.Set(cmd)
+	o.AppFlags.PreflightFlags.Set(cmd)
 	o.PreflightChecks.AddFlags(cmd.Flags())
 	return cmd
 }
@@ -162,6 +164,7 @@ func (o *DeployOptions) deployApp(app appGroupApp) error {
 	deployOpts.ResourceFilterFlags = o.AppFlags.ResourceFilterFlags
 	deployOpts.ApplyFlags = o.AppFlags.ApplyFlags
 	deployOpts.DeployFlags = o.AppFlags.DeployFlags
+	deployOpts.PreflightFlags = o.AppFlags.PreflightFlags
 
 	deployOpts.LabelFlags = o.AppFlags.LabelFlags
 	deployOpts.LabelFlags.Labels = append(
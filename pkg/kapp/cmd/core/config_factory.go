@@ -6,6 +6,7 @@ package core
 import (
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"strings"
 
@@ -18,6 +19,7 @@ type ConfigFactory interface {
 	ConfigureContextResolver(func() (string, error))
 	ConfigureYAMLResolver(func() (string, error))
 	ConfigureClient(float32, int)
+	ConfigureTransportWrapper(func(http.RoundTripper) http.RoundTripper)
 	RESTConfig() (*rest.Config, error)
 	DefaultNamespace() (string, error)
 }
@@ -29,6 +31,8 @@ type ConfigFactoryImpl struct {
 
 	qps   float32
 	burst int
+
+	transportWrapperFunc func(http.RoundTripper) http.RoundTripper
 }
 
 var _ ConfigFactory = &ConfigFactoryImpl{}
@@ -54,6 +58,10 @@ func (f *ConfigFactoryImpl) ConfigureClient(qps float32, burst int) {
 	f.burst = burst
 }
 
+func (f *ConfigFactoryImpl) ConfigureTransportWrapper(wrapperFunc func(http.RoundTripper) http.RoundTripper) {
+	f.transportWrapperFunc = wrapperFunc
+}
+
 func (f *ConfigFactoryImpl) RESTConfig() (*rest.Config, error) {
 	isExplicitYAMLConfig, config, err := f.clientConfig()
 	if err != nil {
@@ -83,6 +91,10 @@ func (f *ConfigFactoryImpl) RESTConfig() (*rest.Config, error) {
 		restConfig.Burst = f.burst
 	}
 
+	if f.transportWrapperFunc != nil {
+		restConfig.Wrap(f.transportWrapperFunc)
+	}
+
 	return restConfig, nil
 }
 
@@ -6,6 +6,7 @@ package core
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"sync"
 
 	"github.com/cppforlife/go-cli-ui/ui"
@@ -23,13 +24,16 @@ type DepsFactory interface {
 	DynamicClient(opts DynamicClientOpts) (dynamic.Interface, error)
 	CoreClient() (kubernetes.Interface, error)
 	RESTMapper() (meta.RESTMapper, error)
+	APIGroupResources(ctx context.Context) ([]*restmapper.APIGroupResources, error)
 	ConfigureWarnings(warnings bool)
+	ConfigureDiscoverySource(source DiscoverySource)
 }
 
 type DepsFactoryImpl struct {
 	configFactory   ConfigFactory
 	ui              ui.UI
 	printTargetOnce *sync.Once
+	discoverySource DiscoverySource
 
 	Warnings bool
 }
@@ -89,6 +93,14 @@ func (f *DepsFactoryImpl) CoreClient() (kubernetes.Interface, error) {
 }
 
 func (f *DepsFactoryImpl) RESTMapper() (meta.RESTMapper, error) {
+	if f.discoverySource != nil {
+		groups, err := f.discoverySource.APIGroupResources()
+		if err != nil {
+			return nil, err
+		}
+		return restmapper.NewDiscoveryRESTMapper(groups), nil
+	}
+
 	config, err := f.configFactory.RESTConfig()
 	if err != nil {
 		return nil, err
@@ -107,10 +119,71 @@ func (f *DepsFactoryImpl) RESTMapper() (meta.RESTMapper, error) {
 	return mapper, nil
 }
 
+// APIGroupResources returns API resources served by the cluster
+// (or provided by the configured DiscoverySource). Discovery requests
+// are made with ctx. If some groups could not be discovered, resources
+// of other groups are returned with discovery.ErrGroupDiscoveryFailed.
+func (f *DepsFactoryImpl) APIGroupResources(ctx context.Context) ([]*restmapper.APIGroupResources, error) {
+	if f.discoverySource != nil {
+		return f.discoverySource.APIGroupResources()
+	}
+
+	config, err := f.configFactory.RESTConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// copy to avoid mutating the passed-in config
+	cpConfig := rest.CopyConfig(config)
+	cpConfig.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return contextRoundTripper{ctx: ctx, delegate: rt}
+	})
+
+	disc, err := discovery.NewDiscoveryClientForConfig(cpConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	f.printTarget(config)
+
+	groups, resourceLists, err := disc.ServerGroupsAndResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, err
+	}
+
+	resourceListsByGV := map[string]*metav1.APIResourceList{}
+	for _, resourceList := range resourceLists {
+		resourceListsByGV[resourceList.GroupVersion] = resourceList
+	}
+
+	var result []*restmapper.APIGroupResources
+
+	for _, group := range groups {
+		groupResources := &restmapper.APIGroupResources{
+			Group:              *group,
+			VersionedResources: map[string][]metav1.APIResource{},
+		}
+		for _, version := range group.Versions {
+			if resourceList, found := resourceListsByGV[version.GroupVersion]; found {
+				groupResources.VersionedResources[version.Version] = resourceList.APIResources
+			}
+		}
+		result = append(result, groupResources)
+	}
+
+	return result, err
+}
+
 func (f *DepsFactoryImpl) ConfigureWarnings(warnings bool) {
 	f.Warnings = warnings
 }
 
+// ConfigureDiscoverySource makes RESTMapper use provided
+// source instead of discovering API resources from the cluster
+func (f *DepsFactoryImpl) ConfigureDiscoverySource(source DiscoverySource) {
+	f.discoverySource = source
+}
+
 func (f *DepsFactoryImpl) printTarget(config *rest.Config) {
 	f.printTargetOnce.Do(func() {
 		nodesDesc := f.summarizeNodes(config)
@@ -162,6 +235,17 @@ func (f *DepsFactoryImpl) newWarningHandler() rest.WarningHandler {
 	return warningWriter
 }
 
+// contextRoundTripper makes requests with a context since
+// discovery client does not accept one (it uses context.TODO)
+type contextRoundTripper struct {
+	ctx      context.Context
+	delegate http.RoundTripper
+}
+
+func (rt contextRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return rt.delegate.RoundTrip(req.WithContext(rt.ctx))
+}
+
 type uiWriter struct {
 	ui ui.UI
 }
@@ -0,0 +1,89 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package core_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/stretchr/testify/require"
+	cmdcore "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/core"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDepsFactoryAPIGroupResources(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var resp interface{}
+
+		switch req.URL.Path {
+		case "/api":
+			resp = metav1.APIVersions{Versions: []string{"v1"}}
+		case "/api/v1":
+			resp = metav1.APIResourceList{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "pods", Namespaced: true, Kind: "Pod"}}}
+		case "/apis":
+			resp = metav1.APIGroupList{}
+		default:
+			http.NotFound(w, req)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(resp))
+	}))
+	defer server.Close()
+
+	counter := preflight.NewAPICallCounter()
+
+	configFactory := cmdcore.NewConfigFactoryImpl()
+	configFactory.ConfigurePathResolver(func() (string, error) { return "", nil })
+	configFactory.ConfigureContextResolver(func() (string, error) { return "", nil })
+	configFactory.ConfigureYAMLResolver(func() (string, error) {
+		return `
+apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: ` + server.URL + `
+contexts:
+- name: test
+  context:
+    cluster: test
+current-context: test
+`, nil
+	})
+	configFactory.ConfigureTransportWrapper(counter.WrapTransport)
+
+	depsFactory := cmdcore.NewDepsFactoryImpl(configFactory, ui.NewNoopUI())
+
+	groups, err := depsFactory.APIGroupResources(preflight.ContextWithCheckName(context.Background(), "someCheck"))
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	require.Equal(t, "pods", groups[0].VersionedResources["v1"][0].Name)
+
+	// Discovery requests (/api, /api/v1 and /apis) are attributed to the check running with the context
+	require.Equal(t, preflight.APICalls{{Verb: "get", Resource: "discovery"}: 3}, counter.Take("someCheck"))
+
+	path := filepath.Join(t.TempDir(), "api-resources.txt")
+	require.NoError(t, os.WriteFile(path, []byte(""+
+		"NAME      APIVERSION       NAMESPACED   KIND\n"+
+		"widgets   example.com/v1   true         Widget\n"), 0600))
+
+	source, err := cmdcore.NewFileDiscoverySource(path)
+	require.NoError(t, err)
+
+	depsFactory.ConfigureDiscoverySource(source)
+
+	groups, err = depsFactory.APIGroupResources(context.Background())
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	require.Equal(t, "example.com", groups[0].Group.Name)
+}
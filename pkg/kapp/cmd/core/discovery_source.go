@@ -0,0 +1,185 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/restmapper"
+)
+
+// DiscoverySource provides information about API resources
+// (their versions and scope) served by a cluster. It allows
+// DepsFactory.RESTMapper to work without cluster connectivity.
+type DiscoverySource interface {
+	APIGroupResources() ([]*restmapper.APIGroupResources, error)
+}
+
+// FileDiscoverySource is a DiscoverySource backed by output of
+// 'kubectl api-resources' (optionally with '-o wide') saved to a file
+type FileDiscoverySource struct {
+	groups []*restmapper.APIGroupResources
+}
+
+var _ DiscoverySource = &FileDiscoverySource{}
+
+func NewFileDiscoverySource(path string) (*FileDiscoverySource, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Reading discovery file: %w", err)
+	}
+
+	groups, err := parseAPIResourcesTable(bs)
+	if err != nil {
+		return nil, fmt.Errorf("Parsing discovery file %q: %w", path, err)
+	}
+
+	return &FileDiscoverySource{groups}, nil
+}
+
+func (s *FileDiscoverySource) APIGroupResources() ([]*restmapper.APIGroupResources, error) {
+	return s.groups, nil
+}
+
+const (
+	apiResourcesNameColumn       = "NAME"
+	apiResourcesAPIVersionColumn = "APIVERSION"
+	apiResourcesNamespacedColumn = "NAMESPACED"
+	apiResourcesKindColumn       = "KIND"
+	apiResourcesVerbsColumn      = "VERBS"
+)
+
+// parseAPIResourcesTable parses 'kubectl api-resources' table output.
+// Columns are located based on header positions since some cells
+// (e.g. SHORTNAMES) may be empty.
+func parseAPIResourcesTable(data []byte) ([]*restmapper.APIGroupResources, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	var columns []apiResourcesColumn
+	var result []*restmapper.APIGroupResources
+	groupsByName := map[string]*restmapper.APIGroupResources{}
+
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		if columns == nil {
+			columns = newAPIResourcesColumns(line)
+			for _, required := range []string{apiResourcesNameColumn, apiResourcesAPIVersionColumn, apiResourcesNamespacedColumn, apiResourcesKindColumn} {
+				if !hasAPIResourcesColumn(columns, required) {
+					return nil, fmt.Errorf("Expected header to include column %s", required)
+				}
+			}
+			continue
+		}
+
+		cells := map[string]string{}
+		for _, col := range columns {
+			cells[col.Name] = col.Value(line)
+		}
+
+		gv, err := schema.ParseGroupVersion(cells[apiResourcesAPIVersionColumn])
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: %w", lineNum, err)
+		}
+		if len(cells[apiResourcesNameColumn]) == 0 || len(cells[apiResourcesKindColumn]) == 0 || len(gv.Version) == 0 {
+			return nil, fmt.Errorf("Line %d: Expected name, kind and API version to be present", lineNum)
+		}
+
+		group, found := groupsByName[gv.Group]
+		if !found {
+			group = &restmapper.APIGroupResources{
+				Group: metav1.APIGroup{
+					Name: gv.Group,
+					// First listed version is considered preferred
+					PreferredVersion: metav1.GroupVersionForDiscovery{GroupVersion: gv.String(), Version: gv.Version},
+				},
+				VersionedResources: map[string][]metav1.APIResource{},
+			}
+			groupsByName[gv.Group] = group
+			result = append(result, group)
+		}
+		if _, found := group.VersionedResources[gv.Version]; !found {
+			group.Group.Versions = append(group.Group.Versions, metav1.GroupVersionForDiscovery{GroupVersion: gv.String(), Version: gv.Version})
+		}
+
+		group.VersionedResources[gv.Version] = append(group.VersionedResources[gv.Version], metav1.APIResource{
+			Name:       cells[apiResourcesNameColumn],
+			Namespaced: cells[apiResourcesNamespacedColumn] == "true",
+			Kind:       cells[apiResourcesKindColumn],
+			Verbs:      parseAPIResourcesVerbs(cells[apiResourcesVerbsColumn]),
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if columns == nil {
+		return nil, fmt.Errorf("Expected header line")
+	}
+
+	return result, nil
+}
+
+type apiResourcesColumn struct {
+	Name  string
+	Start int
+	// End is -1 for the last column
+	End int
+}
+
+func newAPIResourcesColumns(header string) []apiResourcesColumn {
+	var columns []apiResourcesColumn
+
+	for i := 0; i < len(header); {
+		if header[i] == ' ' {
+			i++
+			continue
+		}
+		end := strings.IndexByte(header[i:], ' ')
+		if end < 0 {
+			end = len(header) - i
+		}
+		columns = append(columns, apiResourcesColumn{Name: header[i : i+end], Start: i, End: -1})
+		i += end
+	}
+
+	for i := range columns[:len(columns)-1] {
+		columns[i].End = columns[i+1].Start
+	}
+
+	return columns
+}
+
+func (c apiResourcesColumn) Value(line string) string {
+	if c.Start >= len(line) {
+		return ""
+	}
+	if c.End < 0 || c.End > len(line) {
+		return strings.TrimSpace(line[c.Start:])
+	}
+	return strings.TrimSpace(line[c.Start:c.End])
+}
+
+func hasAPIResourcesColumn(columns []apiResourcesColumn, name string) bool {
+	for _, col := range columns {
+		if col.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAPIResourcesVerbs parses verbs formatted as [get list watch]
+func parseAPIResourcesVerbs(verbs string) metav1.Verbs {
+	return strings.Fields(strings.Trim(verbs, "[]"))
+}
@@ -0,0 +1,69 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package core_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cmdcore "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/core"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/restmapper"
+)
+
+func TestFileDiscoverySource(t *testing.T) {
+	// Output of 'kubectl api-resources -o wide' (trimmed)
+	apiResources := `
+NAME          SHORTNAMES   APIVERSION                     NAMESPACED   KIND          VERBS                          CATEGORIES
+namespaces    ns           v1                             false        Namespace     [create delete get list]
+pods          po           v1                             true         Pod           [create delete get list]       all
+deployments   deploy       apps/v1                        true         Deployment    [create delete get list]       all
+widgets                    example.com/v1alpha1           true         Widget        [get list]
+`
+
+	path := filepath.Join(t.TempDir(), "api-resources.txt")
+	require.NoError(t, os.WriteFile(path, []byte(apiResources), 0600))
+
+	source, err := cmdcore.NewFileDiscoverySource(path)
+	require.NoError(t, err)
+
+	groups, err := source.APIGroupResources()
+	require.NoError(t, err)
+
+	mapper := restmapper.NewDiscoveryRESTMapper(groups)
+
+	testCases := []struct {
+		gk            schema.GroupKind
+		expectedGVR   schema.GroupVersionResource
+		expectedScope meta.RESTScopeName
+	}{
+		{schema.GroupKind{Kind: "Namespace"}, schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}, meta.RESTScopeNameRoot},
+		{schema.GroupKind{Kind: "Pod"}, schema.GroupVersionResource{Version: "v1", Resource: "pods"}, meta.RESTScopeNameNamespace},
+		{schema.GroupKind{Group: "apps", Kind: "Deployment"}, schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, meta.RESTScopeNameNamespace},
+		{schema.GroupKind{Group: "example.com", Kind: "Widget"}, schema.GroupVersionResource{Group: "example.com", Version: "v1alpha1", Resource: "widgets"}, meta.RESTScopeNameNamespace},
+	}
+
+	for _, tc := range testCases {
+		mapping, err := mapper.RESTMapping(tc.gk)
+		require.NoError(t, err)
+		require.Equal(t, tc.expectedGVR, mapping.Resource)
+		require.Equal(t, tc.expectedScope, mapping.Scope.Name())
+	}
+
+	_, err = mapper.RESTMapping(schema.GroupKind{Kind: "Unknown"})
+	require.True(t, meta.IsNoMatchError(err))
+
+	require.Equal(t, []string{"get", "list"}, []string(groups[2].VersionedResources["v1alpha1"][0].Verbs))
+}
+
+func TestFileDiscoverySourceInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api-resources.txt")
+	require.NoError(t, os.WriteFile(path, []byte("NAME KIND\npods Pod\n"), 0600))
+
+	_, err := cmdcore.NewFileDiscoverySource(path)
+	require.ErrorContains(t, err, "Expected header to include column APIVERSION")
+}
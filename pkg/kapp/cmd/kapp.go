@@ -4,7 +4,9 @@
 package cmd
 
 import (
+	"fmt"
 	"io"
+	"time"
 
 	"github.com/cppforlife/cobrautil"
 	"github.com/cppforlife/go-cli-ui/ui"
@@ -14,12 +16,15 @@ import (
 	cmdag "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/appgroup"
 	cmdcm "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/configmap"
 	cmdcore "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/core"
+	cmdpf "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/preflight"
 	cmdsa "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/serviceaccount"
 	cmdtools "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/tools"
 	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/logger"
 	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/permissions"
 	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	preflightchecks "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
 	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/version"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 type KappOptions struct {
@@ -49,15 +54,97 @@ func NewDefaultKappCmd(ui *ui.ConfUI) *cobra.Command {
 	configFactory := cmdcore.NewConfigFactoryImpl()
 	depsFactory := cmdcore.NewDepsFactoryImpl(configFactory, ui)
 	preflights := defaultKappPreflightRegistry(depsFactory)
+
+	apiCallCounter := preflight.NewAPICallCounter()
+	configFactory.ConfigureTransportWrapper(apiCallCounter.WrapTransport)
+	preflights.SetAPICallCounter(apiCallCounter)
+
 	options := NewKappOptions(ui, configFactory, depsFactory, preflights)
 	flagsFactory := cmdcore.NewFlagsFactory(configFactory, depsFactory)
 	return NewKappCmd(options, flagsFactory)
 }
 
 func defaultKappPreflightRegistry(depsFactory cmdcore.DepsFactory) *preflight.Registry {
-	registry := preflight.NewRegistry(map[string]preflight.Check{
-		"PermissionValidation": permissions.NewPreflight(depsFactory, false),
-	})
+	registry := preflight.NewRegistry(nil)
+
+	// Checks that only inspect the change graph are safe to run in parallel
+	parallelOpts := preflight.CheckOpts{Concurrency: preflight.ConcurrencyClassParallel}
+
+	// Findings of checks that read the cluster (objects, nodes, discovery,
+	// permissions or dry-run results) go stale as the cluster changes
+	clusterStateTTL := 5 * time.Minute
+
+	registry.AddCheckWithOpts(preflightchecks.CommandArgsSanityName, preflightchecks.NewCommandArgsSanity(false), parallelOpts)
+	registry.AddCheckWithOpts(preflightchecks.EmptyDirLimitsName, preflightchecks.NewEmptyDirLimits(false), parallelOpts)
+	registry.AddCheckWithOpts(preflightchecks.FieldManagerConflictName, preflightchecks.NewFieldManagerConflict(false), parallelOpts)
+	registry.AddCheckWithOpts(preflightchecks.LegacyApplyAnnotationName, preflightchecks.NewLegacyApplyAnnotation(false), parallelOpts)
+	registry.AddCheckWithOpts(preflightchecks.MountPathConflictName, preflightchecks.NewMountPathConflict(false), parallelOpts)
+	registry.AddCheckWithOpts(preflightchecks.NamespaceOrderingName, preflightchecks.NewNamespaceOrdering(false), parallelOpts)
+	registry.AddCheckWithOpts(preflightchecks.NoDirectNodeNameName, preflightchecks.NewNoDirectNodeName(false), parallelOpts)
+	registry.AddCheckWithOpts(preflightchecks.NoHardcodedResourceVersionName, preflightchecks.NewNoHardcodedResourceVersion(false), parallelOpts)
+	registry.AddCheckWithOpts(preflightchecks.OvercommitRiskName, preflightchecks.NewOvercommitRisk(false), parallelOpts)
+	registry.AddCheckWithOpts(preflightchecks.ServiceAccountTokenAutomountName, preflightchecks.NewServiceAccountTokenAutomount(false), parallelOpts)
+	registry.AddCheckWithOpts(preflightchecks.StartupProbeAdequateName, preflightchecks.NewStartupProbeAdequate(false), parallelOpts)
+
+	// Policy checks may be instantiated multiple times with different configs
+	registry.AddCheckFactory(preflightchecks.AllowedRegistriesName,
+		func() preflight.Check { return preflightchecks.NewAllowedRegistries(false) }, parallelOpts)
+	registry.AddCheckFactory(preflightchecks.CostAllocationLabelsName,
+		func() preflight.Check { return preflightchecks.NewCostAllocationLabels(false) }, parallelOpts)
+	registry.AddCheckFactory(preflightchecks.HASpreadRequiredName,
+		func() preflight.Check { return preflightchecks.NewHASpreadRequired(false) }, parallelOpts)
+
+	registry.AddCheckWithOpts(preflightchecks.ActiveDeadlineSaneName, preflightchecks.NewActiveDeadlineSane(false),
+		preflight.CheckOpts{RunsIf: []schema.GroupVersionKind{{Group: "batch", Kind: "Job"}, {Group: "batch", Kind: "CronJob"}},
+			Concurrency: preflight.ConcurrencyClassParallel})
+	// Simulates removal of built-in API versions when a target version is set
+	registry.AddCheckWithOpts(preflightchecks.CascadingDeleteScopeName, preflightchecks.NewCascadingDeleteScope(depsFactory, false),
+		preflight.CheckOpts{VersionDependent: true, FindingsTTL: clusterStateTTL})
+	registry.AddCheckWithOpts(preflightchecks.ClusterIPConflictName, preflightchecks.NewClusterIPConflict(depsFactory, false),
+		preflight.CheckOpts{RunsIf: []schema.GroupVersionKind{{Kind: "Service"}}, FindingsTTL: clusterStateTTL})
+	// CRDs and their served versions are read from the current cluster
+	registry.AddCheckWithOpts(preflightchecks.CRVersionConsistencyName, preflightchecks.NewCRVersionConsistency(depsFactory, false),
+		preflight.CheckOpts{VersionDependent: true, FindingsTTL: clusterStateTTL})
+	registry.AddCheckWithOpts(preflightchecks.ExternalTrafficPolicyLocalName, preflightchecks.NewExternalTrafficPolicyLocal(depsFactory, false),
+		preflight.CheckOpts{RunsIf: []schema.GroupVersionKind{{Kind: "Service"}}, FindingsTTL: clusterStateTTL})
+	registry.AddCheckWithOpts(preflightchecks.ImagePullSecretExistsName, preflightchecks.NewImagePullSecretExists(depsFactory, false),
+		preflight.CheckOpts{FindingsTTL: clusterStateTTL})
+	registry.AddCheckWithOpts(preflightchecks.LimitRangeFitName, preflightchecks.NewLimitRangeFit(depsFactory, false),
+		preflight.CheckOpts{FindingsTTL: clusterStateTTL})
+	registry.AddCheckWithOpts(preflightchecks.LoadBalancerSupportedName, preflightchecks.NewLoadBalancerSupported(depsFactory, false),
+		preflight.CheckOpts{RunsIf: []schema.GroupVersionKind{{Kind: "Service"}}, FindingsTTL: clusterStateTTL})
+	registry.AddCheckWithOpts(preflightchecks.NetworkPolicyTargetsName, preflightchecks.NewNetworkPolicyTargets(false),
+		preflight.CheckOpts{RunsIf: []schema.GroupVersionKind{{Group: "networking.k8s.io", Kind: "NetworkPolicy"}}, Concurrency: preflight.ConcurrencyClassParallel})
+	registry.AddCheckWithOpts("PermissionValidation", permissions.NewPreflight(depsFactory, false),
+		preflight.CheckOpts{FindingsTTL: clusterStateTTL})
+	registry.AddCheckWithOpts(preflightchecks.PodPVCTopologyConsistentName, preflightchecks.NewPodPVCTopologyConsistent(depsFactory, false),
+		preflight.CheckOpts{FindingsTTL: clusterStateTTL})
+	registry.AddCheckWithOpts(preflightchecks.ProgressDeadlineSaneName, preflightchecks.NewProgressDeadlineSane(false),
+		preflight.CheckOpts{RunsIf: []schema.GroupVersionKind{{Group: "apps", Kind: "Deployment"}}, Concurrency: preflight.ConcurrencyClassParallel})
+	// Simulates removal of built-in API versions when a target version is set
+	registry.AddCheckWithOpts(preflightchecks.RBACResourceExistsName, preflightchecks.NewRBACResourceExists(depsFactory, false),
+		preflight.CheckOpts{VersionDependent: true, FindingsTTL: clusterStateTTL})
+	// Server-side dry-run reflects defaulting of the current cluster version
+	registry.AddCheckWithOpts(preflightchecks.ReconciliationLoopRiskName, preflightchecks.NewReconciliationLoopRisk(depsFactory, false),
+		preflight.CheckOpts{VersionDependent: true, FindingsTTL: clusterStateTTL})
+	registry.AddCheckWithOpts(preflightchecks.RevisionHistorySaneName, preflightchecks.NewRevisionHistorySane(false),
+		preflight.CheckOpts{RunsIf: []schema.GroupVersionKind{
+			{Group: "apps", Kind: "Deployment"}, {Group: "apps", Kind: "StatefulSet"}, {Group: "apps", Kind: "DaemonSet"}},
+			Concurrency: preflight.ConcurrencyClassParallel})
+	registry.AddCheckWithOpts(preflightchecks.RolloutPDBCompatibleName, preflightchecks.NewRolloutPDBCompatible(depsFactory, false),
+		preflight.CheckOpts{RunsIf: []schema.GroupVersionKind{{Group: "apps", Kind: "Deployment"}, {Group: "apps", Kind: "StatefulSet"}},
+			FindingsTTL: clusterStateTTL})
+	registry.AddCheckWithOpts(preflightchecks.SelfAntiAffinityName, preflightchecks.NewSelfAntiAffinity(depsFactory, false),
+		preflight.CheckOpts{FindingsTTL: clusterStateTTL})
+	registry.AddCheckWithOpts(preflightchecks.ServiceConflictsName, preflightchecks.NewServiceConflicts(depsFactory, false),
+		preflight.CheckOpts{RunsIf: []schema.GroupVersionKind{{Kind: "Service"}}, FindingsTTL: clusterStateTTL})
+	registry.AddCheckWithOpts(preflightchecks.StatefulSetPolicySaneName, preflightchecks.NewStatefulSetPolicySane(false),
+		preflight.CheckOpts{RunsIf: []schema.GroupVersionKind{{Group: "apps", Kind: "StatefulSet"}}, Concurrency: preflight.ConcurrencyClassParallel})
+
+	err := registry.Validate()
+	if err != nil {
+		panic(fmt.Sprintf("Internal inconsistency: invalid preflight registry: %s", err))
+	}
 
 	return registry
 }
@@ -133,6 +220,10 @@ func NewKappCmd(o *KappOptions, flagsFactory cmdcore.FlagsFactory) *cobra.Comman
 	saCmd.AddCommand(cmdsa.NewListCmd(cmdsa.NewListOptions(o.ui, o.depsFactory, o.logger), flagsFactory))
 	cmd.AddCommand(saCmd)
 
+	pfCmd := cmdpf.NewCmd()
+	pfCmd.AddCommand(cmdpf.NewCatalogCmd(cmdpf.NewCatalogOptions(o.ui, o.PreflightChecks), flagsFactory))
+	cmd.AddCommand(pfCmd)
+
 	appCmd := cmdtools.NewCmd()
 	appCmd.AddCommand(cmdtools.NewInspectCmd(cmdtools.NewInspectOptions(o.ui, o.depsFactory), flagsFactory))
 	appCmd.AddCommand(cmdtools.NewDiffCmd(cmdtools.NewDiffOptions(o.ui, o.depsFactory), flagsFactory))
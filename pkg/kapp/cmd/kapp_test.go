@@ -0,0 +1,92 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	preflightchecks "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+)
+
+func TestDefaultKappPreflightRegistryVersionDependent(t *testing.T) {
+	registry := defaultKappPreflightRegistry(nil)
+
+	var versionDependent []string
+	for _, desc := range registry.Describe().Checks {
+		if desc.VersionDependent {
+			versionDependent = append(versionDependent, desc.Name)
+		}
+	}
+
+	// Checks relying on discovery or server-side behaviour of the cluster
+	require.Equal(t, []string{
+		preflightchecks.CRVersionConsistencyName,
+		preflightchecks.CascadingDeleteScopeName,
+		preflightchecks.RBACResourceExistsName,
+		preflightchecks.ReconciliationLoopRiskName,
+	}, versionDependent)
+
+	// Checks that cannot simulate a target version are skipped when it is set
+	_, aware := preflightchecks.NewRBACResourceExists(nil, false).(preflight.VersionAwareCheck)
+	require.True(t, aware)
+	_, aware = preflightchecks.NewCascadingDeleteScope(nil, false).(preflight.VersionAwareCheck)
+	require.True(t, aware)
+	_, aware = preflightchecks.NewCRVersionConsistency(nil, false).(preflight.VersionAwareCheck)
+	require.False(t, aware)
+}
+
+func TestDefaultKappPreflightRegistryFindingsTTL(t *testing.T) {
+	registry := defaultKappPreflightRegistry(nil)
+
+	var withTTL []string
+	for _, desc := range registry.Describe().Checks {
+		if len(desc.FindingsTTL) > 0 {
+			require.Equal(t, "5m0s", desc.FindingsTTL)
+			withTTL = append(withTTL, desc.Name)
+		}
+	}
+
+	// Checks that read the cluster
+	require.Equal(t, []string{
+		preflightchecks.CRVersionConsistencyName,
+		preflightchecks.CascadingDeleteScopeName,
+		preflightchecks.ClusterIPConflictName,
+		preflightchecks.ExternalTrafficPolicyLocalName,
+		preflightchecks.ImagePullSecretExistsName,
+		preflightchecks.LimitRangeFitName,
+		preflightchecks.LoadBalancerSupportedName,
+		"PermissionValidation",
+		preflightchecks.PodPVCTopologyConsistentName,
+		preflightchecks.RBACResourceExistsName,
+		preflightchecks.ReconciliationLoopRiskName,
+		preflightchecks.RolloutPDBCompatibleName,
+		preflightchecks.SelfAntiAffinityName,
+		preflightchecks.ServiceConflictsName,
+	}, withTTL)
+}
+
+func TestDefaultKappPreflightRegistryConfigSchema(t *testing.T) {
+	registry := defaultKappPreflightRegistry(nil)
+
+	for _, desc := range registry.Describe().Checks {
+		if !desc.Configurable {
+			continue
+		}
+
+		// Each configuration field is described for users writing config
+		require.NotEmpty(t, desc.ConfigSchema, desc.Name)
+		for key := range desc.Config {
+			var found bool
+			for _, field := range desc.ConfigSchema {
+				if field.Name == key {
+					require.NotEmpty(t, field.Description, "%s: %s", desc.Name, key)
+					found = true
+				}
+			}
+			require.True(t, found, "%s: %s", desc.Name, key)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	uitable "github.com/cppforlife/go-cli-ui/ui/table"
+	"github.com/spf13/cobra"
+	cmdcore "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/core"
+	ctlpf "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+)
+
+const (
+	catalogOutputTable = "table"
+	catalogOutputJSON  = "json"
+)
+
+type CatalogOptions struct {
+	ui              ui.UI
+	preflightChecks *ctlpf.Registry
+
+	Output string
+}
+
+func NewCatalogOptions(ui ui.UI, preflights *ctlpf.Registry) *CatalogOptions {
+	return &CatalogOptions{ui: ui, preflightChecks: preflights}
+}
+
+func NewCatalogCmd(o *CatalogOptions, _ cmdcore.FlagsFactory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "catalog",
+		Short: "List available preflight checks",
+		RunE:  func(_ *cobra.Command, _ []string) error { return o.Run() },
+		Example: `
+  # List available preflight checks
+  kapp preflight catalog
+
+  # Print machine readable catalog of preflight checks
+  kapp preflight catalog --output json`,
+	}
+	cmd.Flags().StringVar(&o.Output, "output", catalogOutputTable, "Output format (table, json)")
+	return cmd
+}
+
+func (o *CatalogOptions) Run() error {
+	catalog := o.preflightChecks.Describe()
+
+	switch o.Output {
+	case catalogOutputJSON:
+		bs, err := json.MarshalIndent(catalog, "", "  ")
+		if err != nil {
+			return fmt.Errorf("Marshaling catalog: %w", err)
+		}
+		o.ui.PrintBlock(append(bs, '\n'))
+
+	case catalogOutputTable:
+		table := uitable.Table{
+			Title:   "Preflight checks",
+			Content: "preflight checks",
+
+			Header: []uitable.Header{
+				uitable.NewHeader("Name"),
+				uitable.NewHeader("Enabled"),
+				uitable.NewHeader("Configurable"),
+				uitable.NewHeader("Locked"),
+				uitable.NewHeader("Description"),
+			},
+
+			SortBy: []uitable.ColumnSort{{Column: 0, Asc: true}},
+		}
+
+		for _, check := range catalog.Checks {
+			table.Rows = append(table.Rows, []uitable.Value{
+				uitable.NewValueString(check.Name),
+				uitable.NewValueBool(check.Enabled),
+				uitable.NewValueBool(check.Configurable),
+				uitable.NewValueBool(check.Locked),
+				uitable.NewValueString(check.Description),
+			})
+		}
+
+		o.ui.PrintTable(table)
+
+	default:
+		return fmt.Errorf("Unknown output format %q (expected one of: %s, %s)", o.Output, catalogOutputTable, catalogOutputJSON)
+	}
+
+	return nil
+}
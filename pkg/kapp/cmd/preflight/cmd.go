@@ -0,0 +1,21 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"github.com/spf13/cobra"
+	cmdcore "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/core"
+)
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "preflight",
+		Aliases: []string{"pf"},
+		Short:   "Preflight checks",
+		Annotations: map[string]string{
+			cmdcore.MiscHelpGroup.Key: cmdcore.MiscHelpGroup.Value,
+		},
+	}
+	return cmd
+}
@@ -29,6 +29,10 @@ func NewPreflight(depsFactory cmdcore.DepsFactory, enabled bool) preflight.Check
 	}
 }
 
+func (p *Preflight) Description() string {
+	return "Validates that the user has permissions to create, update and delete resources in the change"
+}
+
 func (p *Preflight) Enabled() bool {
 	return p.enabled
 }
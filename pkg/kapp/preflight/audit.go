@@ -0,0 +1,85 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"context"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+)
+
+const (
+	// tracingAuditSpanName is the name of the span
+	// that is a parent of spans of audited checks
+	tracingAuditSpanName = "preflight audit"
+)
+
+// AuditResult is the outcome a single check would have
+// if it was enabled. It does not affect outcome of Run.
+type AuditResult struct {
+	Name string
+	// Enabled is true if the check is enforced by Run
+	Enabled bool
+	// Skipped is true if check did not run
+	// because its runs-if conditions were not met
+	Skipped    bool
+	SkipReason string
+	Findings   []Finding
+	// Err is set if the check failed to run
+	Err error
+}
+
+// Status returns would-be outcome of the check: skipped,
+// error (failed to run), failed (reported errors) or passed
+func (r AuditResult) Status() string {
+	switch {
+	case r.Skipped:
+		return CheckStatusSkipped
+	case r.Err != nil:
+		return CheckStatusError
+	case CountAtLeast(r.Findings, SeverityError) > 0:
+		return CheckStatusFailed
+	default:
+		return CheckStatusPassed
+	}
+}
+
+// Audit runs all known checks of PhasePreApply regardless of
+// whether they are enabled and returns their results sorted by
+// check name. It is meant to help deciding which checks to enable;
+// unlike Run it does not fail based on the findings and does not
+// affect Stats or Score. Checks that failed to run (including
+// exceeding goroutines limit, see SetMaxGoroutines) have Err set.
+func (c *Registry) Audit(ctx context.Context, cg *ctldgraph.ChangeGraph) ([]AuditResult, error) {
+	ctx = c.contextWithDeployContext(ctx)
+
+	ctx, span := c.tracerOrNoop().Start(ctx, tracingAuditSpanName)
+	defer span.End()
+
+	runs, pendingRuns := c.prepareRuns(cg, func(string, Check) bool { return true })
+
+	c.runChecks(ctx, cg, pendingRuns)
+
+	var results []AuditResult
+
+	for _, run := range runs {
+		result := AuditResult{Name: run.name, Enabled: c.known[run.name].Enabled(),
+			Skipped: run.stats.Skipped, SkipReason: run.stats.SkipReason, Err: run.err}
+
+		if run.stats.Skipped {
+			c.traceSkippedCheck(ctx, run)
+		} else {
+			expiresAt := c.findingsExpiry(run.name)
+			for i := range run.findings {
+				run.findings[i].Check = run.name
+				run.findings[i].ExpiresAt = expiresAt
+			}
+			result.Findings = c.limitFindings(run.name, run.findings)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
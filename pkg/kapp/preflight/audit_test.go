@@ -0,0 +1,57 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/logger"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestRegistryAudit(t *testing.T) {
+	graph, err := diffgraph.NewChangeGraph(nil, nil, nil, logger.NewTODOLogger())
+	require.NoError(t, err)
+
+	newCheck := func(err error, enabled bool) Check {
+		return NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error { return err }, enabled)
+	}
+
+	registry := &Registry{}
+	registry.AddCheck("enabled", newCheck(errors.Join(NewWarning(nil, "warning")), true))
+	registry.AddCheck("disabledFailing", newCheck(errors.Join(NewError(nil, "error")), false))
+	registry.AddCheck("disabledErroring", newCheck(errors.New("cannot run"), false))
+	registry.AddCheckWithOpts("disabledSkipped", newCheck(nil, false), CheckOpts{RunsIf: []schema.GroupVersionKind{{Kind: "Service"}}})
+
+	_, err = registry.Run(context.Background(), graph)
+	require.NoError(t, err)
+	require.Len(t, registry.Stats(), 1)
+
+	results, err := registry.Audit(context.Background(), graph)
+	require.NoError(t, err)
+
+	var statuses []string
+	for _, result := range results {
+		statuses = append(statuses, result.Name+": "+result.Status())
+	}
+	require.Equal(t, []string{
+		"disabledErroring: error",
+		"disabledFailing: failed",
+		"disabledSkipped: skipped",
+		"enabled: passed",
+	}, statuses)
+
+	require.False(t, results[1].Enabled)
+	require.Equal(t, []Finding{{Check: "disabledFailing", Severity: SeverityError, Message: "error"}}, results[1].Findings)
+	require.Equal(t, "no resources of kind Service in the change", results[2].SkipReason)
+	require.True(t, results[3].Enabled)
+
+	// Audit does not affect results of the enforced run
+	require.Len(t, registry.Stats(), 1)
+	require.False(t, registry.known["disabledFailing"].Enabled())
+}
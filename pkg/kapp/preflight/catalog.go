@@ -0,0 +1,192 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const (
+	// CatalogAPIVersion is the version of the Catalog schema.
+	// Fields may be added within a version, but never removed or changed.
+	CatalogAPIVersion = "preflight.kapp.k14s.io/v1alpha1"
+	CatalogKind       = "PreflightCatalog"
+)
+
+// Catalog is a machine readable description of
+// all preflight checks known to a Registry
+type Catalog struct {
+	APIVersion string             `json:"apiVersion"`
+	Kind       string             `json:"kind"`
+	Checks     []CheckDescription `json:"checks"`
+}
+
+// CheckDescription describes a single preflight check
+type CheckDescription struct {
+	Name         string `json:"name"`
+	Description  string `json:"description,omitempty"`
+	Enabled      bool   `json:"enabled"`
+	Configurable bool   `json:"configurable"`
+	// Locked checks cannot be disabled
+	Locked bool `json:"locked,omitempty"`
+	// RunsIf lists kinds of resources required
+	// to be in the change for the check to run
+	RunsIf []string `json:"runsIf,omitempty"`
+	// VersionDependent checks depend on the Kubernetes version of the cluster
+	VersionDependent bool `json:"versionDependent,omitempty"`
+	// Concurrency is "parallel" for checks that may
+	// run at the same time as other checks
+	Concurrency ConcurrencyClass `json:"concurrency,omitempty"`
+	// PostApply checks are re-run after changes are applied
+	PostApply bool `json:"postApply,omitempty"`
+	// FindingsTTL is how long findings of checks observing
+	// volatile cluster state remain valid (e.g. 5m0s)
+	FindingsTTL string `json:"findingsTTL,omitempty"`
+	// Config is the effective configuration of a configurable check
+	Config CheckConfig `json:"config,omitempty"`
+	// ConfigSchema describes configuration fields accepted by
+	// a configurable check (if it provides a schema)
+	ConfigSchema []ConfigField `json:"configSchema,omitempty"`
+}
+
+// ConfigField describes a configuration field of a check.
+// Type is one of string, boolean, integer, number or object,
+// prefixed with [] for lists and map[string] for maps.
+// Fields describe fields of objects (including list and map values).
+type ConfigField struct {
+	Name        string        `json:"name"`
+	Type        string        `json:"type"`
+	Description string        `json:"description,omitempty"`
+	Fields      []ConfigField `json:"fields,omitempty"`
+}
+
+// ConfigSchemaCheck is a ConfigurableCheck
+// that describes configuration it accepts
+type ConfigSchemaCheck interface {
+	ConfigurableCheck
+	ConfigSchema() []ConfigField
+}
+
+// DescribedCheck is a Check that provides
+// a human readable description of itself
+type DescribedCheck interface {
+	Check
+	Description() string
+}
+
+// Describe returns a Catalog of known preflight checks sorted by name
+func (c *Registry) Describe() Catalog {
+	catalog := Catalog{
+		APIVersion: CatalogAPIVersion,
+		Kind:       CatalogKind,
+		Checks:     []CheckDescription{},
+	}
+
+	for _, name := range c.names() {
+		check := c.known[name]
+		desc := CheckDescription{Name: name, Enabled: check.Enabled(), Locked: c.IsLocked(name),
+			VersionDependent: c.opts[name].VersionDependent, Concurrency: c.opts[name].Concurrency,
+			PostApply: c.opts[name].PostApply}
+
+		if ttl := c.opts[name].FindingsTTL; ttl > 0 {
+			desc.FindingsTTL = ttl.String()
+		}
+
+		for _, gvk := range c.opts[name].RunsIf {
+			desc.RunsIf = append(desc.RunsIf, formatGVK(gvk))
+		}
+
+		if describedCheck, ok := check.(DescribedCheck); ok {
+			desc.Description = describedCheck.Description()
+		}
+		if configurableCheck, ok := check.(ConfigurableCheck); ok {
+			desc.Configurable = true
+			desc.Config = configurableCheck.Config()
+		}
+		if schemaCheck, ok := check.(ConfigSchemaCheck); ok {
+			desc.ConfigSchema = schemaCheck.ConfigSchema()
+		}
+
+		catalog.Checks = append(catalog.Checks, desc)
+	}
+
+	return catalog
+}
+
+// NewCheckConfig converts a configuration struct (via its
+// JSON tags) into a CheckConfig. It is the inverse of CheckConfig.Decode.
+func NewCheckConfig(obj interface{}) CheckConfig {
+	bs, err := json.Marshal(obj)
+	if err != nil {
+		panic(fmt.Sprintf("Internal inconsistency: marshaling check config: %s", err))
+	}
+
+	var config CheckConfig
+
+	err = json.Unmarshal(bs, &config)
+	if err != nil {
+		panic(fmt.Sprintf("Internal inconsistency: unmarshaling check config: %s", err))
+	}
+
+	return config
+}
+
+// NewConfigSchema describes fields of a configuration struct based on
+// their JSON tags (fields without one are skipped) and description tags
+func NewConfigSchema(obj interface{}) []ConfigField {
+	return configFields(reflect.TypeOf(obj))
+}
+
+func configFields(typ reflect.Type) []ConfigField {
+	var result []ConfigField
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if len(name) == 0 || name == "-" {
+			continue
+		}
+
+		fieldType, fields := configFieldType(field.Type)
+
+		result = append(result, ConfigField{
+			Name:        name,
+			Type:        fieldType,
+			Description: field.Tag.Get("description"),
+			Fields:      fields,
+		})
+	}
+
+	return result
+}
+
+func configFieldType(typ reflect.Type) (string, []ConfigField) {
+	switch typ.Kind() {
+	case reflect.Pointer:
+		return configFieldType(typ.Elem())
+	case reflect.Slice, reflect.Array:
+		elemType, fields := configFieldType(typ.Elem())
+		return "[]" + elemType, fields
+	case reflect.Map:
+		elemType, fields := configFieldType(typ.Elem())
+		return "map[string]" + elemType, fields
+	case reflect.Struct:
+		return "object", configFields(typ)
+	case reflect.String:
+		return "string", nil
+	case reflect.Bool:
+		return "boolean", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer", nil
+	case reflect.Float32, reflect.Float64:
+		return "number", nil
+	default:
+		panic(fmt.Sprintf("Internal inconsistency: unsupported check config field type %s", typ))
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+package preflight
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+)
+
+type describedCheck struct {
+	Check
+}
+
+func (describedCheck) Description() string { return "some description" }
+
+func TestRegistryDescribe(t *testing.T) {
+	configurable := newConfigurableCheck()
+	configurable.config = CheckConfig{"key": "value"}
+
+	registry := NewRegistry(map[string]Check{
+		"plain":        NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error { return nil }, false),
+		"described":    describedCheck{NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error { return nil }, true)},
+		"configurable": configurable,
+	})
+
+	bs, err := json.Marshal(registry.Describe())
+	require.NoError(t, err)
+
+	require.JSONEq(t, `{
+  "apiVersion": "preflight.kapp.k14s.io/v1alpha1",
+  "kind": "PreflightCatalog",
+  "checks": [
+    {"name": "configurable", "enabled": true, "configurable": true, "config": {"key": "value"}},
+    {"name": "described", "description": "some description", "enabled": true, "configurable": false},
+    {"name": "plain", "enabled": false, "configurable": false}
+  ]
+}`, string(bs))
+}
+
+func TestNewCheckConfig(t *testing.T) {
+	type config struct {
+		MinSeconds int `json:"minSeconds"`
+	}
+
+	checkConfig := NewCheckConfig(config{MinSeconds: 10})
+
+	var decoded config
+	require.NoError(t, checkConfig.Decode(&decoded))
+	require.Equal(t, config{MinSeconds: 10}, decoded)
+}
+
+func TestNewConfigSchema(t *testing.T) {
+	type rule struct {
+		Key     string `json:"key" description:"Some key"`
+		Pattern string `json:"pattern,omitempty"`
+	}
+	type config struct {
+		MinSeconds int32             `json:"minSeconds" description:"Minimum seconds"`
+		MaxRatio   float64           `json:"maxRatio"`
+		Strict     bool              `json:"strict"`
+		Images     []string          `json:"images"`
+		Selector   map[string]string `json:"selector"`
+		Rules      []rule            `json:"rules"`
+		Ignored    string            `json:"-"`
+		untagged   string
+	}
+
+	require.Equal(t, []ConfigField{
+		{Name: "minSeconds", Type: "integer", Description: "Minimum seconds"},
+		{Name: "maxRatio", Type: "number"},
+		{Name: "strict", Type: "boolean"},
+		{Name: "images", Type: "[]string"},
+		{Name: "selector", Type: "map[string]string"},
+		{Name: "rules", Type: "[]object", Fields: []ConfigField{
+			{Name: "key", Type: "string", Description: "Some key"},
+			{Name: "pattern", Type: "string"},
+		}},
+	}, NewConfigSchema(config{untagged: "val"}))
+}
@@ -0,0 +1,167 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	ActiveDeadlineSaneName = "ActiveDeadlineSane"
+)
+
+// ActiveDeadlineSaneConfig is the configuration accepted
+// by the ActiveDeadlineSane preflight check
+type ActiveDeadlineSaneConfig struct {
+	// MinActiveDeadlineSeconds is the minimum
+	// activeDeadlineSeconds expected on Jobs
+	MinActiveDeadlineSeconds int64 `json:"minActiveDeadlineSeconds" description:"Minimum activeDeadlineSeconds expected on Jobs"`
+}
+
+// ActiveDeadlineSane is an implementation of preflight.Check
+// that warns about Jobs (including Jobs created by CronJobs) whose
+// activeDeadlineSeconds is below the configured minimum or does
+// not exceed the time their containers' startupProbes allow to
+// start. Such Jobs are terminated before they can finish and fail
+// on every run.
+type ActiveDeadlineSane struct {
+	enabled bool
+	config  ActiveDeadlineSaneConfig
+}
+
+var _ preflight.ConfigSchemaCheck = &ActiveDeadlineSane{}
+var _ preflight.DescribedCheck = &ActiveDeadlineSane{}
+
+func NewActiveDeadlineSane(enabled bool) preflight.Check {
+	return &ActiveDeadlineSane{
+		enabled: enabled,
+		config:  ActiveDeadlineSaneConfig{MinActiveDeadlineSeconds: 60},
+	}
+}
+
+func (c *ActiveDeadlineSane) Description() string {
+	return "Warns about Jobs and CronJobs whose activeDeadlineSeconds is too short for them to finish"
+}
+
+func (c *ActiveDeadlineSane) Enabled() bool {
+	return c.enabled
+}
+
+func (c *ActiveDeadlineSane) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *ActiveDeadlineSane) SetConfig(config preflight.CheckConfig) error {
+	newConfig := c.config
+
+	err := config.Decode(&newConfig)
+	if err != nil {
+		return err
+	}
+	if newConfig.MinActiveDeadlineSeconds < 0 {
+		return fmt.Errorf("expected minActiveDeadlineSeconds to be non-negative")
+	}
+
+	c.config = newConfig
+	return nil
+}
+
+func (c *ActiveDeadlineSane) Config() preflight.CheckConfig {
+	return preflight.NewCheckConfig(c.config)
+}
+
+func (c *ActiveDeadlineSane) ConfigSchema() []preflight.ConfigField {
+	return preflight.NewConfigSchema(ActiveDeadlineSaneConfig{})
+}
+
+func (c *ActiveDeadlineSane) Run(_ context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	var findings []error
+
+	for _, change := range changeGraph.All() {
+		res := change.Change.Resource()
+
+		if change.Change.Op() != ctldgraph.ActualChangeOpUpsert {
+			continue
+		}
+
+		jobSpec, found, err := c.jobSpec(res)
+		if err != nil {
+			return err
+		}
+		if !found || jobSpec.ActiveDeadlineSeconds == nil {
+			continue
+		}
+
+		deadline := *jobSpec.ActiveDeadlineSeconds
+
+		if deadline < c.config.MinActiveDeadlineSeconds {
+			findings = append(findings, preflight.NewWarning(res,
+				"activeDeadlineSeconds %d is below minimum %d, job may be terminated before it finishes",
+				deadline, c.config.MinActiveDeadlineSeconds))
+			continue
+		}
+
+		if container, startup, found := c.slowestStartup(jobSpec.Template.Spec); found && deadline <= int64(startup) {
+			findings = append(findings, preflight.NewWarning(res,
+				"activeDeadlineSeconds %d does not exceed %ds allowed by startupProbe of container %q to start, "+
+					"job may be terminated before it finishes", deadline, startup, container))
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+// jobSpec returns spec of the Job or of Jobs created by the CronJob
+func (c *ActiveDeadlineSane) jobSpec(res ctlres.Resource) (batchv1.JobSpec, bool, error) {
+	var err error
+	var spec batchv1.JobSpec
+
+	switch res.GroupKind() {
+	case jobGK:
+		var job batchv1.Job
+		err = res.AsUncheckedTypedObj(&job)
+		spec = job.Spec
+
+	case cronJobGK:
+		var cronJob batchv1.CronJob
+		err = res.AsUncheckedTypedObj(&cronJob)
+		spec = cronJob.Spec.JobTemplate.Spec
+
+	default:
+		return spec, false, nil
+	}
+
+	if err != nil {
+		return spec, false, fmt.Errorf("Resource %s: %w", res.Description(), err)
+	}
+
+	return spec, true, nil
+}
+
+// slowestStartup returns container whose startupProbe
+// allows the most time to start
+func (c *ActiveDeadlineSane) slowestStartup(podSpec corev1.PodSpec) (string, int32, bool) {
+	var name string
+	var slowest int32
+	var found bool
+
+	for _, container := range podSpec.Containers {
+		if container.StartupProbe == nil {
+			continue
+		}
+		if allowed := probeAllowedSeconds(container.StartupProbe); !found || allowed > slowest {
+			name, slowest, found = container.Name, allowed, true
+		}
+	}
+
+	return name, slowest, found
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+func TestActiveDeadlineSane(t *testing.T) {
+	shortJob := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: short
+  namespace: default
+spec:
+  activeDeadlineSeconds: 30
+  template:
+    spec:
+      containers:
+      - name: migrate
+        image: migrate
+`))
+	slowStartingCronJob := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: report
+  namespace: default
+spec:
+  schedule: "0 * * * *"
+  jobTemplate:
+    spec:
+      activeDeadlineSeconds: 90
+      template:
+        spec:
+          containers:
+          - name: sidecar
+            image: proxy
+            startupProbe:
+              httpGet:
+                port: 8080
+          - name: report
+            image: report
+            startupProbe:
+              httpGet:
+                port: 8080
+              periodSeconds: 10
+              failureThreshold: 12
+`))
+	saneJob := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: sane
+  namespace: default
+spec:
+  activeDeadlineSeconds: 600
+  template:
+    spec:
+      containers:
+      - name: backup
+        image: backup
+        startupProbe:
+          exec:
+            command: ["true"]
+`))
+	noDeadlineJob := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: no-deadline
+  namespace: default
+spec:
+  template:
+    spec:
+      containers:
+      - name: backup
+        image: backup
+`))
+
+	resources := []ctlres.Resource{shortJob, slowStartingCronJob, saneJob, noDeadlineJob}
+
+	testCases := []struct {
+		name             string
+		config           preflight.CheckConfig
+		expectedWarnings []string
+	}{
+		{
+			name: "default config",
+			expectedWarnings: []string{
+				`job/short (batch/v1) namespace: default: activeDeadlineSeconds 30 is below minimum 60, ` +
+					`job may be terminated before it finishes`,
+				`cronjob/report (batch/v1) namespace: default: activeDeadlineSeconds 90 does not exceed 120s allowed ` +
+					`by startupProbe of container "report" to start, job may be terminated before it finishes`,
+			},
+		},
+		{
+			name:   "custom minimum",
+			config: preflight.CheckConfig{"minActiveDeadlineSeconds": 900},
+			expectedWarnings: []string{
+				`job/short (batch/v1) namespace: default: activeDeadlineSeconds 30 is below minimum 900, ` +
+					`job may be terminated before it finishes`,
+				`cronjob/report (batch/v1) namespace: default: activeDeadlineSeconds 90 is below minimum 900, ` +
+					`job may be terminated before it finishes`,
+				`job/sane (batch/v1) namespace: default: activeDeadlineSeconds 600 is below minimum 900, ` +
+					`job may be terminated before it finishes`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			check := checks.NewActiveDeadlineSane(true).(preflight.ConfigurableCheck)
+			require.NoError(t, check.SetConfig(tc.config))
+
+			findings := preflighttest.RunCheckOnResources(t, check, resources)
+			require.Equal(t, tc.expectedWarnings, preflighttest.Messages(findings))
+		})
+	}
+
+	t.Run("negative minimum, error returned", func(t *testing.T) {
+		check := checks.NewActiveDeadlineSane(true).(preflight.ConfigurableCheck)
+		require.EqualError(t, check.SetConfig(preflight.CheckConfig{"minActiveDeadlineSeconds": -1}),
+			`expected minActiveDeadlineSeconds to be non-negative`)
+	})
+}
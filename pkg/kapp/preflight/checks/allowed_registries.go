@@ -0,0 +1,186 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+)
+
+const (
+	AllowedRegistriesName = "AllowedRegistries"
+
+	dockerHubRegistry = "docker.io"
+)
+
+// AllowedRegistriesConfig is the configuration accepted
+// by the AllowedRegistries preflight check. Entries are
+// registry hosts (e.g. gcr.io) optionally followed by
+// a repository path prefix (e.g. gcr.io/my-project).
+type AllowedRegistriesConfig struct {
+	// Allowed registries. If empty, all registries
+	// that are not denied are allowed.
+	Allowed []string `json:"allowed" description:"Allowed registries (all registries that are not denied if empty)"`
+	// Denied registries take precedence over allowed ones
+	Denied []string `json:"denied" description:"Denied registries, taking precedence over allowed ones"`
+}
+
+// AllowedRegistries is an implementation of preflight.Check
+// that warns about containers using images from registries that
+// are not allowed by configuration. Images without a registry
+// (e.g. nginx) are treated as coming from docker.io.
+type AllowedRegistries struct {
+	enabled bool
+	config  AllowedRegistriesConfig
+}
+
+var _ preflight.ConfigSchemaCheck = &AllowedRegistries{}
+var _ preflight.DescribedCheck = &AllowedRegistries{}
+
+func NewAllowedRegistries(enabled bool) preflight.Check {
+	return &AllowedRegistries{enabled: enabled}
+}
+
+func (c *AllowedRegistries) Description() string {
+	return "Warns about containers using images from registries that are not allowed"
+}
+
+func (c *AllowedRegistries) Enabled() bool {
+	return c.enabled
+}
+
+func (c *AllowedRegistries) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *AllowedRegistries) SetConfig(config preflight.CheckConfig) error {
+	var newConfig AllowedRegistriesConfig
+
+	err := config.Decode(&newConfig)
+	if err != nil {
+		return err
+	}
+
+	for _, entries := range [][]string{newConfig.Allowed, newConfig.Denied} {
+		for i, entry := range entries {
+			if len(strings.Trim(entry, "/")) == 0 {
+				return fmt.Errorf("expected registry entries to be non-empty")
+			}
+			entries[i] = normalizeRegistryEntry(entry)
+		}
+	}
+
+	c.config = newConfig
+	return nil
+}
+
+func (c *AllowedRegistries) Config() preflight.CheckConfig {
+	return preflight.NewCheckConfig(c.config)
+}
+
+func (c *AllowedRegistries) ConfigSchema() []preflight.ConfigField {
+	return preflight.NewConfigSchema(AllowedRegistriesConfig{})
+}
+
+func (c *AllowedRegistries) Run(_ context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	if len(c.config.Allowed) == 0 && len(c.config.Denied) == 0 {
+		return nil
+	}
+
+	workloads, err := upsertedWorkloads(changeGraph)
+	if err != nil {
+		return err
+	}
+
+	var findings []error
+
+	for _, wl := range workloads {
+		for _, container := range allContainers(wl.Template.Spec) {
+			repo := normalizeImageRepository(container.Image)
+
+			switch {
+			case matchesRegistryEntry(repo, c.config.Denied):
+				findings = append(findings, preflight.NewWarning(wl.Resource,
+					"container %q uses image %q from denied registry %q", container.Name, container.Image, imageRegistry(repo)))
+
+			case len(c.config.Allowed) > 0 && !matchesRegistryEntry(repo, c.config.Allowed):
+				findings = append(findings, preflight.NewWarning(wl.Resource,
+					"container %q uses image %q from registry %q which is not allowed", container.Name, container.Image, imageRegistry(repo)))
+			}
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+// normalizeImageRepository returns fully qualified repository of the image
+// (without tag or digest) following docker conventions, e.g.
+// nginx:1.25 becomes docker.io/library/nginx
+func normalizeImageRepository(image string) string {
+	repo := image
+	if idx := strings.Index(repo, "@"); idx >= 0 {
+		repo = repo[:idx]
+	}
+	// Colon after last slash separates the tag (colon before it is a registry port)
+	if idx := strings.LastIndex(repo, ":"); idx > strings.LastIndex(repo, "/") {
+		repo = repo[:idx]
+	}
+
+	pieces := strings.SplitN(repo, "/", 2)
+	if len(pieces) == 1 || !isRegistryHost(pieces[0]) {
+		repo = dockerHubRegistry + "/" + repo
+		pieces = strings.SplitN(repo, "/", 2)
+	}
+
+	registry := normalizeRegistryHost(pieces[0])
+	path := pieces[1]
+	if registry == dockerHubRegistry && !strings.Contains(path, "/") {
+		path = "library/" + path
+	}
+
+	return registry + "/" + path
+}
+
+// normalizeRegistryEntry normalizes user provided registry
+// (with optional path prefix) so that it can be compared
+// against normalized image repositories
+func normalizeRegistryEntry(entry string) string {
+	pieces := strings.SplitN(strings.Trim(entry, "/"), "/", 2)
+	if len(pieces) == 1 {
+		return normalizeRegistryHost(pieces[0])
+	}
+	return normalizeRegistryHost(pieces[0]) + "/" + pieces[1]
+}
+
+func normalizeRegistryHost(host string) string {
+	host = strings.ToLower(host)
+	if host == "index.docker.io" || host == "registry-1.docker.io" {
+		return dockerHubRegistry
+	}
+	return host
+}
+
+func matchesRegistryEntry(repo string, entries []string) bool {
+	for _, entry := range entries {
+		if repo == entry || strings.HasPrefix(repo, entry+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func imageRegistry(repo string) string {
+	return strings.SplitN(repo, "/", 2)[0]
+}
+
+// isRegistryHost follows docker conventions: first path component is
+// a registry if it contains a dot or port, or is localhost
+func isRegistryHost(component string) bool {
+	return strings.ContainsAny(component, ".:") || component == "localhost"
+}
@@ -0,0 +1,88 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+func TestAllowedRegistries(t *testing.T) {
+	res := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: default
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: registry.example.com:5000/tools/init:v1
+      containers:
+      - name: app
+        image: nginx:1.25
+      - name: sidecar
+        image: gcr.io/my-project/sidecar@sha256:0000000000000000000000000000000000000000000000000000000000000000
+`))
+
+	testCases := []struct {
+		name             string
+		config           preflight.CheckConfig
+		expectedWarnings []string
+	}{
+		{
+			name: "no registries configured, no warnings",
+		},
+		{
+			name: "all registries allowed, implicit docker.io is normalized",
+			config: preflight.CheckConfig{
+				"allowed": []interface{}{"index.docker.io/library", "gcr.io/my-project", "Registry.Example.com:5000"},
+			},
+		},
+		{
+			name:   "images from registries not on allowlist",
+			config: preflight.CheckConfig{"allowed": []interface{}{"docker.io", "gcr.io/other-project"}},
+			expectedWarnings: []string{
+				`deployment/app (apps/v1) namespace: default: container "init" uses image "registry.example.com:5000/tools/init:v1" ` +
+					`from registry "registry.example.com:5000" which is not allowed`,
+				`deployment/app (apps/v1) namespace: default: container "sidecar" uses image ` +
+					`"gcr.io/my-project/sidecar@sha256:0000000000000000000000000000000000000000000000000000000000000000" ` +
+					`from registry "gcr.io" which is not allowed`,
+			},
+		},
+		{
+			name: "denylist takes precedence",
+			config: preflight.CheckConfig{
+				"allowed": []interface{}{"docker.io", "gcr.io", "registry.example.com:5000"},
+				"denied":  []interface{}{"docker.io"},
+			},
+			expectedWarnings: []string{
+				`deployment/app (apps/v1) namespace: default: container "app" uses image "nginx:1.25" from denied registry "docker.io"`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			check := checks.NewAllowedRegistries(true).(preflight.ConfigurableCheck)
+			require.NoError(t, check.SetConfig(tc.config))
+
+			findings := preflighttest.RunCheckOnResources(t, check, []ctlres.Resource{res})
+			require.Equal(t, tc.expectedWarnings, preflighttest.Messages(findings))
+		})
+	}
+}
+
+func TestAllowedRegistriesInvalidConfig(t *testing.T) {
+	check := checks.NewAllowedRegistries(true).(preflight.ConfigurableCheck)
+	require.Error(t, check.SetConfig(preflight.CheckConfig{"allowed": "docker.io"}))
+	require.Error(t, check.SetConfig(preflight.CheckConfig{"denied": []interface{}{""}}))
+}
@@ -0,0 +1,404 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	cmdcore "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/core"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	CascadingDeleteScopeName = "CascadingDeleteScope"
+
+	// Same as used by kapp when deleting resources
+	deleteStrategyAnnKey         = "kapp.k14s.io/delete-strategy"
+	deleteStrategyOrphanAnnValue = "orphan"
+)
+
+var (
+	namespaceGK = schema.GroupKind{Group: "", Kind: "Namespace"}
+	crdGK       = schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}
+
+	// Kinds that are commonly garbage collected via owner references
+	cascadingDeleteChildGVKs = []schema.GroupVersionKind{
+		{Group: "apps", Version: "v1", Kind: "ReplicaSet"},
+		{Group: "apps", Version: "v1", Kind: "ControllerRevision"},
+		{Group: "batch", Version: "v1", Kind: "Job"},
+		{Group: "", Version: "v1", Kind: "Pod"},
+	}
+
+	// Namespaces that kapp never deletes
+	undeletableNamespaces = map[string]struct{}{
+		"default": {}, "kube-node-lease": {}, "kube-public": {}, "kube-system": {},
+	}
+)
+
+// CascadingDeleteScopeConfig is the configuration accepted
+// by the CascadingDeleteScope preflight check
+type CascadingDeleteScopeConfig struct {
+	// MaxDependents is the number of resources that may be
+	// deleted as a side effect of a single delete without a warning
+	MaxDependents int `json:"maxDependents" description:"Number of resources that may be deleted as a side effect of a single delete without a warning"`
+}
+
+// CascadingDeleteScope is an implementation of preflight.Check
+// that estimates how many resources not included in the change would
+// be deleted as a side effect of deleting resources in the change:
+// dependents garbage collected via owner references, contents of
+// deleted namespaces and custom resources of deleted CRDs. When
+// simulating a target Kubernetes version, contents of namespaces
+// only include resources served by that version.
+type CascadingDeleteScope struct {
+	depsFactory   cmdcore.DepsFactory
+	enabled       bool
+	config        CascadingDeleteScopeConfig
+	targetVersion *preflight.KubernetesVersion
+}
+
+var _ preflight.ConfigSchemaCheck = &CascadingDeleteScope{}
+var _ preflight.DescribedCheck = &CascadingDeleteScope{}
+var _ preflight.VersionAwareCheck = &CascadingDeleteScope{}
+
+func NewCascadingDeleteScope(depsFactory cmdcore.DepsFactory, enabled bool) preflight.Check {
+	return &CascadingDeleteScope{
+		depsFactory: depsFactory,
+		enabled:     enabled,
+		config:      CascadingDeleteScopeConfig{MaxDependents: 20},
+	}
+}
+
+func (c *CascadingDeleteScope) Description() string {
+	return "Warns about deletes that would cascade to many resources not included in the change"
+}
+
+func (c *CascadingDeleteScope) Enabled() bool {
+	return c.enabled
+}
+
+func (c *CascadingDeleteScope) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *CascadingDeleteScope) SetConfig(config preflight.CheckConfig) error {
+	newConfig := c.config
+
+	err := config.Decode(&newConfig)
+	if err != nil {
+		return err
+	}
+	if newConfig.MaxDependents < 0 {
+		return fmt.Errorf("expected maxDependents to be non-negative")
+	}
+
+	c.config = newConfig
+	return nil
+}
+
+func (c *CascadingDeleteScope) Config() preflight.CheckConfig {
+	return preflight.NewCheckConfig(c.config)
+}
+
+func (c *CascadingDeleteScope) ConfigSchema() []preflight.ConfigField {
+	return preflight.NewConfigSchema(CascadingDeleteScopeConfig{})
+}
+
+func (c *CascadingDeleteScope) SetTargetVersion(version preflight.KubernetesVersion) {
+	c.targetVersion = &version
+}
+
+func (c *CascadingDeleteScope) Run(ctx context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	var deleted []ctlres.Resource
+	// Resources deleted as part of the change are expected to go away
+	deletedUIDs := map[types.UID]struct{}{}
+
+	for _, change := range changeGraph.All() {
+		res := change.Change.Resource()
+		if change.Change.Op() != ctldgraph.ActualChangeOpDelete {
+			continue
+		}
+		deletedUIDs[types.UID(res.UID())] = struct{}{}
+		if res.Annotations()[deleteStrategyAnnKey] != deleteStrategyOrphanAnnValue {
+			deleted = append(deleted, res)
+		}
+	}
+
+	if len(deleted) == 0 {
+		return nil
+	}
+
+	estimator, err := c.newEstimator(deletedUIDs)
+	if err != nil {
+		return err
+	}
+
+	var findings []error
+
+	for _, res := range deleted {
+		var count int
+		var desc string
+
+		switch res.GroupKind() {
+		case namespaceGK:
+			if _, found := undeletableNamespaces[res.Name()]; found {
+				continue
+			}
+			count, err = estimator.NamespaceContents(ctx, res.Name())
+			desc = "deleting namespace would delete %d resource(s) in it that are not part of the change (threshold %d)"
+
+		case crdGK:
+			count, err = estimator.CustomResources(ctx, res)
+			desc = "deleting CustomResourceDefinition would delete %d custom resource(s) (threshold %d)"
+
+		default:
+			if len(res.Namespace()) == 0 {
+				continue
+			}
+			count, err = estimator.Dependents(ctx, res)
+			desc = "deleting would garbage collect %d dependent resource(s) that are not part of the change (threshold %d)"
+		}
+		if err != nil {
+			return fmt.Errorf("Resource %s: %w", res.Description(), err)
+		}
+
+		if count > c.config.MaxDependents {
+			findings = append(findings, preflight.NewWarning(res, desc, count, c.config.MaxDependents))
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+func (c *CascadingDeleteScope) newEstimator(deletedUIDs map[types.UID]struct{}) (*cascadingDeleteEstimator, error) {
+	dynamicClient, err := c.depsFactory.DynamicClient(cmdcore.DynamicClientOpts{})
+	if err != nil {
+		return nil, err
+	}
+
+	mapper, err := c.depsFactory.RESTMapper()
+	if err != nil {
+		return nil, err
+	}
+
+	return &cascadingDeleteEstimator{
+		depsFactory:   c.depsFactory,
+		targetVersion: c.targetVersion,
+		dynamicClient: dynamicClient,
+		mapper:        mapper,
+		deletedUIDs:   deletedUIDs,
+		childrenByNs:  map[string]map[types.UID][]types.UID{},
+	}, nil
+}
+
+type cascadingDeleteEstimator struct {
+	depsFactory   cmdcore.DepsFactory
+	targetVersion *preflight.KubernetesVersion
+	dynamicClient dynamic.Interface
+	mapper        meta.RESTMapper
+	deletedUIDs   map[types.UID]struct{}
+
+	// childrenByNs caches owner UID to dependent UIDs per namespace
+	childrenByNs map[string]map[types.UID][]types.UID
+}
+
+// Dependents returns number of resources (transitively)
+// owned by the resource that are not deleted explicitly
+func (e *cascadingDeleteEstimator) Dependents(ctx context.Context, res ctlres.Resource) (int, error) {
+	children, err := e.children(ctx, res.Namespace())
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+
+	visited := map[types.UID]struct{}{}
+	queue := []types.UID{types.UID(res.UID())}
+
+	for len(queue) > 0 {
+		uid := queue[0]
+		queue = queue[1:]
+
+		for _, childUID := range children[uid] {
+			if _, found := visited[childUID]; found {
+				continue
+			}
+			visited[childUID] = struct{}{}
+			queue = append(queue, childUID)
+
+			if _, found := e.deletedUIDs[childUID]; !found {
+				count++
+			}
+		}
+	}
+
+	return count, nil
+}
+
+func (e *cascadingDeleteEstimator) children(ctx context.Context, namespace string) (map[types.UID][]types.UID, error) {
+	if children, found := e.childrenByNs[namespace]; found {
+		return children, nil
+	}
+
+	children := map[types.UID][]types.UID{}
+
+	for _, gvk := range cascadingDeleteChildGVKs {
+		mapping, err := e.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		list, err := e.dynamicClient.Resource(mapping.Resource).Namespace(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("Listing %s: %w", mapping.Resource.Resource, err)
+		}
+
+		for _, item := range list.Items {
+			for _, ownerRef := range item.GetOwnerReferences() {
+				children[ownerRef.UID] = append(children[ownerRef.UID], item.GetUID())
+			}
+		}
+	}
+
+	e.childrenByNs[namespace] = children
+	return children, nil
+}
+
+// NamespaceContents returns number of resources in the
+// namespace that are not deleted explicitly. Resources served by
+// multiple groups (e.g. Events via v1 and events.k8s.io/v1) are
+// counted once.
+func (e *cascadingDeleteEstimator) NamespaceContents(ctx context.Context, namespace string) (int, error) {
+	resources, err := e.namespacedResources(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+
+	counted := map[types.UID]struct{}{}
+
+	for _, gvr := range resources {
+		list, err := e.dynamicClient.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return 0, fmt.Errorf("Listing %s: %w", gvr.GroupResource(), err)
+		}
+
+		for _, item := range list.Items {
+			if _, found := counted[item.GetUID()]; found {
+				continue
+			}
+			counted[item.GetUID()] = struct{}{}
+
+			if _, found := e.deletedUIDs[item.GetUID()]; !found {
+				count++
+			}
+		}
+	}
+
+	return count, nil
+}
+
+// namespacedResources returns namespaced resources that can be listed
+// and deleted, each in its preferred version (e.g. Deployments only
+// via apps/v1 so that they are not counted multiple times). Resources
+// without known verbs (e.g. from a discovery file without verbs)
+// are included. Groups that could not be discovered and versions
+// removed in the target Kubernetes version (if any) are skipped.
+func (e *cascadingDeleteEstimator) namespacedResources(ctx context.Context) ([]schema.GroupVersionResource, error) {
+	groups, err := e.depsFactory.APIGroupResources(ctx)
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("Discovering namespaced resources: %w", err)
+	}
+
+	var result []schema.GroupVersionResource
+
+	for _, group := range groups {
+		seen := map[string]struct{}{}
+
+		versions := []string{group.Group.PreferredVersion.Version}
+		for _, version := range group.Group.Versions {
+			versions = append(versions, version.Version)
+		}
+
+		for _, version := range versions {
+			for _, apiRes := range group.VersionedResources[version] {
+				if _, found := seen[apiRes.Name]; found || !apiRes.Namespaced || strings.Contains(apiRes.Name, "/") {
+					continue
+				}
+				gvr := schema.GroupVersionResource{Group: group.Group.Name, Version: version, Resource: apiRes.Name}
+				if e.targetVersion != nil && !e.targetVersion.ServesAPI(gvr) {
+					continue
+				}
+				seen[apiRes.Name] = struct{}{}
+
+				if len(apiRes.Verbs) > 0 && (!apiResourceHasVerb(apiRes, "list") || !apiResourceHasVerb(apiRes, "delete")) {
+					continue
+				}
+				result = append(result, gvr)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// CustomResources returns number of resources served by the CRD
+func (e *cascadingDeleteEstimator) CustomResources(ctx context.Context, res ctlres.Resource) (int, error) {
+	obj := res.UnstructuredObject()
+
+	group, _, _ := unstructured.NestedString(obj, "spec", "group")
+	plural, _, _ := unstructured.NestedString(obj, "spec", "names", "plural")
+	versions, _, _ := unstructured.NestedSlice(obj, "spec", "versions")
+
+	for _, version := range versions {
+		typedVersion, ok := version.(map[string]interface{})
+		if !ok || typedVersion["served"] != true {
+			continue
+		}
+
+		versionName, _ := typedVersion["name"].(string)
+		gvr := schema.GroupVersionResource{Group: group, Version: versionName, Resource: plural}
+
+		list, err := e.dynamicClient.Resource(gvr).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return 0, fmt.Errorf("Listing %s: %w", gvr.GroupResource(), err)
+		}
+
+		var count int
+		for _, item := range list.Items {
+			if _, found := e.deletedUIDs[item.GetUID()]; !found {
+				count++
+			}
+		}
+		// All versions list the same resources
+		return count, nil
+	}
+
+	return 0, nil
+}
+
+func apiResourceHasVerb(apiRes metav1.APIResource, verb string) bool {
+	for _, v := range apiRes.Verbs {
+		if v == verb {
+			return true
+		}
+	}
+	return false
+}
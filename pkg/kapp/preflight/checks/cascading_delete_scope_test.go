@@ -0,0 +1,231 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestCascadingDeleteScope(t *testing.T) {
+	newObj := func(apiVersion, kind, namespace, name, ownerUID string) unstructured.Unstructured {
+		obj := unstructured.Unstructured{}
+		obj.SetAPIVersion(apiVersion)
+		obj.SetKind(kind)
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+		obj.SetUID(types.UID(name + "-uid"))
+		if len(ownerUID) > 0 {
+			obj.SetOwnerReferences([]metav1.OwnerReference{{UID: types.UID(ownerUID)}})
+		}
+		return obj
+	}
+	asRes := func(obj unstructured.Unstructured) ctlres.Resource {
+		return ctlres.NewResourceUnstructured(obj, ctlres.ResourceType{})
+	}
+
+	podsGVR := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	rsGVR := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"}
+	configMapsGVR := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	widgetsGVR := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	widgetsV1beta1GVR := schema.GroupVersionResource{Group: "example.com", Version: "v1beta1", Resource: "widgets"}
+	eventsGVR := schema.GroupVersionResource{Version: "v1", Resource: "events"}
+	eventsV1GVR := schema.GroupVersionResource{Group: "events.k8s.io", Version: "v1", Resource: "events"}
+
+	widgets := []unstructured.Unstructured{
+		newObj("example.com/v1", "Widget", "default", "w1", ""),
+		newObj("example.com/v1", "Widget", "team", "w2", ""),
+		newObj("example.com/v1", "Widget", "other", "w3", ""),
+	}
+	events := []unstructured.Unstructured{
+		newObj("v1", "Event", "team", "e1", ""),
+		newObj("v1", "Event", "team", "e2", ""),
+	}
+
+	dynamicClient := &fakeDynamicClient{
+		objects: map[schema.GroupVersionResource][]unstructured.Unstructured{
+			rsGVR: {
+				newObj("apps/v1", "ReplicaSet", "default", "app-rs", "app-uid"),
+				newObj("apps/v1", "ReplicaSet", "default", "small-rs", "small-uid"),
+			},
+			podsGVR: {
+				newObj("v1", "Pod", "default", "app-pod1", "app-rs-uid"),
+				newObj("v1", "Pod", "default", "app-pod2", "app-rs-uid"),
+				newObj("v1", "Pod", "default", "app-pod3", "app-rs-uid"),
+				newObj("v1", "Pod", "default", "small-pod1", "small-rs-uid"),
+			},
+			configMapsGVR: {
+				newObj("v1", "ConfigMap", "team", "cm1", ""),
+				newObj("v1", "ConfigMap", "team", "cm2", ""),
+				newObj("v1", "ConfigMap", "team", "cm3", ""),
+			},
+			widgetsGVR:        widgets,
+			widgetsV1beta1GVR: widgets,
+			eventsGVR:         events,
+			eventsV1GVR:       events,
+		},
+	}
+	apiGroups := newFakeAPIGroupResources(&metav1.APIResourceList{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: []string{"list", "delete"}},
+			{Name: "configmaps/status", Namespaced: true, Kind: "ConfigMap", Verbs: []string{"get"}},
+			{Name: "bindings", Namespaced: true, Kind: "Binding", Verbs: []string{"create"}},
+			{Name: "namespaces", Namespaced: false, Kind: "Namespace", Verbs: []string{"list", "delete"}},
+			{Name: "events", Namespaced: true, Kind: "Event", Verbs: []string{"list", "delete"}},
+		},
+	}, &metav1.APIResourceList{
+		// Events served by multiple groups are only counted once
+		GroupVersion: "events.k8s.io/v1",
+		APIResources: []metav1.APIResource{{Name: "events", Namespaced: true, Kind: "Event", Verbs: []string{"list", "delete"}}},
+	}, &metav1.APIResourceList{
+		// Resources served in multiple versions are only counted once
+		GroupVersion: "example.com/v1",
+		APIResources: []metav1.APIResource{{Name: "widgets", Namespaced: true, Kind: "Widget"}},
+	}, &metav1.APIResourceList{
+		GroupVersion: "example.com/v1beta1",
+		APIResources: []metav1.APIResource{{Name: "widgets", Namespaced: true, Kind: "Widget"}},
+	})
+	depsFactory := fakeDepsFactory{
+		apiGroups:     apiGroups,
+		dynamicClient: dynamicClient,
+		mapper: newFakeRESTMapper(
+			schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"},
+			schema.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		),
+	}
+
+	crd := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    plural: widgets
+    kind: Widget
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+`))
+
+	orphanedApp := newObj("apps/v1", "Deployment", "default", "app", "")
+	orphanedApp.SetAnnotations(map[string]string{"kapp.k14s.io/delete-strategy": "orphan"})
+
+	testCases := []struct {
+		name             string
+		changes          []preflighttest.Change
+		expectedWarnings []string
+	}{
+		{
+			name: "deletes with dependents above threshold",
+			changes: []preflighttest.Change{
+				{Res: asRes(newObj("apps/v1", "Deployment", "default", "app", "")), ChangeOp: ctldgraph.ActualChangeOpDelete},
+				{Res: asRes(newObj("apps/v1", "Deployment", "default", "small", "")), ChangeOp: ctldgraph.ActualChangeOpDelete},
+				{Res: asRes(newObj("v1", "Namespace", "", "team", "")), ChangeOp: ctldgraph.ActualChangeOpDelete},
+				{Res: asRes(newObj("v1", "Namespace", "", "kube-system", "")), ChangeOp: ctldgraph.ActualChangeOpDelete},
+				{Res: crd, ChangeOp: ctldgraph.ActualChangeOpDelete},
+			},
+			expectedWarnings: []string{
+				"deployment/app (apps/v1) namespace: default: deleting would garbage collect 4 dependent resource(s) " +
+					"that are not part of the change (threshold 2)",
+				"namespace/team (v1) cluster: deleting namespace would delete 6 resource(s) in it " +
+					"that are not part of the change (threshold 2)",
+				"customresourcedefinition/widgets.example.com (apiextensions.k8s.io/v1) cluster: " +
+					"deleting CustomResourceDefinition would delete 3 custom resource(s) (threshold 2)",
+			},
+		},
+		{
+			name: "dependents deleted as part of the change are not counted",
+			changes: []preflighttest.Change{
+				{Res: asRes(newObj("apps/v1", "Deployment", "default", "app", "")), ChangeOp: ctldgraph.ActualChangeOpDelete},
+				{Res: asRes(newObj("v1", "Pod", "default", "app-pod1", "app-rs-uid")), ChangeOp: ctldgraph.ActualChangeOpDelete},
+				{Res: asRes(newObj("v1", "Pod", "default", "app-pod2", "app-rs-uid")), ChangeOp: ctldgraph.ActualChangeOpDelete},
+			},
+		},
+		{
+			name: "orphaned and upserted resources are not checked",
+			changes: []preflighttest.Change{
+				{Res: asRes(orphanedApp), ChangeOp: ctldgraph.ActualChangeOpDelete},
+				{Res: asRes(newObj("v1", "Namespace", "", "team", "")), ChangeOp: ctldgraph.ActualChangeOpUpsert},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			check := checks.NewCascadingDeleteScope(depsFactory, true).(preflight.ConfigurableCheck)
+			require.NoError(t, check.SetConfig(preflight.CheckConfig{"maxDependents": 2}))
+
+			findings := preflighttest.RunCheckOnChanges(t, check, tc.changes...)
+			require.Equal(t, tc.expectedWarnings, preflighttest.Messages(findings))
+		})
+	}
+}
+
+func TestCascadingDeleteScopeTargetVersion(t *testing.T) {
+	newObj := func(apiVersion, kind, name string) unstructured.Unstructured {
+		obj := unstructured.Unstructured{}
+		obj.SetAPIVersion(apiVersion)
+		obj.SetKind(kind)
+		obj.SetNamespace("team")
+		obj.SetName(name)
+		obj.SetUID(types.UID(name + "-uid"))
+		return obj
+	}
+
+	dynamicClient := &fakeDynamicClient{
+		objects: map[schema.GroupVersionResource][]unstructured.Unstructured{
+			{Version: "v1", Resource: "configmaps"}: {
+				newObj("v1", "ConfigMap", "cm1"),
+				newObj("v1", "ConfigMap", "cm2"),
+			},
+			{Group: "batch", Version: "v1beta1", Resource: "cronjobs"}: {
+				newObj("batch/v1beta1", "CronJob", "cron1"),
+				newObj("batch/v1beta1", "CronJob", "cron2"),
+			},
+		},
+	}
+	depsFactory := fakeDepsFactory{
+		dynamicClient: dynamicClient,
+		mapper:        newFakeRESTMapper(),
+		apiGroups: newFakeAPIGroupResources(&metav1.APIResourceList{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{{Name: "configmaps", Namespaced: true, Kind: "ConfigMap"}},
+		}, &metav1.APIResourceList{
+			GroupVersion: "batch/v1beta1",
+			APIResources: []metav1.APIResource{{Name: "cronjobs", Namespaced: true, Kind: "CronJob"}},
+		}),
+	}
+
+	namespace := ctlres.MustNewResourceFromBytes([]byte(`{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "team"}}`))
+
+	check := checks.NewCascadingDeleteScope(depsFactory, true).(preflight.ConfigurableCheck)
+	require.NoError(t, check.SetConfig(preflight.CheckConfig{"maxDependents": 3}))
+
+	findings := preflighttest.RunCheckOnChanges(t, check, preflighttest.Change{Res: namespace, ChangeOp: ctldgraph.ActualChangeOpDelete})
+	require.Equal(t, []string{
+		"namespace/team (v1) cluster: deleting namespace would delete 4 resource(s) in it " +
+			"that are not part of the change (threshold 3)",
+	}, preflighttest.Messages(findings))
+
+	// CronJobs are not served via batch/v1beta1 by Kubernetes 1.25
+	check.(preflight.VersionAwareCheck).SetTargetVersion(preflight.KubernetesVersion{Major: 1, Minor: 25})
+
+	findings = preflighttest.RunCheckOnChanges(t, check, preflighttest.Change{Res: namespace, ChangeOp: ctldgraph.ActualChangeOpDelete})
+	require.Empty(t, preflighttest.Messages(findings))
+}
@@ -0,0 +1,34 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package checks contains built-in preflight checks
+// that can be added to a preflight.Registry
+package checks
+
+import (
+	"sort"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+// clusterOriginalResource returns resource as it currently exists
+// in the cluster if change provides it, otherwise nil
+func clusterOriginalResource(change ctldgraph.ActualChange) ctlres.Resource {
+	changeWithOriginal, ok := change.(interface {
+		ClusterOriginalResource() ctlres.Resource
+	})
+	if !ok {
+		return nil
+	}
+	return changeWithOriginal.ClusterOriginalResource()
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,184 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	cmdcore "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/core"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	ClusterIPConflictName = "ClusterIPConflict"
+)
+
+// ClusterIPConflictConfig is the configuration accepted
+// by the ClusterIPConflict preflight check
+type ClusterIPConflictConfig struct {
+	// CheckCluster enables checking clusterIPs against
+	// Services that already exist in the cluster
+	CheckCluster bool `json:"checkCluster" description:"Check clusterIPs against Services that already exist in the cluster"`
+}
+
+// ClusterIPConflict is an implementation of preflight.Check
+// that warns about Services with an explicit clusterIP that is
+// also specified by another Service in the change or already
+// allocated to a Service in the cluster. Such Services fail to apply.
+type ClusterIPConflict struct {
+	depsFactory cmdcore.DepsFactory
+	enabled     bool
+	config      ClusterIPConflictConfig
+}
+
+var _ preflight.ConfigSchemaCheck = &ClusterIPConflict{}
+var _ preflight.DescribedCheck = &ClusterIPConflict{}
+
+func NewClusterIPConflict(depsFactory cmdcore.DepsFactory, enabled bool) preflight.Check {
+	return &ClusterIPConflict{
+		depsFactory: depsFactory,
+		enabled:     enabled,
+		config:      ClusterIPConflictConfig{CheckCluster: true},
+	}
+}
+
+func (c *ClusterIPConflict) Description() string {
+	return "Warns about Services with explicit clusterIPs conflicting with other Services"
+}
+
+func (c *ClusterIPConflict) Enabled() bool {
+	return c.enabled
+}
+
+func (c *ClusterIPConflict) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *ClusterIPConflict) SetConfig(config preflight.CheckConfig) error {
+	newConfig := c.config
+
+	err := config.Decode(&newConfig)
+	if err != nil {
+		return err
+	}
+
+	c.config = newConfig
+	return nil
+}
+
+func (c *ClusterIPConflict) Config() preflight.CheckConfig {
+	return preflight.NewCheckConfig(c.config)
+}
+
+func (c *ClusterIPConflict) ConfigSchema() []preflight.ConfigField {
+	return preflight.NewConfigSchema(ClusterIPConflictConfig{})
+}
+
+func (c *ClusterIPConflict) Run(ctx context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	services, err := upsertedServices(changeGraph)
+	if err != nil {
+		return err
+	}
+
+	var findings []error
+
+	changed := map[types.NamespacedName]struct{}{}
+	// Value is the Service that specifies the clusterIP
+	clusterIPs := map[string]ctlres.Resource{}
+
+	for _, svc := range services {
+		changed[types.NamespacedName{Namespace: svc.Resource.Namespace(), Name: svc.Resource.Name()}] = struct{}{}
+
+		for _, ip := range explicitClusterIPs(svc.Service) {
+			if claimedBy, found := clusterIPs[ip]; found {
+				findings = append(findings, preflight.NewWarning(svc.Resource,
+					"clusterIP %s conflicts with %s", ip, claimedBy.Description()))
+				continue
+			}
+			clusterIPs[ip] = svc.Resource
+		}
+	}
+
+	if c.config.CheckCluster && len(clusterIPs) > 0 {
+		clusterFindings, err := c.checkClusterIPs(ctx, changeGraph, clusterIPs, changed)
+		if err != nil {
+			return err
+		}
+		findings = append(findings, clusterFindings...)
+	}
+
+	return errors.Join(findings...)
+}
+
+// checkClusterIPs compares clusterIPs against Services in the cluster
+// excluding Services that are updated or deleted as part of the change
+func (c *ClusterIPConflict) checkClusterIPs(ctx context.Context, changeGraph *ctldgraph.ChangeGraph,
+	clusterIPs map[string]ctlres.Resource, changed map[types.NamespacedName]struct{}) ([]error, error) {
+
+	for _, change := range changeGraph.All() {
+		res := change.Change.Resource()
+		if change.Change.Op() == ctldgraph.ActualChangeOpDelete && res.GroupKind() == serviceGK {
+			changed[types.NamespacedName{Namespace: res.Namespace(), Name: res.Name()}] = struct{}{}
+		}
+	}
+
+	client, err := c.depsFactory.CoreClient()
+	if err != nil {
+		return nil, err
+	}
+
+	clusterServices, err := client.CoreV1().Services("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("Listing services: %w", err)
+	}
+
+	var findings []error
+
+	for _, clusterSvc := range clusterServices.Items {
+		name := types.NamespacedName{Namespace: clusterSvc.Namespace, Name: clusterSvc.Name}
+		if _, found := changed[name]; found {
+			continue
+		}
+
+		for _, ip := range explicitClusterIPs(clusterSvc) {
+			if claimedBy, found := clusterIPs[ip]; found {
+				findings = append(findings, preflight.NewWarning(claimedBy,
+					"clusterIP %s is already allocated to existing service %s", ip, name))
+			}
+		}
+	}
+
+	return findings, nil
+}
+
+// explicitClusterIPs returns normalized clusterIPs of a Service
+// (excluding headless Services)
+func explicitClusterIPs(svc corev1.Service) []string {
+	var result []string
+	seen := map[string]struct{}{}
+
+	for _, val := range append([]string{svc.Spec.ClusterIP}, svc.Spec.ClusterIPs...) {
+		if len(val) == 0 || val == corev1.ClusterIPNone {
+			continue
+		}
+		ip := val
+		if parsedIP := net.ParseIP(val); parsedIP != nil {
+			ip = parsedIP.String()
+		}
+		if _, found := seen[ip]; !found {
+			seen[ip] = struct{}{}
+			result = append(result, ip)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,108 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestClusterIPConflict(t *testing.T) {
+	serviceRes := func(name, namespace, clusterIP string) ctlres.Resource {
+		return ctlres.MustNewResourceFromBytes([]byte(fmt.Sprintf(`
+apiVersion: v1
+kind: Service
+metadata:
+  name: %s
+  namespace: %s
+spec:
+  clusterIP: %q
+  ports:
+  - port: 80
+`, name, namespace, clusterIP)))
+	}
+
+	clusterServices := []corev1.Service{{
+		ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "other"},
+		Spec:       corev1.ServiceSpec{ClusterIP: "10.96.0.100", ClusterIPs: []string{"10.96.0.100"}},
+	}, {
+		ObjectMeta: metav1.ObjectMeta{Name: "updated", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{ClusterIP: "10.96.0.200", ClusterIPs: []string{"10.96.0.200"}},
+	}, {
+		ObjectMeta: metav1.ObjectMeta{Name: "headless", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{ClusterIP: corev1.ClusterIPNone},
+	}}
+
+	testCases := []struct {
+		name             string
+		changes          []preflighttest.Change
+		config           preflight.CheckConfig
+		expectedWarnings []string
+	}{
+		{
+			name: "services with distinct, allocated or no clusterIPs, no warnings",
+			changes: []preflighttest.Change{
+				{Res: serviceRes("app1", "default", "10.96.0.1"), ChangeOp: ctldgraph.ActualChangeOpUpsert},
+				{Res: serviceRes("app2", "default", "10.96.0.2"), ChangeOp: ctldgraph.ActualChangeOpUpsert},
+				{Res: serviceRes("app3", "default", ""), ChangeOp: ctldgraph.ActualChangeOpUpsert},
+				{Res: serviceRes("app4", "default", "None"), ChangeOp: ctldgraph.ActualChangeOpUpsert},
+			},
+		},
+		{
+			name: "clusterIP specified by multiple services within the change",
+			changes: []preflighttest.Change{
+				{Res: serviceRes("app1", "default", "10.96.0.1"), ChangeOp: ctldgraph.ActualChangeOpUpsert},
+				{Res: serviceRes("app2", "other", "10.96.0.1"), ChangeOp: ctldgraph.ActualChangeOpUpsert},
+			},
+			expectedWarnings: []string{
+				"service/app2 (v1) namespace: other: clusterIP 10.96.0.1 conflicts with service/app1 (v1) namespace: default",
+			},
+		},
+		{
+			name: "clusterIP allocated to existing service in the cluster",
+			changes: []preflighttest.Change{
+				{Res: serviceRes("app1", "default", "10.96.0.100"), ChangeOp: ctldgraph.ActualChangeOpUpsert},
+			},
+			expectedWarnings: []string{
+				"service/app1 (v1) namespace: default: clusterIP 10.96.0.100 is already allocated to existing service other/existing",
+			},
+		},
+		{
+			name: "clusterIPs of services updated or deleted in the change are not considered allocated",
+			changes: []preflighttest.Change{
+				{Res: serviceRes("updated", "default", "10.96.0.200"), ChangeOp: ctldgraph.ActualChangeOpUpsert},
+				{Res: serviceRes("existing", "other", "10.96.0.100"), ChangeOp: ctldgraph.ActualChangeOpDelete},
+				{Res: serviceRes("app1", "default", "10.96.0.100"), ChangeOp: ctldgraph.ActualChangeOpUpsert},
+			},
+		},
+		{
+			name: "cluster check disabled",
+			changes: []preflighttest.Change{
+				{Res: serviceRes("app1", "default", "10.96.0.100"), ChangeOp: ctldgraph.ActualChangeOpUpsert},
+			},
+			config: preflight.CheckConfig{"checkCluster": false},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			depsFactory := fakeDepsFactory{coreClient: &fakeCoreClient{services: clusterServices}}
+
+			check := checks.NewClusterIPConflict(depsFactory, true).(preflight.ConfigurableCheck)
+			require.NoError(t, check.SetConfig(tc.config))
+
+			findings := preflighttest.RunCheckOnChanges(t, check, tc.changes...)
+			require.Equal(t, tc.expectedWarnings, preflighttest.Messages(findings))
+		})
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	CommandArgsSanityName = "CommandArgsSanity"
+)
+
+// CommandArgsSanityConfig is the configuration accepted
+// by the CommandArgsSanity preflight check
+type CommandArgsSanityConfig struct {
+	// ImagesRequiringCommand lists image repositories (e.g. busybox
+	// or gcr.io/project/app) that do not define an entrypoint, hence
+	// containers using them must specify a command when args are set
+	ImagesRequiringCommand []string `json:"imagesRequiringCommand" description:"Image repositories without an entrypoint that require a command when args are set"`
+}
+
+// CommandArgsSanity is an implementation of preflight.Check
+// that warns about containers with obviously broken command
+// or args (e.g. empty command, empty executable name, or
+// an executable name that contains spaces). Since images are
+// not inspected, image specific expectations come from config.
+type CommandArgsSanity struct {
+	enabled bool
+	config  CommandArgsSanityConfig
+
+	imagesRequiringCommand map[string]struct{}
+}
+
+var _ preflight.ConfigSchemaCheck = &CommandArgsSanity{}
+var _ preflight.DescribedCheck = &CommandArgsSanity{}
+
+func NewCommandArgsSanity(enabled bool) preflight.Check {
+	return &CommandArgsSanity{enabled: enabled}
+}
+
+func (c *CommandArgsSanity) Description() string {
+	return "Warns about containers with obviously broken command or args"
+}
+
+func (c *CommandArgsSanity) Enabled() bool {
+	return c.enabled
+}
+
+func (c *CommandArgsSanity) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *CommandArgsSanity) SetConfig(config preflight.CheckConfig) error {
+	var newConfig CommandArgsSanityConfig
+
+	err := config.Decode(&newConfig)
+	if err != nil {
+		return err
+	}
+
+	images := map[string]struct{}{}
+	for _, image := range newConfig.ImagesRequiringCommand {
+		images[normalizeImageRepository(image)] = struct{}{}
+	}
+
+	c.config = newConfig
+	c.imagesRequiringCommand = images
+	return nil
+}
+
+func (c *CommandArgsSanity) Config() preflight.CheckConfig {
+	return preflight.NewCheckConfig(c.config)
+}
+
+func (c *CommandArgsSanity) ConfigSchema() []preflight.ConfigField {
+	return preflight.NewConfigSchema(CommandArgsSanityConfig{})
+}
+
+func (c *CommandArgsSanity) Run(_ context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	workloads, err := upsertedWorkloads(changeGraph)
+	if err != nil {
+		return err
+	}
+
+	var findings []error
+
+	for _, wl := range workloads {
+		for _, container := range allContainers(wl.Template.Spec) {
+			if problem := c.problem(container); len(problem) > 0 {
+				findings = append(findings, preflight.NewWarning(wl.Resource, "container %q %s", container.Name, problem))
+			}
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+// problem returns description of the first problem found
+// with the container command or args, if any
+func (c *CommandArgsSanity) problem(container corev1.Container) string {
+	switch {
+	case container.Command != nil && len(container.Command) == 0:
+		// Explicitly empty command behaves like unset command,
+		// which is rarely the intent when it is specified
+		return "specifies an empty command"
+
+	case len(container.Command) > 0 && len(strings.TrimSpace(container.Command[0])) == 0:
+		return "specifies an empty executable as the first command element"
+
+	case len(container.Command) > 0 && strings.ContainsAny(strings.TrimSpace(container.Command[0]), " \t"):
+		return fmt.Sprintf("specifies executable %q containing whitespace "+
+			"(command and its arguments are expected to be separate elements)", container.Command[0])
+
+	case len(container.Command) == 0 && len(container.Args) > 0 && c.requiresCommand(container.Image):
+		return fmt.Sprintf("specifies args without a command but image %q is configured to require a command", container.Image)
+	}
+
+	return ""
+}
+
+func (c *CommandArgsSanity) requiresCommand(image string) bool {
+	_, found := c.imagesRequiringCommand[normalizeImageRepository(image)]
+	return found
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+func TestCommandArgsSanity(t *testing.T) {
+	res := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: Pod
+metadata:
+  name: app
+  namespace: default
+spec:
+  initContainers:
+  - name: empty-command
+    image: app
+    command: []
+  containers:
+  - name: valid
+    image: app
+    command: ["/bin/sh", "-c"]
+    args: ["echo hello"]
+  - name: empty-executable
+    image: app
+    command: [""]
+  - name: executable-with-args
+    image: app
+    command: ["/bin/sh -c echo"]
+  - name: args-only
+    image: busybox:1.36
+    args: ["sleep", "10"]
+`))
+
+	testCases := []struct {
+		name             string
+		config           preflight.CheckConfig
+		expectedWarnings []string
+	}{
+		{
+			name: "default config",
+			expectedWarnings: []string{
+				`pod/app (v1) namespace: default: container "empty-command" specifies an empty command`,
+				`pod/app (v1) namespace: default: container "empty-executable" specifies an empty executable as the first command element`,
+				`pod/app (v1) namespace: default: container "executable-with-args" specifies executable "/bin/sh -c echo" ` +
+					`containing whitespace (command and its arguments are expected to be separate elements)`,
+			},
+		},
+		{
+			name:   "images configured to require a command",
+			config: preflight.CheckConfig{"imagesRequiringCommand": []interface{}{"docker.io/library/busybox"}},
+			expectedWarnings: []string{
+				`pod/app (v1) namespace: default: container "empty-command" specifies an empty command`,
+				`pod/app (v1) namespace: default: container "empty-executable" specifies an empty executable as the first command element`,
+				`pod/app (v1) namespace: default: container "executable-with-args" specifies executable "/bin/sh -c echo" ` +
+					`containing whitespace (command and its arguments are expected to be separate elements)`,
+				`pod/app (v1) namespace: default: container "args-only" specifies args without a command ` +
+					`but image "busybox:1.36" is configured to require a command`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			check := checks.NewCommandArgsSanity(true).(preflight.ConfigurableCheck)
+			require.NoError(t, check.SetConfig(tc.config))
+
+			findings := preflighttest.RunCheckOnResources(t, check, []ctlres.Resource{res})
+			require.Equal(t, tc.expectedWarnings, preflighttest.Messages(findings))
+		})
+	}
+}
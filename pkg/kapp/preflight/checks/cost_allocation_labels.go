@@ -0,0 +1,172 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+const (
+	CostAllocationLabelsName = "CostAllocationLabels"
+)
+
+// CostAllocationLabelsConfig is the configuration accepted
+// by the CostAllocationLabels preflight check
+type CostAllocationLabelsConfig struct {
+	Labels      []CostAllocationKey `json:"labels" description:"Required labels"`
+	Annotations []CostAllocationKey `json:"annotations" description:"Required annotations"`
+	// Kinds limits the check to resources of specified kinds.
+	// All resources are checked if empty.
+	Kinds []string `json:"kinds" description:"Kinds of resources to check (all resources if empty)"`
+}
+
+// CostAllocationKey is a required label or annotation
+type CostAllocationKey struct {
+	Key string `json:"key" description:"Label or annotation key"`
+	// Pattern is an optional regular expression that
+	// the value must match (e.g. ^CC-[0-9]{4}$)
+	Pattern string `json:"pattern,omitempty" description:"Regular expression the value must match"`
+}
+
+// CostAllocationLabels is an implementation of preflight.Check
+// that warns about resources missing labels or annotations required
+// for cost allocation (or having values in an unexpected format).
+type CostAllocationLabels struct {
+	enabled bool
+	config  CostAllocationLabelsConfig
+
+	labelPatterns      []*regexp.Regexp
+	annotationPatterns []*regexp.Regexp
+}
+
+var _ preflight.ConfigSchemaCheck = &CostAllocationLabels{}
+var _ preflight.DescribedCheck = &CostAllocationLabels{}
+
+func NewCostAllocationLabels(enabled bool) preflight.Check {
+	return &CostAllocationLabels{enabled: enabled}
+}
+
+func (c *CostAllocationLabels) Description() string {
+	return "Warns about resources missing labels or annotations required for cost allocation"
+}
+
+func (c *CostAllocationLabels) Enabled() bool {
+	return c.enabled
+}
+
+func (c *CostAllocationLabels) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *CostAllocationLabels) SetConfig(config preflight.CheckConfig) error {
+	var newConfig CostAllocationLabelsConfig
+
+	err := config.Decode(&newConfig)
+	if err != nil {
+		return err
+	}
+
+	labelPatterns, err := c.compilePatterns(newConfig.Labels)
+	if err != nil {
+		return fmt.Errorf("labels: %w", err)
+	}
+
+	annotationPatterns, err := c.compilePatterns(newConfig.Annotations)
+	if err != nil {
+		return fmt.Errorf("annotations: %w", err)
+	}
+
+	c.config = newConfig
+	c.labelPatterns = labelPatterns
+	c.annotationPatterns = annotationPatterns
+	return nil
+}
+
+func (c *CostAllocationLabels) Config() preflight.CheckConfig {
+	return preflight.NewCheckConfig(c.config)
+}
+
+func (c *CostAllocationLabels) ConfigSchema() []preflight.ConfigField {
+	return preflight.NewConfigSchema(CostAllocationLabelsConfig{})
+}
+
+func (c *CostAllocationLabels) Run(_ context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	var findings []error
+
+	for _, change := range changeGraph.All() {
+		res := change.Change.Resource()
+
+		if change.Change.Op() != ctldgraph.ActualChangeOpUpsert || !c.checksKind(res) {
+			continue
+		}
+
+		findings = append(findings, c.checkKeys(res, "label", res.Labels(), c.config.Labels, c.labelPatterns)...)
+		findings = append(findings, c.checkKeys(res, "annotation", res.Annotations(), c.config.Annotations, c.annotationPatterns)...)
+	}
+
+	return errors.Join(findings...)
+}
+
+func (c *CostAllocationLabels) checkKeys(res ctlres.Resource, keyType string, values map[string]string,
+	keys []CostAllocationKey, patterns []*regexp.Regexp) []error {
+
+	var findings []error
+
+	for i, key := range keys {
+		val, found := values[key.Key]
+		switch {
+		case !found:
+			findings = append(findings, preflight.NewWarning(res,
+				"missing cost allocation %s %q", keyType, key.Key))
+
+		case patterns[i] != nil && !patterns[i].MatchString(val):
+			findings = append(findings, preflight.NewWarning(res,
+				"cost allocation %s %q value %q does not match pattern %q", keyType, key.Key, val, key.Pattern))
+		}
+	}
+
+	return findings
+}
+
+func (c *CostAllocationLabels) checksKind(res ctlres.Resource) bool {
+	if len(c.config.Kinds) == 0 {
+		return true
+	}
+	for _, kind := range c.config.Kinds {
+		if kind == res.Kind() {
+			return true
+		}
+	}
+	return false
+}
+
+// compilePatterns returns compiled pattern for each
+// key (nil if key does not specify a pattern)
+func (c *CostAllocationLabels) compilePatterns(keys []CostAllocationKey) ([]*regexp.Regexp, error) {
+	var result []*regexp.Regexp
+
+	for _, key := range keys {
+		if len(key.Key) == 0 {
+			return nil, fmt.Errorf("expected key to be non-empty")
+		}
+		if len(key.Pattern) == 0 {
+			result = append(result, nil)
+			continue
+		}
+		pattern, err := regexp.Compile(key.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", key.Key, err)
+		}
+		result = append(result, pattern)
+	}
+
+	return result, nil
+}
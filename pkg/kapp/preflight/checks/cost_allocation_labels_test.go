@@ -0,0 +1,83 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+func TestCostAllocationLabels(t *testing.T) {
+	resources := []ctlres.Resource{
+		ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: compliant
+  namespace: default
+  labels:
+    cost-center: CC-1234
+  annotations:
+    billing.example.com/owner: team-a
+`)),
+		ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: non-compliant
+  namespace: default
+  labels:
+    cost-center: marketing
+`)),
+		ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: default
+`)),
+	}
+
+	config := preflight.CheckConfig{
+		"labels":      []interface{}{map[string]interface{}{"key": "cost-center", "pattern": "^CC-[0-9]{4}$"}},
+		"annotations": []interface{}{map[string]interface{}{"key": "billing.example.com/owner"}},
+	}
+
+	t.Run("all resources are checked by default", func(t *testing.T) {
+		check := checks.NewCostAllocationLabels(true).(preflight.ConfigurableCheck)
+		require.NoError(t, check.SetConfig(config))
+
+		findings := preflighttest.RunCheckOnResources(t, check, resources)
+		require.Equal(t, []string{
+			`deployment/non-compliant (apps/v1) namespace: default: cost allocation label "cost-center" value "marketing" does not match pattern "^CC-[0-9]{4}$"`,
+			`deployment/non-compliant (apps/v1) namespace: default: missing cost allocation annotation "billing.example.com/owner"`,
+			`configmap/config (v1) namespace: default: missing cost allocation label "cost-center"`,
+			`configmap/config (v1) namespace: default: missing cost allocation annotation "billing.example.com/owner"`,
+		}, preflighttest.Messages(findings))
+	})
+
+	t.Run("checked resources are limited by kinds", func(t *testing.T) {
+		check := checks.NewCostAllocationLabels(true).(preflight.ConfigurableCheck)
+		require.NoError(t, check.SetConfig(preflight.CheckConfig{
+			"labels": config["labels"],
+			"kinds":  []interface{}{"ConfigMap"},
+		}))
+
+		findings := preflighttest.RunCheckOnResources(t, check, resources)
+		require.Equal(t, []string{
+			`configmap/config (v1) namespace: default: missing cost allocation label "cost-center"`,
+		}, preflighttest.Messages(findings))
+	})
+
+	t.Run("invalid configuration", func(t *testing.T) {
+		check := checks.NewCostAllocationLabels(true).(preflight.ConfigurableCheck)
+		require.Error(t, check.SetConfig(preflight.CheckConfig{"labels": []interface{}{map[string]interface{}{"key": ""}}}))
+		require.Error(t, check.SetConfig(preflight.CheckConfig{"labels": []interface{}{map[string]interface{}{"key": "a", "pattern": "("}}}))
+	})
+}
@@ -0,0 +1,239 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	cmdcore "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/core"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	CRVersionConsistencyName = "CRVersionConsistency"
+)
+
+var (
+	crdGVR = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+)
+
+// CRVersionConsistency is an implementation of preflight.Check
+// that warns about custom resources using versions that are not
+// defined or not served by their CRD, and about custom resources of
+// the same kind using different versions of which some are not the
+// storage version. CRDs are looked up in the change first and
+// in the cluster otherwise.
+type CRVersionConsistency struct {
+	depsFactory cmdcore.DepsFactory
+	enabled     bool
+}
+
+var _ preflight.DescribedCheck = &CRVersionConsistency{}
+
+func NewCRVersionConsistency(depsFactory cmdcore.DepsFactory, enabled bool) preflight.Check {
+	return &CRVersionConsistency{depsFactory: depsFactory, enabled: enabled}
+}
+
+func (c *CRVersionConsistency) Description() string {
+	return "Warns about custom resources using versions not served by their CRD or mixing non-storage versions"
+}
+
+func (c *CRVersionConsistency) Enabled() bool {
+	return c.enabled
+}
+
+func (c *CRVersionConsistency) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *CRVersionConsistency) Run(ctx context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	crds := map[schema.GroupKind]crdVersions{}
+	customResources := map[schema.GroupKind][]ctlres.Resource{}
+
+	for _, change := range changeGraph.All() {
+		res := change.Change.Resource()
+
+		if change.Change.Op() != ctldgraph.ActualChangeOpUpsert {
+			continue
+		}
+
+		switch {
+		case res.GroupKind() == crdGK:
+			gk, versions := newCRDVersions(res)
+			crds[gk] = versions
+		case (ctlres.CustomResourceMatcher{}).Matches(res):
+			customResources[res.GroupKind()] = append(customResources[res.GroupKind()], res)
+		}
+	}
+
+	var clusterCRDsListed bool
+	var findings []error
+
+	for _, gk := range sortedGroupKinds(customResources) {
+		versions, found := crds[gk]
+		if !found && !clusterCRDsListed {
+			err := c.addClusterCRDs(ctx, crds)
+			if err != nil {
+				return err
+			}
+			clusterCRDsListed = true
+			versions, found = crds[gk]
+		}
+		if !found {
+			// Resource is not served by a CRD (e.g. aggregated API)
+			continue
+		}
+
+		usedVersions := map[string]struct{}{}
+
+		for _, res := range customResources[gk] {
+			version := res.GroupVersion().Version
+			usedVersions[version] = struct{}{}
+
+			ver, found := versions.Find(version)
+			switch {
+			case !found:
+				findings = append(findings, preflight.NewWarning(res,
+					"version %s is not defined by CRD %s (defined versions: %s)", version, versions.CRDName, versions.Names(false)))
+			case !ver.Served:
+				findings = append(findings, preflight.NewWarning(res,
+					"version %s is not served by CRD %s (served versions: %s)", version, versions.CRDName, versions.Names(true)))
+			}
+		}
+
+		storageVersion, found := versions.Storage()
+		if len(usedVersions) < 2 || !found {
+			continue
+		}
+
+		for _, res := range customResources[gk] {
+			version := res.GroupVersion().Version
+			if ver, found := versions.Find(version); !found || !ver.Served || version == storageVersion {
+				continue
+			}
+			findings = append(findings, preflight.NewWarning(res,
+				"version %s is not the storage version %s of CRD %s while %s resources in the change use versions %s",
+				version, storageVersion, versions.CRDName, gk.Kind, strings.Join(sortedSetKeys(usedVersions), ", ")))
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+// addClusterCRDs adds CRDs found in the cluster unless
+// they are already included (i.e. are part of the change)
+func (c *CRVersionConsistency) addClusterCRDs(ctx context.Context, crds map[schema.GroupKind]crdVersions) error {
+	dynamicClient, err := c.depsFactory.DynamicClient(cmdcore.DynamicClientOpts{})
+	if err != nil {
+		return err
+	}
+
+	list, err := dynamicClient.Resource(crdGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("Listing custom resource definitions: %w", err)
+	}
+
+	for _, item := range list.Items {
+		gk, versions := newCRDVersions(ctlres.NewResourceUnstructured(item, ctlres.ResourceType{}))
+		if _, found := crds[gk]; !found {
+			crds[gk] = versions
+		}
+	}
+
+	return nil
+}
+
+type crdVersion struct {
+	Name    string
+	Served  bool
+	Storage bool
+}
+
+type crdVersions struct {
+	CRDName  string
+	Versions []crdVersion
+}
+
+func newCRDVersions(crd ctlres.Resource) (schema.GroupKind, crdVersions) {
+	obj := crd.UnstructuredObject()
+
+	group, _, _ := unstructured.NestedString(obj, "spec", "group")
+	kind, _, _ := unstructured.NestedString(obj, "spec", "names", "kind")
+	versions, _, _ := unstructured.NestedSlice(obj, "spec", "versions")
+
+	result := crdVersions{CRDName: crd.Name()}
+
+	for _, version := range versions {
+		typedVersion, ok := version.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := typedVersion["name"].(string)
+		served, _ := typedVersion["served"].(bool)
+		storage, _ := typedVersion["storage"].(bool)
+
+		result.Versions = append(result.Versions, crdVersion{Name: name, Served: served, Storage: storage})
+	}
+
+	return schema.GroupKind{Group: group, Kind: kind}, result
+}
+
+func (v crdVersions) Find(name string) (crdVersion, bool) {
+	for _, ver := range v.Versions {
+		if ver.Name == name {
+			return ver, true
+		}
+	}
+	return crdVersion{}, false
+}
+
+func (v crdVersions) Storage() (string, bool) {
+	for _, ver := range v.Versions {
+		if ver.Storage {
+			return ver.Name, true
+		}
+	}
+	return "", false
+}
+
+// Names returns comma separated names of (served) versions
+func (v crdVersions) Names(servedOnly bool) string {
+	var result []string
+	for _, ver := range v.Versions {
+		if ver.Served || !servedOnly {
+			result = append(result, ver.Name)
+		}
+	}
+	if len(result) == 0 {
+		return "none"
+	}
+	return strings.Join(result, ", ")
+}
+
+func sortedGroupKinds(m map[schema.GroupKind][]ctlres.Resource) []schema.GroupKind {
+	result := make([]schema.GroupKind, 0, len(m))
+	for gk := range m {
+		result = append(result, gk)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].String() < result[j].String() })
+	return result
+}
+
+func sortedSetKeys(m map[string]struct{}) []string {
+	result := make([]string, 0, len(m))
+	for key := range m {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCRVersionConsistency(t *testing.T) {
+	widgetsCRD := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    plural: widgets
+  versions:
+  - name: v1
+    served: true
+    storage: true
+  - name: v1beta1
+    served: true
+    storage: false
+  - name: v1alpha1
+    served: false
+    storage: false
+`))
+	// Gadgets CRD is only found in the cluster
+	gadgetsCRD := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: gadgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Gadget
+    plural: gadgets
+  versions:
+  - name: v2
+    served: true
+    storage: true
+`))
+
+	newCR := func(apiVersion, kind, name string) ctlres.Resource {
+		return ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: ` + apiVersion + `
+kind: ` + kind + `
+metadata:
+  name: ` + name + `
+  namespace: default
+`))
+	}
+
+	resources := []ctlres.Resource{
+		widgetsCRD,
+		newCR("example.com/v1", "Widget", "stored"),
+		newCR("example.com/v1beta1", "Widget", "converted"),
+		newCR("example.com/v1alpha1", "Widget", "not-served"),
+		newCR("example.com/v2", "Gadget", "gadget"),
+		newCR("example.com/v1", "Gadget", "undefined"),
+		newCR("other.example.com/v1", "Unknown", "aggregated"),
+		newCR("apps/v1", "Deployment", "builtin"),
+	}
+
+	crdGVR := schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+	dynamicClient := &fakeDynamicClient{objects: map[schema.GroupVersionResource][]unstructured.Unstructured{
+		crdGVR: {{Object: gadgetsCRD.UnstructuredObject()}},
+	}}
+
+	findings := preflighttest.RunCheckOnResources(t,
+		checks.NewCRVersionConsistency(fakeDepsFactory{dynamicClient: dynamicClient}, true), resources)
+
+	require.Equal(t, []string{
+		`gadget/undefined (example.com/v1) namespace: default: version v1 is not defined by CRD gadgets.example.com (defined versions: v2)`,
+		`widget/not-served (example.com/v1alpha1) namespace: default: version v1alpha1 is not served by CRD widgets.example.com (served versions: v1, v1beta1)`,
+		`widget/converted (example.com/v1beta1) namespace: default: version v1beta1 is not the storage version v1 of CRD widgets.example.com ` +
+			`while Widget resources in the change use versions v1, v1alpha1, v1beta1`,
+	}, preflighttest.Messages(findings))
+}
@@ -0,0 +1,153 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	EmptyDirLimitsName = "EmptyDirLimits"
+)
+
+// EmptyDirLimitsConfig is the configuration accepted
+// by the EmptyDirLimits preflight check
+type EmptyDirLimitsConfig struct {
+	// MaxSizeLimit is the largest acceptable sizeLimit
+	// of a disk backed emptyDir (e.g. 10Gi)
+	MaxSizeLimit string `json:"maxSizeLimit" description:"Largest acceptable sizeLimit of a disk backed emptyDir (e.g. 10Gi)"`
+	// RequireMemorySizeLimit requires memory backed
+	// emptyDirs to specify a sizeLimit
+	RequireMemorySizeLimit bool `json:"requireMemorySizeLimit" description:"Require memory backed emptyDirs to specify a sizeLimit"`
+}
+
+// EmptyDirLimits is an implementation of preflight.Check that
+// warns about emptyDir volumes that may result in pod eviction:
+// disk backed volumes with large size limits and memory backed
+// volumes that are unbounded or exceed container memory limits
+// (memory backed volumes count towards memory usage of the pod).
+type EmptyDirLimits struct {
+	enabled bool
+	config  EmptyDirLimitsConfig
+
+	maxSizeLimit resource.Quantity
+}
+
+var _ preflight.ConfigSchemaCheck = &EmptyDirLimits{}
+var _ preflight.DescribedCheck = &EmptyDirLimits{}
+
+func NewEmptyDirLimits(enabled bool) preflight.Check {
+	check := &EmptyDirLimits{enabled: enabled}
+
+	err := check.SetConfig(preflight.CheckConfig{"maxSizeLimit": "10Gi", "requireMemorySizeLimit": true})
+	if err != nil {
+		panic(fmt.Sprintf("Internal inconsistency: default config of %s: %s", EmptyDirLimitsName, err))
+	}
+
+	return check
+}
+
+func (c *EmptyDirLimits) Description() string {
+	return "Warns about emptyDir volumes with risky size limits or unbounded memory usage"
+}
+
+func (c *EmptyDirLimits) Enabled() bool {
+	return c.enabled
+}
+
+func (c *EmptyDirLimits) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *EmptyDirLimits) SetConfig(config preflight.CheckConfig) error {
+	newConfig := c.config
+
+	err := config.Decode(&newConfig)
+	if err != nil {
+		return err
+	}
+
+	maxSizeLimit, err := resource.ParseQuantity(newConfig.MaxSizeLimit)
+	if err != nil {
+		return fmt.Errorf("parsing maxSizeLimit: %w", err)
+	}
+
+	c.config = newConfig
+	c.maxSizeLimit = maxSizeLimit
+	return nil
+}
+
+func (c *EmptyDirLimits) Config() preflight.CheckConfig {
+	return preflight.NewCheckConfig(c.config)
+}
+
+func (c *EmptyDirLimits) ConfigSchema() []preflight.ConfigField {
+	return preflight.NewConfigSchema(EmptyDirLimitsConfig{})
+}
+
+func (c *EmptyDirLimits) Run(_ context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	workloads, err := upsertedWorkloads(changeGraph)
+	if err != nil {
+		return err
+	}
+
+	var findings []error
+
+	for _, wl := range workloads {
+		podSpec := wl.Template.Spec
+
+		for _, vol := range podSpec.Volumes {
+			emptyDir := vol.EmptyDir
+			if emptyDir == nil {
+				continue
+			}
+
+			if emptyDir.Medium != corev1.StorageMediumMemory {
+				if emptyDir.SizeLimit != nil && emptyDir.SizeLimit.Cmp(c.maxSizeLimit) > 0 {
+					findings = append(findings, preflight.NewWarning(wl.Resource,
+						"emptyDir volume %q sizeLimit %s exceeds maximum %s", vol.Name, emptyDir.SizeLimit, c.config.MaxSizeLimit))
+				}
+				continue
+			}
+
+			switch memoryLimits, limited := c.totalMemoryLimits(podSpec); {
+			case emptyDir.SizeLimit == nil:
+				if c.config.RequireMemorySizeLimit {
+					findings = append(findings, preflight.NewWarning(wl.Resource,
+						"memory backed emptyDir volume %q has no sizeLimit", vol.Name))
+				}
+
+			case limited && emptyDir.SizeLimit.Cmp(memoryLimits) > 0:
+				findings = append(findings, preflight.NewWarning(wl.Resource,
+					"memory backed emptyDir volume %q sizeLimit %s exceeds total container memory limits %s",
+					vol.Name, emptyDir.SizeLimit, &memoryLimits))
+			}
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+// totalMemoryLimits returns sum of memory limits of all containers.
+// Returns false if any container does not specify a memory limit.
+func (c *EmptyDirLimits) totalMemoryLimits(podSpec corev1.PodSpec) (resource.Quantity, bool) {
+	var total resource.Quantity
+
+	for _, container := range podSpec.Containers {
+		limit, found := container.Resources.Limits[corev1.ResourceMemory]
+		if !found {
+			return resource.Quantity{}, false
+		}
+		total.Add(limit)
+	}
+
+	return total, len(podSpec.Containers) > 0
+}
@@ -0,0 +1,94 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+func TestEmptyDirLimits(t *testing.T) {
+	res := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: default
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        resources:
+          limits:
+            memory: 512Mi
+      - name: sidecar
+        resources:
+          limits:
+            memory: 512Mi
+      volumes:
+      - name: config
+        configMap:
+          name: config
+      - name: small-scratch
+        emptyDir:
+          sizeLimit: 1Gi
+      - name: large-scratch
+        emptyDir:
+          sizeLimit: 50Gi
+      - name: unbounded-cache
+        emptyDir:
+          medium: Memory
+      - name: small-cache
+        emptyDir:
+          medium: Memory
+          sizeLimit: 256Mi
+      - name: large-cache
+        emptyDir:
+          medium: Memory
+          sizeLimit: 2Gi
+`))
+
+	testCases := []struct {
+		name             string
+		config           preflight.CheckConfig
+		expectedWarnings []string
+	}{
+		{
+			name: "default thresholds",
+			expectedWarnings: []string{
+				`deployment/app (apps/v1) namespace: default: emptyDir volume "large-scratch" sizeLimit 50Gi exceeds maximum 10Gi`,
+				`deployment/app (apps/v1) namespace: default: memory backed emptyDir volume "unbounded-cache" has no sizeLimit`,
+				`deployment/app (apps/v1) namespace: default: memory backed emptyDir volume "large-cache" sizeLimit 2Gi exceeds total container memory limits 1Gi`,
+			},
+		},
+		{
+			name:   "configured thresholds",
+			config: preflight.CheckConfig{"maxSizeLimit": "100Gi", "requireMemorySizeLimit": false},
+			expectedWarnings: []string{
+				`deployment/app (apps/v1) namespace: default: memory backed emptyDir volume "large-cache" sizeLimit 2Gi exceeds total container memory limits 1Gi`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			check := checks.NewEmptyDirLimits(true).(preflight.ConfigurableCheck)
+			require.NoError(t, check.SetConfig(tc.config))
+
+			findings := preflighttest.RunCheckOnResources(t, check, []ctlres.Resource{res})
+			require.Equal(t, tc.expectedWarnings, preflighttest.Messages(findings))
+		})
+	}
+}
+
+func TestEmptyDirLimitsInvalidConfig(t *testing.T) {
+	check := checks.NewEmptyDirLimits(true).(preflight.ConfigurableCheck)
+	require.Error(t, check.SetConfig(preflight.CheckConfig{"maxSizeLimit": "lots"}))
+}
@@ -0,0 +1,194 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	cmdcore "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/core"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	ExternalTrafficPolicyLocalName = "ExternalTrafficPolicyLocal"
+)
+
+// ExternalTrafficPolicyLocalConfig is the configuration accepted
+// by the ExternalTrafficPolicyLocal preflight check
+type ExternalTrafficPolicyLocalConfig struct {
+	// CheckNodes compares number of backing pods with
+	// number of schedulable nodes in the cluster
+	CheckNodes bool `json:"checkNodes" description:"Compare number of backing pods with number of schedulable nodes in the cluster"`
+	// MinReplicas is the minimum number of backing replicas
+	// when nodes are not checked
+	MinReplicas int32 `json:"minReplicas" description:"Minimum number of backing replicas when nodes are not checked"`
+}
+
+// ExternalTrafficPolicyLocal is an implementation of preflight.Check
+// that warns about LoadBalancer and NodePort Services with
+// externalTrafficPolicy Local backed by workloads (in the same change)
+// that will not run on all nodes. Traffic arriving at nodes
+// without backing pods is dropped.
+type ExternalTrafficPolicyLocal struct {
+	depsFactory cmdcore.DepsFactory
+	enabled     bool
+	config      ExternalTrafficPolicyLocalConfig
+}
+
+var _ preflight.ConfigSchemaCheck = &ExternalTrafficPolicyLocal{}
+var _ preflight.DescribedCheck = &ExternalTrafficPolicyLocal{}
+
+func NewExternalTrafficPolicyLocal(depsFactory cmdcore.DepsFactory, enabled bool) preflight.Check {
+	return &ExternalTrafficPolicyLocal{
+		depsFactory: depsFactory,
+		enabled:     enabled,
+		config: ExternalTrafficPolicyLocalConfig{
+			CheckNodes:  true,
+			MinReplicas: 2,
+		},
+	}
+}
+
+func (c *ExternalTrafficPolicyLocal) Description() string {
+	return "Warns about Services with externalTrafficPolicy Local backed by workloads that do not run on all nodes"
+}
+
+func (c *ExternalTrafficPolicyLocal) Enabled() bool {
+	return c.enabled
+}
+
+func (c *ExternalTrafficPolicyLocal) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *ExternalTrafficPolicyLocal) SetConfig(config preflight.CheckConfig) error {
+	newConfig := c.config
+
+	err := config.Decode(&newConfig)
+	if err != nil {
+		return err
+	}
+	if newConfig.MinReplicas < 0 {
+		return fmt.Errorf("expected minReplicas to be non-negative")
+	}
+
+	c.config = newConfig
+	return nil
+}
+
+func (c *ExternalTrafficPolicyLocal) Config() preflight.CheckConfig {
+	return preflight.NewCheckConfig(c.config)
+}
+
+func (c *ExternalTrafficPolicyLocal) ConfigSchema() []preflight.ConfigField {
+	return preflight.NewConfigSchema(ExternalTrafficPolicyLocalConfig{})
+}
+
+func (c *ExternalTrafficPolicyLocal) Run(ctx context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	services, err := upsertedServices(changeGraph)
+	if err != nil {
+		return err
+	}
+
+	workloads, err := upsertedWorkloads(changeGraph)
+	if err != nil {
+		return err
+	}
+
+	var nodes []corev1.Node
+	var findings []error
+
+	for _, svc := range services {
+		if !c.isLocalExternalService(svc.Service) {
+			continue
+		}
+
+		selector := labels.SelectorFromSet(svc.Service.Spec.Selector)
+
+		for _, wl := range workloads {
+			if !c.isLongRunning(wl) || wl.Resource.Namespace() != svc.Resource.Namespace() ||
+				!selector.Matches(labels.Set(wl.Template.Labels)) {
+				continue
+			}
+
+			// Only list nodes once a relevant workload is found
+			if c.config.CheckNodes && nodes == nil {
+				nodes, err = listNodes(ctx, c.depsFactory)
+				if err != nil {
+					return err
+				}
+			}
+
+			if msg, found := c.coverageGap(wl, nodes); found {
+				findings = append(findings, preflight.NewWarning(svc.Resource,
+					"externalTrafficPolicy Local drops traffic on nodes without pods: backing %s %s",
+					wl.Resource.Description(), msg))
+			}
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+func (c *ExternalTrafficPolicyLocal) isLocalExternalService(svc corev1.Service) bool {
+	switch svc.Spec.Type {
+	case corev1.ServiceTypeLoadBalancer, corev1.ServiceTypeNodePort:
+	default:
+		return false
+	}
+	// Services without selector do not select pods of workloads
+	return svc.Spec.ExternalTrafficPolicy == corev1.ServiceExternalTrafficPolicyLocal &&
+		len(svc.Spec.Selector) > 0
+}
+
+func (c *ExternalTrafficPolicyLocal) isLongRunning(wl workload) bool {
+	gk := wl.Resource.GroupKind()
+	return gk != jobGK && gk != cronJobGK
+}
+
+// coverageGap describes why workload pods are not going to run on
+// all nodes. Nodes are nil if they are not checked.
+func (c *ExternalTrafficPolicyLocal) coverageGap(wl workload, nodes []corev1.Node) (string, bool) {
+	nodeSelector := wl.Template.Spec.NodeSelector
+
+	if wl.Resource.GroupKind() == daemonSetGK {
+		if len(nodeSelector) == 0 {
+			return "", false
+		}
+		if nodes == nil {
+			return "runs only on nodes matching its node selector", true
+		}
+		numAll := len(schedulableNodes(nodes, nil))
+		numSelected := len(schedulableNodes(nodes, nodeSelector))
+		if numSelected < numAll {
+			return fmt.Sprintf("runs on %d of %d schedulable node(s)", numSelected, numAll), true
+		}
+		return "", false
+	}
+
+	replicas := wl.ReplicaCount()
+
+	if nodes == nil {
+		if replicas < c.config.MinReplicas {
+			return fmt.Sprintf("has %d replica(s) (minimum %d)", replicas, c.config.MinReplicas), true
+		}
+		return "", false
+	}
+
+	numNodes := len(schedulableNodes(nodes, nil))
+	if int(replicas) < numNodes {
+		return fmt.Sprintf("has %d replica(s) for %d schedulable node(s)", replicas, numNodes), true
+	}
+	if len(nodeSelector) > 0 {
+		if numSelected := len(schedulableNodes(nodes, nodeSelector)); numSelected < numNodes {
+			return fmt.Sprintf("is limited to %d of %d schedulable node(s) by its node selector", numSelected, numNodes), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,137 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestExternalTrafficPolicyLocal(t *testing.T) {
+	nodes := []corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"pool": "a"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: map[string]string{"pool": "a"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node3", Labels: map[string]string{"pool": "b"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node4"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+	}
+
+	serviceYAML := func(svcType, policy string) string {
+		return `
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+  namespace: default
+spec:
+  type: ` + svcType + `
+  externalTrafficPolicy: ` + policy + `
+  selector:
+    app: app
+  ports:
+  - port: 80
+`
+	}
+
+	workloadYAML := func(kind, replicas, nodeSelector string) string {
+		return `
+apiVersion: apps/v1
+kind: ` + kind + `
+metadata:
+  name: app
+  namespace: default
+spec:
+  replicas: ` + replicas + `
+  template:
+    metadata:
+      labels:
+        app: app
+    spec:
+      nodeSelector: ` + nodeSelector + `
+`
+	}
+
+	testCases := []struct {
+		name             string
+		resYAMLs         []string
+		config           preflight.CheckConfig
+		expectedWarnings []string
+	}{
+		{
+			name:     "replicas cover all schedulable nodes",
+			resYAMLs: []string{serviceYAML("LoadBalancer", "Local"), workloadYAML("Deployment", "3", "{}")},
+		},
+		{
+			name:     "fewer replicas than schedulable nodes",
+			resYAMLs: []string{serviceYAML("LoadBalancer", "Local"), workloadYAML("Deployment", "2", "{}")},
+			expectedWarnings: []string{
+				"service/app (v1) namespace: default: externalTrafficPolicy Local drops traffic on nodes without pods: " +
+					"backing deployment/app (apps/v1) namespace: default has 2 replica(s) for 3 schedulable node(s)",
+			},
+		},
+		{
+			name:     "replicas limited by node selector",
+			resYAMLs: []string{serviceYAML("NodePort", "Local"), workloadYAML("Deployment", "3", "{pool: a}")},
+			expectedWarnings: []string{
+				"service/app (v1) namespace: default: externalTrafficPolicy Local drops traffic on nodes without pods: " +
+					"backing deployment/app (apps/v1) namespace: default is limited to 2 of 3 schedulable node(s) by its node selector",
+			},
+		},
+		{
+			name:     "daemonset without node selector runs on all nodes",
+			resYAMLs: []string{serviceYAML("LoadBalancer", "Local"), workloadYAML("DaemonSet", "null", "{}")},
+		},
+		{
+			name:     "daemonset with node selector",
+			resYAMLs: []string{serviceYAML("LoadBalancer", "Local"), workloadYAML("DaemonSet", "null", "{pool: b}")},
+			expectedWarnings: []string{
+				"service/app (v1) namespace: default: externalTrafficPolicy Local drops traffic on nodes without pods: " +
+					"backing daemonset/app (apps/v1) namespace: default runs on 1 of 3 schedulable node(s)",
+			},
+		},
+		{
+			name:     "nodes are not checked",
+			resYAMLs: []string{serviceYAML("LoadBalancer", "Local"), workloadYAML("Deployment", "1", "{}")},
+			config:   preflight.CheckConfig{"checkNodes": false},
+			expectedWarnings: []string{
+				"service/app (v1) namespace: default: externalTrafficPolicy Local drops traffic on nodes without pods: " +
+					"backing deployment/app (apps/v1) namespace: default has 1 replica(s) (minimum 2)",
+			},
+		},
+		{
+			name:     "cluster traffic policy is ignored",
+			resYAMLs: []string{serviceYAML("LoadBalancer", "Cluster"), workloadYAML("Deployment", "1", "{}")},
+		},
+		{
+			name:     "cluster ip services are ignored",
+			resYAMLs: []string{serviceYAML("ClusterIP", "Local"), workloadYAML("Deployment", "1", "{}")},
+		},
+		{
+			name:     "services without backing workloads in the change are ignored",
+			resYAMLs: []string{serviceYAML("LoadBalancer", "Local")},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var resources []ctlres.Resource
+			for _, resYAML := range tc.resYAMLs {
+				resources = append(resources, ctlres.MustNewResourceFromBytes([]byte(resYAML)))
+			}
+			depsFactory := fakeDepsFactory{coreClient: &fakeCoreClient{nodes: nodes}}
+
+			check := checks.NewExternalTrafficPolicyLocal(depsFactory, true).(preflight.ConfigurableCheck)
+			require.NoError(t, check.SetConfig(tc.config))
+
+			findings := preflighttest.RunCheckOnResources(t, check, resources)
+			require.Equal(t, tc.expectedWarnings, preflighttest.Messages(findings))
+		})
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	FieldManagerConflictName = "FieldManagerConflict"
+
+	kappFieldManager = "kapp"
+)
+
+// FieldManagerConflict is an implementation of preflight.Check
+// that warns when an updated resource sets fields (to a different value)
+// that are currently owned by another field manager. Such fields
+// would result in conflicts once server-side apply is used.
+type FieldManagerConflict struct {
+	enabled bool
+}
+
+var _ preflight.DescribedCheck = &FieldManagerConflict{}
+
+func NewFieldManagerConflict(enabled bool) preflight.Check {
+	return &FieldManagerConflict{enabled: enabled}
+}
+
+func (c *FieldManagerConflict) Description() string {
+	return "Warns about updated fields currently owned by other field managers"
+}
+
+func (c *FieldManagerConflict) Enabled() bool {
+	return c.enabled
+}
+
+func (c *FieldManagerConflict) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *FieldManagerConflict) Run(_ context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	var findings []error
+
+	for _, change := range changeGraph.All() {
+		if change.Change.Op() != ctldgraph.ActualChangeOpUpsert {
+			continue
+		}
+
+		existingRes := clusterOriginalResource(change.Change)
+		if existingRes == nil {
+			continue
+		}
+
+		contested, err := c.contestedFields(change.Change.Resource(), existingRes)
+		if err != nil {
+			return fmt.Errorf("Resource %s: %w", existingRes.Description(), err)
+		}
+
+		for _, manager := range sortedKeys(contested) {
+			findings = append(findings, preflight.NewWarning(change.Change.Resource(),
+				"fields owned by field manager %q would conflict: %s", manager, strings.Join(contested[manager], ", ")))
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+// contestedFields returns fields (grouped by field manager) that newRes sets
+// to a value different from existingRes and that are owned by other managers
+func (c *FieldManagerConflict) contestedFields(newRes, existingRes ctlres.Resource) (map[string][]string, error) {
+	newFields := settableFields(newRes.UnstructuredObject())
+	existingFields := settableFields(existingRes.UnstructuredObject())
+
+	managedFields := (&unstructured.Unstructured{Object: existingRes.UnstructuredObject()}).GetManagedFields()
+	result := map[string][]string{}
+
+	for _, entry := range managedFields {
+		if entry.Manager == kappFieldManager || entry.FieldsV1 == nil {
+			continue
+		}
+
+		var fieldsV1 map[string]interface{}
+		err := json.Unmarshal(entry.FieldsV1.Raw, &fieldsV1)
+		if err != nil {
+			return nil, fmt.Errorf("Parsing managed fields of manager %q: %w", entry.Manager, err)
+		}
+
+		owned := map[string]struct{}{}
+		ownedFieldPaths(fieldsV1, "", owned)
+
+		for path, newVal := range newFields {
+			if _, found := owned[path]; !found {
+				continue
+			}
+			if existingVal, found := existingFields[path]; found && isSubset(newVal, existingVal) {
+				continue
+			}
+			result[entry.Manager] = append(result[entry.Manager], path)
+		}
+	}
+
+	for manager := range result {
+		sort.Strings(result[manager])
+	}
+
+	return result, nil
+}
+
+// settableFields flattens a resource into leaf field paths
+// (lists are treated as leaves) ignoring identity and status fields
+func settableFields(obj map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+
+	for key, val := range obj {
+		switch key {
+		case "apiVersion", "kind", "status":
+			continue
+		case "metadata":
+			meta, _ := val.(map[string]interface{})
+			for _, metaKey := range []string{"labels", "annotations"} {
+				if metaVal, found := meta[metaKey]; found {
+					flattenFields(metaVal, ".metadata."+metaKey, result)
+				}
+			}
+		default:
+			flattenFields(val, "."+key, result)
+		}
+	}
+
+	return result
+}
+
+func flattenFields(val interface{}, path string, result map[string]interface{}) {
+	typedVal, ok := val.(map[string]interface{})
+	if !ok || len(typedVal) == 0 {
+		result[path] = val
+		return
+	}
+	for key, subVal := range typedVal {
+		flattenFields(subVal, path+"."+key, result)
+	}
+}
+
+// ownedFieldPaths collects field paths from managedFields entry of FieldsV1 type.
+// Ownership of list items (k:, v:, i: keys) is attributed to the list field itself.
+func ownedFieldPaths(fields map[string]interface{}, path string, result map[string]struct{}) {
+	if len(fields) == 0 {
+		result[path] = struct{}{}
+		return
+	}
+	for key, val := range fields {
+		switch {
+		case key == ".":
+			result[path] = struct{}{}
+		case strings.HasPrefix(key, "f:"):
+			subFields, _ := val.(map[string]interface{})
+			ownedFieldPaths(subFields, path+"."+strings.TrimPrefix(key, "f:"), result)
+		default:
+			result[path] = struct{}{}
+		}
+	}
+}
+
+// isSubset returns true if all values in expected are present in actual.
+// It allows actual values to include server defaulted fields.
+func isSubset(expected, actual interface{}) bool {
+	switch typedExpected := expected.(type) {
+	case map[string]interface{}:
+		typedActual, ok := actual.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, val := range typedExpected {
+			if !isSubset(val, typedActual[key]) {
+				return false
+			}
+		}
+		return true
+
+	case []interface{}:
+		typedActual, ok := actual.([]interface{})
+		if !ok || len(typedExpected) != len(typedActual) {
+			return false
+		}
+		for i, val := range typedExpected {
+			if !isSubset(val, typedActual[i]) {
+				return false
+			}
+		}
+		return true
+
+	case string:
+		// Server normalizes quantities (e.g. 1000m becomes 1)
+		if typedActual, ok := actual.(string); ok && typedExpected != typedActual {
+			expectedQty, err1 := resource.ParseQuantity(typedExpected)
+			actualQty, err2 := resource.ParseQuantity(typedActual)
+			return err1 == nil && err2 == nil && expectedQty.Cmp(actualQty) == 0
+		}
+		return fmt.Sprintf("%v", expected) == fmt.Sprintf("%v", actual)
+
+	default:
+		return fmt.Sprintf("%v", expected) == fmt.Sprintf("%v", actual)
+	}
+}
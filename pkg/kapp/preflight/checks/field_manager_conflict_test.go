@@ -0,0 +1,139 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+func TestFieldManagerConflict(t *testing.T) {
+	existingRes := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: default
+  labels:
+    team: a
+  managedFields:
+  - manager: kubectl-client-side-apply
+    operation: Update
+    fieldsType: FieldsV1
+    fieldsV1:
+      f:metadata:
+        f:labels:
+          .: {}
+          f:team: {}
+      f:spec:
+        f:replicas: {}
+        f:template:
+          f:spec:
+            f:containers:
+              k:{"name":"app"}:
+                .: {}
+                f:image: {}
+  - manager: kapp
+    operation: Update
+    fieldsType: FieldsV1
+    fieldsV1:
+      f:spec:
+        f:paused: {}
+spec:
+  replicas: 1
+  paused: false
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:v1
+        imagePullPolicy: IfNotPresent
+        resources:
+          limits:
+            cpu: "1"
+`))
+
+	testCases := []struct {
+		name             string
+		newResYAML       string
+		op               ctldgraph.ActualChangeOp
+		expectedWarnings []string
+	}{
+		{
+			name: "fields owned by other manager set to same or equivalent values, no warnings",
+			newResYAML: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: default
+  labels:
+    team: a
+spec:
+  replicas: 1
+  paused: true
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:v1
+        resources:
+          limits:
+            cpu: 1000m
+`,
+			op: ctldgraph.ActualChangeOpUpsert,
+		},
+		{
+			name: "fields owned by other manager set to different values, warning returned",
+			newResYAML: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: default
+  labels:
+    team: b
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:v2
+`,
+			op: ctldgraph.ActualChangeOpUpsert,
+			expectedWarnings: []string{
+				`deployment/app (apps/v1) namespace: default: fields owned by field manager "kubectl-client-side-apply" would conflict: ` +
+					`.metadata.labels.team, .spec.replicas, .spec.template.spec.containers`,
+			},
+		},
+		{
+			name: "deleted resources are not checked",
+			newResYAML: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: default
+spec:
+  replicas: 3
+`,
+			op: ctldgraph.ActualChangeOpDelete,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			newRes := ctlres.MustNewResourceFromBytes([]byte(tc.newResYAML))
+			findings := preflighttest.RunCheckOnChanges(t, checks.NewFieldManagerConflict(true),
+				preflighttest.Change{Res: newRes, ExistingRes: existingRes, ChangeOp: tc.op})
+			require.Equal(t, tc.expectedWarnings, preflighttest.Messages(findings))
+		})
+	}
+}
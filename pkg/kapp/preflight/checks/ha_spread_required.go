@@ -0,0 +1,187 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	HASpreadRequiredName = "HASpreadRequired"
+)
+
+// HASpreadRequiredConfig is the configuration accepted
+// by the HASpreadRequired preflight check
+type HASpreadRequiredConfig struct {
+	// TopologyKeys lists node labels (e.g. topology.kubernetes.io/zone)
+	// across which pods of each workload must be spread
+	TopologyKeys []string `json:"topologyKeys" description:"Node labels across which pods of each workload must be spread"`
+	// MinReplicas is the number of replicas from which spreading is required
+	MinReplicas int32 `json:"minReplicas" description:"Number of replicas from which spreading is required"`
+	// AllowPreferred accepts preferred pod anti-affinity
+	// in addition to required one
+	AllowPreferred bool `json:"allowPreferred" description:"Accept preferred pod anti-affinity in addition to required one"`
+	// Kinds limits the check to workloads of specified kinds
+	Kinds []string `json:"kinds" description:"Kinds of workloads to check"`
+	// Namespaces limits the check to workloads in specified
+	// namespaces. All namespaces are checked if empty.
+	Namespaces []string `json:"namespaces" description:"Namespaces of workloads to check (all namespaces if empty)"`
+	// Selector limits the check to workloads with matching labels
+	Selector map[string]string `json:"selector" description:"Labels of workloads to check"`
+}
+
+// HASpreadRequired is an implementation of preflight.Check
+// that warns about multi-replica workloads selected by policy that do
+// not spread their pods across required topology keys via pod
+// anti-affinity or topology spread constraints.
+type HASpreadRequired struct {
+	enabled bool
+	config  HASpreadRequiredConfig
+}
+
+var _ preflight.ConfigSchemaCheck = &HASpreadRequired{}
+var _ preflight.DescribedCheck = &HASpreadRequired{}
+
+func NewHASpreadRequired(enabled bool) preflight.Check {
+	return &HASpreadRequired{
+		enabled: enabled,
+		config: HASpreadRequiredConfig{
+			TopologyKeys:   []string{corev1.LabelHostname},
+			MinReplicas:    2,
+			AllowPreferred: true,
+			Kinds:          []string{deploymentGK.Kind, statefulSetGK.Kind},
+		},
+	}
+}
+
+func (c *HASpreadRequired) Description() string {
+	return "Warns about multi-replica workloads that are not spread across required topology keys"
+}
+
+func (c *HASpreadRequired) Enabled() bool {
+	return c.enabled
+}
+
+func (c *HASpreadRequired) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *HASpreadRequired) SetConfig(config preflight.CheckConfig) error {
+	newConfig := c.config
+
+	err := config.Decode(&newConfig)
+	if err != nil {
+		return err
+	}
+	if newConfig.MinReplicas < 2 {
+		return fmt.Errorf("expected minReplicas to be at least 2")
+	}
+
+	c.config = newConfig
+	return nil
+}
+
+func (c *HASpreadRequired) Config() preflight.CheckConfig {
+	return preflight.NewCheckConfig(c.config)
+}
+
+func (c *HASpreadRequired) ConfigSchema() []preflight.ConfigField {
+	return preflight.NewConfigSchema(HASpreadRequiredConfig{})
+}
+
+func (c *HASpreadRequired) Run(_ context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	workloads, err := upsertedWorkloads(changeGraph)
+	if err != nil {
+		return err
+	}
+
+	var findings []error
+
+	for _, wl := range workloads {
+		if wl.Replicas == nil || wl.ReplicaCount() < c.config.MinReplicas || !c.selected(wl) {
+			continue
+		}
+
+		for _, key := range c.config.TopologyKeys {
+			if !c.spreads(wl.Template, key) {
+				findings = append(findings, preflight.NewWarning(wl.Resource,
+					"%d replicas are not spread across topology key %q (no pod anti-affinity or topology spread constraint selecting own pods)",
+					wl.ReplicaCount(), key))
+			}
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+func (c *HASpreadRequired) selected(wl workload) bool {
+	res := wl.Resource
+
+	if !containsString(c.config.Kinds, res.Kind()) {
+		return false
+	}
+	if len(c.config.Namespaces) > 0 && !containsString(c.config.Namespaces, res.Namespace()) {
+		return false
+	}
+	return labels.SelectorFromSet(c.config.Selector).Matches(labels.Set(res.Labels()))
+}
+
+// spreads returns true if pods of the template are spread
+// across topology key via constraints selecting their own pods
+func (c *HASpreadRequired) spreads(template corev1.PodTemplateSpec, topologyKey string) bool {
+	podLabels := labels.Set(template.Labels)
+
+	selectsOwnPods := func(selector *metav1.LabelSelector) bool {
+		if selector == nil {
+			return false
+		}
+		s, err := metav1.LabelSelectorAsSelector(selector)
+		return err == nil && s.Matches(podLabels)
+	}
+
+	for _, constraint := range template.Spec.TopologySpreadConstraints {
+		if constraint.TopologyKey == topologyKey && selectsOwnPods(constraint.LabelSelector) {
+			return true
+		}
+	}
+
+	affinity := template.Spec.Affinity
+	if affinity == nil || affinity.PodAntiAffinity == nil {
+		return false
+	}
+
+	for _, term := range affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+		if term.TopologyKey == topologyKey && selectsOwnPods(term.LabelSelector) {
+			return true
+		}
+	}
+
+	if c.config.AllowPreferred {
+		for _, weighted := range affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+			term := weighted.PodAffinityTerm
+			if term.TopologyKey == topologyKey && selectsOwnPods(term.LabelSelector) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func containsString(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+func TestHASpreadRequired(t *testing.T) {
+	deploymentYAML := func(replicas, podSpec string) string {
+		return `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: default
+  labels:
+    tier: critical
+spec:
+  replicas: ` + replicas + `
+  template:
+    metadata:
+      labels:
+        app: app
+    spec: ` + podSpec + `
+`
+	}
+
+	antiAffinity := `
+      affinity:
+        podAntiAffinity:
+          preferredDuringSchedulingIgnoredDuringExecution:
+          - weight: 100
+            podAffinityTerm:
+              topologyKey: kubernetes.io/hostname
+              labelSelector:
+                matchLabels:
+                  app: app`
+
+	spreadConstraints := `
+      topologySpreadConstraints:
+      - topologyKey: topology.kubernetes.io/zone
+        maxSkew: 1
+        whenUnsatisfiable: DoNotSchedule
+        labelSelector:
+          matchLabels:
+            app: app`
+
+	testCases := []struct {
+		name             string
+		resYAML          string
+		config           preflight.CheckConfig
+		expectedWarnings []string
+	}{
+		{
+			name:    "single replica is not required to spread",
+			resYAML: deploymentYAML("1", "{}"),
+		},
+		{
+			name:    "multiple replicas without spreading",
+			resYAML: deploymentYAML("3", "{}"),
+			expectedWarnings: []string{
+				`deployment/app (apps/v1) namespace: default: 3 replicas are not spread across topology key "kubernetes.io/hostname" ` +
+					`(no pod anti-affinity or topology spread constraint selecting own pods)`,
+			},
+		},
+		{
+			name:    "preferred anti-affinity is accepted",
+			resYAML: deploymentYAML("3", antiAffinity),
+		},
+		{
+			name:    "preferred anti-affinity is not accepted when configured",
+			resYAML: deploymentYAML("3", antiAffinity),
+			config:  preflight.CheckConfig{"allowPreferred": false},
+			expectedWarnings: []string{
+				`deployment/app (apps/v1) namespace: default: 3 replicas are not spread across topology key "kubernetes.io/hostname" ` +
+					`(no pod anti-affinity or topology spread constraint selecting own pods)`,
+			},
+		},
+		{
+			name:    "multiple required topology keys",
+			resYAML: deploymentYAML("3", spreadConstraints),
+			config:  preflight.CheckConfig{"topologyKeys": []interface{}{"kubernetes.io/hostname", "topology.kubernetes.io/zone"}},
+			expectedWarnings: []string{
+				`deployment/app (apps/v1) namespace: default: 3 replicas are not spread across topology key "kubernetes.io/hostname" ` +
+					`(no pod anti-affinity or topology spread constraint selecting own pods)`,
+			},
+		},
+		{
+			name:    "workloads not selected by policy are ignored",
+			resYAML: deploymentYAML("3", "{}"),
+			config:  preflight.CheckConfig{"selector": map[string]interface{}{"tier": "best-effort"}},
+		},
+		{
+			name:    "workloads in other namespaces are ignored",
+			resYAML: deploymentYAML("3", "{}"),
+			config:  preflight.CheckConfig{"namespaces": []interface{}{"prod"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := ctlres.MustNewResourceFromBytes([]byte(tc.resYAML))
+
+			check := checks.NewHASpreadRequired(true).(preflight.ConfigurableCheck)
+			require.NoError(t, check.SetConfig(tc.config))
+
+			findings := preflighttest.RunCheckOnResources(t, check, []ctlres.Resource{res})
+			require.Equal(t, tc.expectedWarnings, preflighttest.Messages(findings))
+		})
+	}
+}
+
+func TestHASpreadRequiredInvalidConfig(t *testing.T) {
+	check := checks.NewHASpreadRequired(true).(preflight.ConfigurableCheck)
+	require.Error(t, check.SetConfig(preflight.CheckConfig{"unknownKey": 1}))
+	require.Error(t, check.SetConfig(preflight.CheckConfig{"minReplicas": 1}))
+}
@@ -0,0 +1,292 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"context"
+
+	cmdcore "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/core"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	typedpolicyv1 "k8s.io/client-go/kubernetes/typed/policy/v1"
+	typedstoragev1 "k8s.io/client-go/kubernetes/typed/storage/v1"
+	"k8s.io/client-go/restmapper"
+)
+
+// fakeDepsFactory only implements methods used by preflight checks.
+// Calling other methods panics.
+type fakeDepsFactory struct {
+	cmdcore.DepsFactory
+	coreClient    *fakeCoreClient
+	dynamicClient *fakeDynamicClient
+	mapper        meta.RESTMapper
+	apiGroups     []*restmapper.APIGroupResources
+}
+
+func (f fakeDepsFactory) CoreClient() (kubernetes.Interface, error) { return f.coreClient, nil }
+func (f fakeDepsFactory) RESTMapper() (meta.RESTMapper, error)      { return f.mapper, nil }
+
+func (f fakeDepsFactory) APIGroupResources(_ context.Context) ([]*restmapper.APIGroupResources, error) {
+	return f.apiGroups, nil
+}
+
+func (f fakeDepsFactory) DynamicClient(_ cmdcore.DynamicClientOpts) (dynamic.Interface, error) {
+	return f.dynamicClient, nil
+}
+
+type fakeCoreClient struct {
+	kubernetes.Interface
+	nodes          []corev1.Node
+	services       []corev1.Service
+	pvcs           []corev1.PersistentVolumeClaim
+	secrets        []corev1.Secret
+	limitRanges    []corev1.LimitRange
+	pdbs           []policyv1.PodDisruptionBudget
+	storageClasses []storagev1.StorageClass
+}
+
+func (c *fakeCoreClient) CoreV1() typedcorev1.CoreV1Interface { return fakeCoreV1{client: c} }
+
+type fakeCoreV1 struct {
+	typedcorev1.CoreV1Interface
+	client *fakeCoreClient
+}
+
+func (c fakeCoreV1) Nodes() typedcorev1.NodeInterface { return fakeNodes{client: c.client} }
+
+func (c fakeCoreV1) Services(_ string) typedcorev1.ServiceInterface {
+	return fakeServices{client: c.client}
+}
+
+func (c fakeCoreV1) PersistentVolumeClaims(namespace string) typedcorev1.PersistentVolumeClaimInterface {
+	return fakePVCs{client: c.client, namespace: namespace}
+}
+
+func (c fakeCoreV1) Secrets(namespace string) typedcorev1.SecretInterface {
+	return fakeSecrets{client: c.client, namespace: namespace}
+}
+
+func (c fakeCoreV1) LimitRanges(namespace string) typedcorev1.LimitRangeInterface {
+	return fakeLimitRanges{client: c.client, namespace: namespace}
+}
+
+type fakeNodes struct {
+	typedcorev1.NodeInterface
+	client *fakeCoreClient
+}
+
+func (n fakeNodes) List(_ context.Context, _ metav1.ListOptions) (*corev1.NodeList, error) {
+	return &corev1.NodeList{Items: n.client.nodes}, nil
+}
+
+// fakeServices ignores namespace and always lists all services
+type fakeServices struct {
+	typedcorev1.ServiceInterface
+	client *fakeCoreClient
+}
+
+func (s fakeServices) List(_ context.Context, _ metav1.ListOptions) (*corev1.ServiceList, error) {
+	return &corev1.ServiceList{Items: s.client.services}, nil
+}
+
+type fakePVCs struct {
+	typedcorev1.PersistentVolumeClaimInterface
+	client    *fakeCoreClient
+	namespace string
+}
+
+func (p fakePVCs) List(_ context.Context, _ metav1.ListOptions) (*corev1.PersistentVolumeClaimList, error) {
+	list := &corev1.PersistentVolumeClaimList{}
+	for _, pvc := range p.client.pvcs {
+		if pvc.Namespace == p.namespace {
+			list.Items = append(list.Items, pvc)
+		}
+	}
+	return list, nil
+}
+
+type fakeSecrets struct {
+	typedcorev1.SecretInterface
+	client    *fakeCoreClient
+	namespace string
+}
+
+func (s fakeSecrets) Get(_ context.Context, name string, _ metav1.GetOptions) (*corev1.Secret, error) {
+	for _, secret := range s.client.secrets {
+		if secret.Namespace == s.namespace && secret.Name == name {
+			return &secret, nil
+		}
+	}
+	return nil, kerrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+}
+
+type fakeLimitRanges struct {
+	typedcorev1.LimitRangeInterface
+	client    *fakeCoreClient
+	namespace string
+}
+
+func (l fakeLimitRanges) List(_ context.Context, _ metav1.ListOptions) (*corev1.LimitRangeList, error) {
+	list := &corev1.LimitRangeList{}
+	for _, limitRange := range l.client.limitRanges {
+		if limitRange.Namespace == l.namespace {
+			list.Items = append(list.Items, limitRange)
+		}
+	}
+	return list, nil
+}
+
+func (c *fakeCoreClient) PolicyV1() typedpolicyv1.PolicyV1Interface {
+	return fakePolicyV1{client: c}
+}
+
+type fakePolicyV1 struct {
+	typedpolicyv1.PolicyV1Interface
+	client *fakeCoreClient
+}
+
+func (p fakePolicyV1) PodDisruptionBudgets(namespace string) typedpolicyv1.PodDisruptionBudgetInterface {
+	return fakePDBs{client: p.client, namespace: namespace}
+}
+
+type fakePDBs struct {
+	typedpolicyv1.PodDisruptionBudgetInterface
+	client    *fakeCoreClient
+	namespace string
+}
+
+func (p fakePDBs) List(_ context.Context, _ metav1.ListOptions) (*policyv1.PodDisruptionBudgetList, error) {
+	list := &policyv1.PodDisruptionBudgetList{}
+	for _, pdb := range p.client.pdbs {
+		if pdb.Namespace == p.namespace {
+			list.Items = append(list.Items, pdb)
+		}
+	}
+	return list, nil
+}
+
+func (c *fakeCoreClient) StorageV1() typedstoragev1.StorageV1Interface {
+	return fakeStorageV1{client: c}
+}
+
+type fakeStorageV1 struct {
+	typedstoragev1.StorageV1Interface
+	client *fakeCoreClient
+}
+
+func (s fakeStorageV1) StorageClasses() typedstoragev1.StorageClassInterface {
+	return fakeStorageClasses{client: s.client}
+}
+
+type fakeStorageClasses struct {
+	typedstoragev1.StorageClassInterface
+	client *fakeCoreClient
+}
+
+func (s fakeStorageClasses) List(_ context.Context, _ metav1.ListOptions) (*storagev1.StorageClassList, error) {
+	return &storagev1.StorageClassList{Items: s.client.storageClasses}, nil
+}
+
+// fakeDynamicClient simulates server side apply by
+// returning patched object after passing it through mutate
+// and lists objects by resource
+type fakeDynamicClient struct {
+	dynamic.Interface
+	mutate  func(obj *unstructured.Unstructured) error
+	objects map[schema.GroupVersionResource][]unstructured.Unstructured
+}
+
+func (c *fakeDynamicClient) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return fakeDynamicResource{client: c, gvr: gvr}
+}
+
+type fakeDynamicResource struct {
+	dynamic.NamespaceableResourceInterface
+	client    *fakeDynamicClient
+	gvr       schema.GroupVersionResource
+	namespace string
+}
+
+func (r fakeDynamicResource) Namespace(namespace string) dynamic.ResourceInterface {
+	r.namespace = namespace
+	return r
+}
+
+func (r fakeDynamicResource) List(_ context.Context, _ metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	list := &unstructured.UnstructuredList{}
+	for _, obj := range r.client.objects[r.gvr] {
+		if len(r.namespace) == 0 || obj.GetNamespace() == r.namespace {
+			list.Items = append(list.Items, obj)
+		}
+	}
+	return list, nil
+}
+
+func (r fakeDynamicResource) Patch(_ context.Context, _ string, _ types.PatchType,
+	data []byte, _ metav1.PatchOptions, _ ...string) (*unstructured.Unstructured, error) {
+
+	obj := &unstructured.Unstructured{}
+
+	err := obj.UnmarshalJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.client.mutate(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+func newFakeRESTMapper(gvks ...schema.GroupVersionKind) meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	for _, gvk := range gvks {
+		mapper.Add(gvk, meta.RESTScopeNamespace)
+	}
+	return mapper
+}
+
+// newFakeAPIGroupResources groups resource lists by API group
+// (first listed version of a group is its preferred version)
+func newFakeAPIGroupResources(resourceLists ...*metav1.APIResourceList) []*restmapper.APIGroupResources {
+	var result []*restmapper.APIGroupResources
+	groupsByName := map[string]*restmapper.APIGroupResources{}
+
+	for _, resourceList := range resourceLists {
+		gv, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			panic(err)
+		}
+
+		group, found := groupsByName[gv.Group]
+		if !found {
+			group = &restmapper.APIGroupResources{
+				Group: metav1.APIGroup{
+					Name:             gv.Group,
+					PreferredVersion: metav1.GroupVersionForDiscovery{GroupVersion: gv.String(), Version: gv.Version},
+				},
+				VersionedResources: map[string][]metav1.APIResource{},
+			}
+			groupsByName[gv.Group] = group
+			result = append(result, group)
+		}
+
+		group.Group.Versions = append(group.Group.Versions, metav1.GroupVersionForDiscovery{GroupVersion: gv.String(), Version: gv.Version})
+		group.VersionedResources[gv.Version] = resourceList.APIResources
+	}
+
+	return result
+}
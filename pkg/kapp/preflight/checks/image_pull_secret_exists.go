@@ -0,0 +1,135 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	cmdcore "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/core"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	ImagePullSecretExistsName = "ImagePullSecretExists"
+)
+
+var (
+	secretGK = schema.GroupKind{Group: "", Kind: "Secret"}
+)
+
+// ImagePullSecretExists is an implementation of preflight.Check
+// that warns about workloads referencing image pull secrets that
+// are neither part of the change nor found in the cluster, or that
+// are not of a docker config type. Kubelet ignores such secrets,
+// hence images from private registries fail to be pulled.
+type ImagePullSecretExists struct {
+	depsFactory cmdcore.DepsFactory
+	enabled     bool
+}
+
+var _ preflight.DescribedCheck = &ImagePullSecretExists{}
+
+func NewImagePullSecretExists(depsFactory cmdcore.DepsFactory, enabled bool) preflight.Check {
+	return &ImagePullSecretExists{depsFactory: depsFactory, enabled: enabled}
+}
+
+func (c *ImagePullSecretExists) Description() string {
+	return "Warns about workloads referencing image pull secrets that do not exist"
+}
+
+func (c *ImagePullSecretExists) Enabled() bool {
+	return c.enabled
+}
+
+func (c *ImagePullSecretExists) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *ImagePullSecretExists) Run(ctx context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	workloads, err := upsertedWorkloads(changeGraph)
+	if err != nil {
+		return err
+	}
+
+	// Deleted secrets are considered missing
+	secrets := map[string]*corev1.Secret{}
+
+	for _, change := range changeGraph.All() {
+		res := change.Change.Resource()
+
+		if res.GroupKind() != secretGK {
+			continue
+		}
+
+		switch change.Change.Op() {
+		case ctldgraph.ActualChangeOpUpsert:
+			var secret corev1.Secret
+			err := res.AsUncheckedTypedObj(&secret)
+			if err != nil {
+				return fmt.Errorf("Resource %s: %w", res.Description(), err)
+			}
+			secrets[res.Namespace()+"/"+res.Name()] = &secret
+		case ctldgraph.ActualChangeOpDelete:
+			secrets[res.Namespace()+"/"+res.Name()] = nil
+		}
+	}
+
+	var findings []error
+
+	for _, wl := range workloads {
+		for _, ref := range wl.Template.Spec.ImagePullSecrets {
+			if len(ref.Name) == 0 {
+				continue
+			}
+
+			key := wl.Resource.Namespace() + "/" + ref.Name
+
+			secret, found := secrets[key]
+			if !found {
+				secret, err = c.clusterSecret(ctx, wl.Resource.Namespace(), ref.Name)
+				if err != nil {
+					return err
+				}
+				secrets[key] = secret
+			}
+
+			switch {
+			case secret == nil:
+				findings = append(findings, preflight.NewWarning(wl.Resource,
+					"image pull secret %q is not found in the change or in the cluster", ref.Name))
+			case secret.Type != corev1.SecretTypeDockerConfigJson && secret.Type != corev1.SecretTypeDockercfg:
+				findings = append(findings, preflight.NewWarning(wl.Resource,
+					"image pull secret %q is of type %q, expected %s or %s",
+					ref.Name, secret.Type, corev1.SecretTypeDockerConfigJson, corev1.SecretTypeDockercfg))
+			}
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+// clusterSecret returns nil if secret is not found
+func (c *ImagePullSecretExists) clusterSecret(ctx context.Context, namespace, name string) (*corev1.Secret, error) {
+	client, err := c.depsFactory.CoreClient()
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := client.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Getting secret %s/%s: %w", namespace, name, err)
+	}
+
+	return secret, nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestImagePullSecretExists(t *testing.T) {
+	deployment := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: default
+spec:
+  template:
+    spec:
+      imagePullSecrets:
+      - name: in-change
+      - name: in-cluster
+      - name: missing
+      - name: opaque
+      - name: deleted
+      containers:
+      - name: app
+        image: registry.example.com/app
+`))
+	inChange := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: Secret
+metadata:
+  name: in-change
+  namespace: default
+type: kubernetes.io/dockerconfigjson
+`))
+	deleted := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: Secret
+metadata:
+  name: deleted
+  namespace: default
+type: kubernetes.io/dockerconfigjson
+`))
+	// Same secret in another namespace does not satisfy the reference
+	otherNamespace := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: Secret
+metadata:
+  name: missing
+  namespace: other
+type: kubernetes.io/dockerconfigjson
+`))
+
+	clusterSecrets := []corev1.Secret{
+		{ObjectMeta: metav1.ObjectMeta{Name: "in-cluster", Namespace: "default"}, Type: corev1.SecretTypeDockercfg},
+		{ObjectMeta: metav1.ObjectMeta{Name: "opaque", Namespace: "default"}, Type: corev1.SecretTypeOpaque},
+		{ObjectMeta: metav1.ObjectMeta{Name: "deleted", Namespace: "default"}, Type: corev1.SecretTypeDockerConfigJson},
+	}
+
+	depsFactory := fakeDepsFactory{coreClient: &fakeCoreClient{secrets: clusterSecrets}}
+
+	findings := preflighttest.RunCheckOnChanges(t, checks.NewImagePullSecretExists(depsFactory, true),
+		preflighttest.Change{Res: deployment, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+		preflighttest.Change{Res: inChange, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+		preflighttest.Change{Res: deleted, ChangeOp: ctldgraph.ActualChangeOpDelete},
+		preflighttest.Change{Res: otherNamespace, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+	)
+
+	require.Equal(t, []string{
+		`deployment/app (apps/v1) namespace: default: image pull secret "missing" is not found in the change or in the cluster`,
+		`deployment/app (apps/v1) namespace: default: image pull secret "opaque" is of type "Opaque", ` +
+			`expected kubernetes.io/dockerconfigjson or kubernetes.io/dockercfg`,
+		`deployment/app (apps/v1) namespace: default: image pull secret "deleted" is not found in the change or in the cluster`,
+	}, preflighttest.Messages(findings))
+}
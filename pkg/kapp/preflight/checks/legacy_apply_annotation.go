@@ -0,0 +1,64 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	LegacyApplyAnnotationName = "LegacyApplyAnnotation"
+)
+
+// LegacyApplyAnnotation is an implementation of preflight.Check
+// that warns about resources carrying kubectl's last-applied-configuration
+// annotation. It is typically left over from exporting resources that were
+// previously managed with kubectl apply, and since kapp does not maintain it,
+// its value gets stale and confuses tools that rely on it.
+type LegacyApplyAnnotation struct {
+	enabled bool
+}
+
+var _ preflight.DescribedCheck = &LegacyApplyAnnotation{}
+
+func NewLegacyApplyAnnotation(enabled bool) preflight.Check {
+	return &LegacyApplyAnnotation{enabled: enabled}
+}
+
+func (c *LegacyApplyAnnotation) Description() string {
+	return "Warns about resources carrying kubectl's last-applied-configuration annotation"
+}
+
+func (c *LegacyApplyAnnotation) Enabled() bool {
+	return c.enabled
+}
+
+func (c *LegacyApplyAnnotation) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *LegacyApplyAnnotation) Run(_ context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	var findings []error
+
+	for _, change := range changeGraph.All() {
+		res := change.Change.Resource()
+
+		if change.Change.Op() != ctldgraph.ActualChangeOpUpsert {
+			continue
+		}
+
+		if _, found := res.Annotations()[corev1.LastAppliedConfigAnnotation]; found {
+			findings = append(findings, preflight.NewWarning(res,
+				"annotation %q is left over from kubectl apply and is not maintained by kapp, remove it from the manifest",
+				corev1.LastAppliedConfigAnnotation))
+		}
+	}
+
+	return errors.Join(findings...)
+}
@@ -0,0 +1,56 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+func TestLegacyApplyAnnotation(t *testing.T) {
+	annotated := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: exported
+  namespace: default
+  annotations:
+    kubectl.kubernetes.io/last-applied-configuration: |
+      {"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"exported","namespace":"default"}}
+`))
+	deleted := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: deleted
+  namespace: default
+  annotations:
+    kubectl.kubernetes.io/last-applied-configuration: "{}"
+`))
+	clean := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: clean
+  namespace: default
+  annotations:
+    kubectl.kubernetes.io/restartedAt: "2024-01-01T00:00:00Z"
+`))
+
+	findings := preflighttest.RunCheckOnChanges(t, checks.NewLegacyApplyAnnotation(true),
+		preflighttest.Change{Res: annotated, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+		preflighttest.Change{Res: deleted, ChangeOp: ctldgraph.ActualChangeOpDelete},
+		preflighttest.Change{Res: clean, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+	)
+
+	require.Equal(t, []string{
+		`configmap/exported (v1) namespace: default: annotation "kubectl.kubernetes.io/last-applied-configuration" ` +
+			`is left over from kubectl apply and is not maintained by kapp, remove it from the manifest`,
+	}, preflighttest.Messages(findings))
+}
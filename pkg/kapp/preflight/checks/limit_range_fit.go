@@ -0,0 +1,225 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	cmdcore "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/core"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	LimitRangeFitName = "LimitRangeFit"
+)
+
+var (
+	limitRangeGK = schema.GroupKind{Group: "", Kind: "LimitRange"}
+)
+
+// LimitRangeFit is an implementation of preflight.Check
+// that warns about containers whose requests or limits are
+// outside of per-container bounds (min, max and max limit to
+// request ratio) of LimitRanges in their namespace. Pods with
+// such containers are rejected on creation. LimitRanges are
+// taken from the change and from the cluster (unless replaced
+// or deleted by the change). Only explicitly specified requests
+// and limits are compared since defaults are applied by the
+// LimitRange itself.
+type LimitRangeFit struct {
+	depsFactory cmdcore.DepsFactory
+	enabled     bool
+}
+
+var _ preflight.DescribedCheck = &LimitRangeFit{}
+
+func NewLimitRangeFit(depsFactory cmdcore.DepsFactory, enabled bool) preflight.Check {
+	return &LimitRangeFit{depsFactory: depsFactory, enabled: enabled}
+}
+
+func (c *LimitRangeFit) Description() string {
+	return "Warns about containers whose requests or limits are outside of LimitRange bounds of their namespace"
+}
+
+func (c *LimitRangeFit) Enabled() bool {
+	return c.enabled
+}
+
+func (c *LimitRangeFit) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *LimitRangeFit) Run(ctx context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	workloads, err := upsertedWorkloads(changeGraph)
+	if err != nil {
+		return err
+	}
+
+	changeLimitRanges, deletedLimitRanges, err := c.changeLimitRanges(changeGraph)
+	if err != nil {
+		return err
+	}
+
+	// LimitRanges by namespace, listed on first use
+	limitRanges := map[string][]corev1.LimitRange{}
+
+	var findings []error
+
+	for _, wl := range workloads {
+		namespace := wl.Resource.Namespace()
+
+		nsLimitRanges, found := limitRanges[namespace]
+		if !found {
+			nsLimitRanges, err = c.namespaceLimitRanges(ctx, namespace, changeLimitRanges, deletedLimitRanges)
+			if err != nil {
+				return err
+			}
+			limitRanges[namespace] = nsLimitRanges
+		}
+
+		for _, limitRange := range nsLimitRanges {
+			for _, item := range limitRange.Spec.Limits {
+				if item.Type != corev1.LimitTypeContainer {
+					continue
+				}
+				for _, container := range allContainers(wl.Template.Spec) {
+					for _, violation := range c.violations(container.Resources, item) {
+						findings = append(findings, preflight.NewWarning(wl.Resource,
+							"container %q %s of LimitRange %q, pods will be rejected", container.Name, violation, limitRange.Name))
+					}
+				}
+			}
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+// changeLimitRanges returns LimitRanges upserted by the change and
+// names (namespace/name) of LimitRanges deleted by the change
+func (c *LimitRangeFit) changeLimitRanges(changeGraph *ctldgraph.ChangeGraph) ([]corev1.LimitRange, map[string]struct{}, error) {
+	var upserted []corev1.LimitRange
+	deleted := map[string]struct{}{}
+
+	for _, change := range changeGraph.All() {
+		res := change.Change.Resource()
+		if res.GroupKind() != limitRangeGK {
+			continue
+		}
+
+		switch change.Change.Op() {
+		case ctldgraph.ActualChangeOpUpsert:
+			var limitRange corev1.LimitRange
+
+			err := res.AsUncheckedTypedObj(&limitRange)
+			if err != nil {
+				return nil, nil, fmt.Errorf("Resource %s: %w", res.Description(), err)
+			}
+			upserted = append(upserted, limitRange)
+
+		case ctldgraph.ActualChangeOpDelete:
+			deleted[res.Namespace()+"/"+res.Name()] = struct{}{}
+		}
+	}
+
+	return upserted, deleted, nil
+}
+
+// namespaceLimitRanges returns LimitRanges of the namespace as they
+// will be once the change is applied (cluster LimitRanges replaced
+// or deleted by the change are excluded)
+func (c *LimitRangeFit) namespaceLimitRanges(ctx context.Context, namespace string,
+	changeLimitRanges []corev1.LimitRange, deletedLimitRanges map[string]struct{}) ([]corev1.LimitRange, error) {
+
+	var result []corev1.LimitRange
+	inChange := map[string]struct{}{}
+
+	for _, limitRange := range changeLimitRanges {
+		if limitRange.Namespace == namespace {
+			result = append(result, limitRange)
+			inChange[limitRange.Name] = struct{}{}
+		}
+	}
+
+	coreClient, err := c.depsFactory.CoreClient()
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := coreClient.CoreV1().LimitRanges(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("Listing LimitRanges in namespace %q: %w", namespace, err)
+	}
+
+	for _, limitRange := range list.Items {
+		if _, found := inChange[limitRange.Name]; found {
+			continue
+		}
+		if _, found := deletedLimitRanges[namespace+"/"+limitRange.Name]; found {
+			continue
+		}
+		result = append(result, limitRange)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	return result, nil
+}
+
+// violations describes breached bounds of a LimitRange item
+func (c *LimitRangeFit) violations(reqs corev1.ResourceRequirements, item corev1.LimitRangeItem) []string {
+	var result []string
+
+	for _, name := range sortedResourceNames(item.Max) {
+		maxQty := item.Max[name]
+		if limit, found := reqs.Limits[name]; found && limit.Cmp(maxQty) > 0 {
+			result = append(result, fmt.Sprintf("%s limit %s exceeds maximum %s", name, limit.String(), maxQty.String()))
+		}
+		if request, found := reqs.Requests[name]; found && request.Cmp(maxQty) > 0 {
+			result = append(result, fmt.Sprintf("%s request %s exceeds maximum %s", name, request.String(), maxQty.String()))
+		}
+	}
+
+	for _, name := range sortedResourceNames(item.Min) {
+		minQty := item.Min[name]
+		if request, found := reqs.Requests[name]; found && request.Cmp(minQty) < 0 {
+			result = append(result, fmt.Sprintf("%s request %s is below minimum %s", name, request.String(), minQty.String()))
+		}
+		if limit, found := reqs.Limits[name]; found && limit.Cmp(minQty) < 0 {
+			result = append(result, fmt.Sprintf("%s limit %s is below minimum %s", name, limit.String(), minQty.String()))
+		}
+	}
+
+	for _, name := range sortedResourceNames(item.MaxLimitRequestRatio) {
+		maxRatio := item.MaxLimitRequestRatio[name]
+		limit, limitFound := reqs.Limits[name]
+		request, requestFound := reqs.Requests[name]
+		if !limitFound || !requestFound || request.IsZero() {
+			continue
+		}
+
+		ratio := float64(limit.MilliValue()) / float64(request.MilliValue())
+		if ratio > maxRatio.AsApproximateFloat64() {
+			result = append(result, fmt.Sprintf("%s limit to request ratio %g exceeds maximum %s", name, ratio, maxRatio.String()))
+		}
+	}
+
+	return result
+}
+
+func sortedResourceNames(list corev1.ResourceList) []corev1.ResourceName {
+	var result []corev1.ResourceName
+	for name := range list {
+		result = append(result, name)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
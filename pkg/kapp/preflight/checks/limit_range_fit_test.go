@@ -0,0 +1,134 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLimitRangeFit(t *testing.T) {
+	deployment := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: default
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        resources:
+          requests:
+            memory: 8Mi
+      containers:
+      - name: app
+        resources:
+          requests:
+            cpu: 100m
+          limits:
+            cpu: "2"
+            memory: 1Gi
+      - name: sidecar
+        resources:
+          requests:
+            cpu: 100m
+          limits:
+            cpu: 200m
+`))
+	// Other namespace is not constrained by LimitRanges of default namespace
+	otherNamespace := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: other
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        resources:
+          limits:
+            cpu: "8"
+`))
+	inChange := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: LimitRange
+metadata:
+  name: cpu
+  namespace: default
+spec:
+  limits:
+  - type: Container
+    max:
+      cpu: "1"
+    maxLimitRequestRatio:
+      cpu: "4"
+  - type: Pod
+    max:
+      cpu: 100m
+`))
+	deleted := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: LimitRange
+metadata:
+  name: deleted
+  namespace: default
+`))
+
+	clusterLimitRanges := []corev1.LimitRange{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "memory", Namespace: "default"},
+			Spec: corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{{
+				Type: corev1.LimitTypeContainer,
+				Max:  corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+				Min:  corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("16Mi")},
+			}}},
+		},
+		// Replaced by LimitRange in the change
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "cpu", Namespace: "default"},
+			Spec: corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{{
+				Type: corev1.LimitTypeContainer,
+				Max:  corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10m")},
+			}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "deleted", Namespace: "default"},
+			Spec: corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{{
+				Type: corev1.LimitTypeContainer,
+				Max:  corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10m")},
+			}}},
+		},
+	}
+
+	depsFactory := fakeDepsFactory{coreClient: &fakeCoreClient{limitRanges: clusterLimitRanges}}
+
+	findings := preflighttest.RunCheckOnChanges(t, checks.NewLimitRangeFit(depsFactory, true),
+		preflighttest.Change{Res: deployment, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+		preflighttest.Change{Res: otherNamespace, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+		preflighttest.Change{Res: inChange, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+		preflighttest.Change{Res: deleted, ChangeOp: ctldgraph.ActualChangeOpDelete},
+	)
+
+	require.Equal(t, []string{
+		`deployment/app (apps/v1) namespace: default: container "app" cpu limit 2 exceeds maximum 1 of LimitRange "cpu", pods will be rejected`,
+		`deployment/app (apps/v1) namespace: default: container "app" cpu limit to request ratio 20 exceeds maximum 4 ` +
+			`of LimitRange "cpu", pods will be rejected`,
+		`deployment/app (apps/v1) namespace: default: container "init" memory request 8Mi is below minimum 16Mi ` +
+			`of LimitRange "memory", pods will be rejected`,
+		`deployment/app (apps/v1) namespace: default: container "app" memory limit 1Gi exceeds maximum 512Mi ` +
+			`of LimitRange "memory", pods will be rejected`,
+	}, preflighttest.Messages(findings))
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	cmdcore "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/core"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	LoadBalancerSupportedName = "LoadBalancerSupported"
+)
+
+// LoadBalancerSupported is an implementation of preflight.Check
+// that warns about LoadBalancer Services when there are no signs
+// of a load balancer implementation in the cluster. Without one
+// such Services remain pending forever. Load balancers are
+// considered supported if nodes are managed by a cloud provider
+// (have a provider ID) or if an existing LoadBalancer Service (of
+// the same load balancer class) has been assigned an ingress.
+type LoadBalancerSupported struct {
+	depsFactory cmdcore.DepsFactory
+	enabled     bool
+}
+
+var _ preflight.DescribedCheck = &LoadBalancerSupported{}
+
+func NewLoadBalancerSupported(depsFactory cmdcore.DepsFactory, enabled bool) preflight.Check {
+	return &LoadBalancerSupported{depsFactory: depsFactory, enabled: enabled}
+}
+
+func (c *LoadBalancerSupported) Description() string {
+	return "Warns about LoadBalancer Services in clusters without a detectable load balancer implementation"
+}
+
+func (c *LoadBalancerSupported) Enabled() bool {
+	return c.enabled
+}
+
+func (c *LoadBalancerSupported) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *LoadBalancerSupported) Run(ctx context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	services, err := upsertedServices(changeGraph)
+	if err != nil {
+		return err
+	}
+
+	var supportedClasses map[string]struct{}
+	var findings []error
+
+	for _, svc := range services {
+		if svc.Service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			continue
+		}
+
+		// Only inspect the cluster once a relevant Service is found
+		if supportedClasses == nil {
+			supportedClasses, err = c.supportedClasses(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		class := loadBalancerClass(svc.Service)
+		if _, found := supportedClasses[class]; found {
+			continue
+		}
+
+		if len(class) > 0 {
+			findings = append(findings, preflight.NewWarning(svc.Resource,
+				"no LoadBalancer Service of load balancer class %q has been assigned an ingress, "+
+					"service may remain pending if there is no controller for the class", class))
+		} else {
+			findings = append(findings, preflight.NewWarning(svc.Resource,
+				"no load balancer implementation detected (nodes have no provider ID and no LoadBalancer Service "+
+					"has been assigned an ingress), service may remain pending"))
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+// supportedClasses returns load balancer classes (empty for
+// the default class) that appear to be supported by the cluster
+func (c *LoadBalancerSupported) supportedClasses(ctx context.Context) (map[string]struct{}, error) {
+	result := map[string]struct{}{}
+
+	nodes, err := listNodes(ctx, c.depsFactory)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, node := range nodes {
+		if len(node.Spec.ProviderID) > 0 {
+			result[""] = struct{}{}
+			break
+		}
+	}
+
+	client, err := c.depsFactory.CoreClient()
+	if err != nil {
+		return nil, err
+	}
+
+	services, err := client.CoreV1().Services("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("Listing services: %w", err)
+	}
+
+	for _, svc := range services.Items {
+		if svc.Spec.Type == corev1.ServiceTypeLoadBalancer && len(svc.Status.LoadBalancer.Ingress) > 0 {
+			result[loadBalancerClass(svc)] = struct{}{}
+		}
+	}
+
+	return result, nil
+}
+
+func loadBalancerClass(svc corev1.Service) string {
+	if svc.Spec.LoadBalancerClass == nil {
+		return ""
+	}
+	return *svc.Spec.LoadBalancerClass
+}
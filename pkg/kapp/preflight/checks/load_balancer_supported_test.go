@@ -0,0 +1,107 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLoadBalancerSupported(t *testing.T) {
+	resources := []ctlres.Resource{
+		ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: Service
+metadata:
+  name: lb
+  namespace: default
+spec:
+  type: LoadBalancer
+`)),
+		ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: Service
+metadata:
+  name: lb-custom
+  namespace: default
+spec:
+  type: LoadBalancer
+  loadBalancerClass: example.com/lb
+`)),
+		ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: Service
+metadata:
+  name: internal
+  namespace: default
+spec:
+  type: ClusterIP
+`)),
+	}
+
+	customClass := "example.com/lb"
+	loadBalancerIngress := corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{
+		Ingress: []corev1.LoadBalancerIngress{{IP: "192.0.2.1"}}}}
+
+	testCases := []struct {
+		name             string
+		nodes            []corev1.Node
+		services         []corev1.Service
+		expectedWarnings []string
+	}{
+		{
+			name:  "no load balancer implementation detected",
+			nodes: []corev1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}},
+			services: []corev1.Service{{
+				ObjectMeta: metav1.ObjectMeta{Name: "pending", Namespace: "other"},
+				Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+			}},
+			expectedWarnings: []string{
+				`service/lb (v1) namespace: default: no load balancer implementation detected (nodes have no provider ID ` +
+					`and no LoadBalancer Service has been assigned an ingress), service may remain pending`,
+				`service/lb-custom (v1) namespace: default: no LoadBalancer Service of load balancer class "example.com/lb" ` +
+					`has been assigned an ingress, service may remain pending if there is no controller for the class`,
+			},
+		},
+		{
+			name: "nodes managed by a cloud provider",
+			nodes: []corev1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+				Spec: corev1.NodeSpec{ProviderID: "aws:///us-east-1a/i-0123"}}},
+			expectedWarnings: []string{
+				`service/lb-custom (v1) namespace: default: no LoadBalancer Service of load balancer class "example.com/lb" ` +
+					`has been assigned an ingress, service may remain pending if there is no controller for the class`,
+			},
+		},
+		{
+			name: "existing load balancers have been assigned an ingress",
+			services: []corev1.Service{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "other"},
+					Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+					Status:     loadBalancerIngress,
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "existing-custom", Namespace: "other"},
+					Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer, LoadBalancerClass: &customClass},
+					Status:     loadBalancerIngress,
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			depsFactory := fakeDepsFactory{coreClient: &fakeCoreClient{nodes: tc.nodes, services: tc.services}}
+
+			findings := preflighttest.RunCheckOnResources(t, checks.NewLoadBalancerSupported(depsFactory, true), resources)
+			require.Equal(t, tc.expectedWarnings, preflighttest.Messages(findings))
+		})
+	}
+}
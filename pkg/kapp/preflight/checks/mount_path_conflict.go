@@ -0,0 +1,114 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	MountPathConflictName = "MountPathConflict"
+)
+
+// MountPathConflict is an implementation of preflight.Check
+// that detects overlapping volume mounts within a container.
+// Mounts with the same mountPath are rejected by the API server.
+// Mounts nested inside a ConfigMap, Secret, projected or downward
+// API volume silently shadow the content of that volume at the
+// nested path (e.g. a key of the ConfigMap).
+type MountPathConflict struct {
+	enabled bool
+}
+
+var _ preflight.DescribedCheck = &MountPathConflict{}
+
+func NewMountPathConflict(enabled bool) preflight.Check {
+	return &MountPathConflict{enabled: enabled}
+}
+
+func (c *MountPathConflict) Description() string {
+	return "Detects volume mounts of a container that overlap and shadow each other"
+}
+
+func (c *MountPathConflict) Enabled() bool {
+	return c.enabled
+}
+
+func (c *MountPathConflict) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *MountPathConflict) Run(_ context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	workloads, err := upsertedWorkloads(changeGraph)
+	if err != nil {
+		return err
+	}
+
+	var findings []error
+
+	for _, wl := range workloads {
+		volumes := map[string]corev1.Volume{}
+		for _, vol := range wl.Template.Spec.Volumes {
+			volumes[vol.Name] = vol
+		}
+
+		for _, container := range allContainers(wl.Template.Spec) {
+			mounts := container.VolumeMounts
+
+			for i := range mounts {
+				for j := i + 1; j < len(mounts); j++ {
+					first, second := mounts[i], mounts[j]
+					firstPath, secondPath := path.Clean(first.MountPath), path.Clean(second.MountPath)
+
+					switch {
+					case firstPath == secondPath:
+						findings = append(findings, preflight.NewError(wl.Resource,
+							"container %q mounts %s and %s at the same path %q",
+							container.Name, c.formatMount(first), c.formatMount(second), firstPath))
+
+					case c.isNested(firstPath, secondPath) && c.isProjected(volumes[first.Name]):
+						findings = append(findings, c.shadowingFinding(wl.Resource, container.Name, first, second))
+
+					case c.isNested(secondPath, firstPath) && c.isProjected(volumes[second.Name]):
+						findings = append(findings, c.shadowingFinding(wl.Resource, container.Name, second, first))
+					}
+				}
+			}
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+func (c *MountPathConflict) shadowingFinding(res ctlres.Resource, containerName string, outer, inner corev1.VolumeMount) error {
+	return preflight.NewWarning(res, "container %q mounts %s at %q inside %s mounted at %q, it shadows content of volume %q at that path",
+		containerName, c.formatMount(inner), path.Clean(inner.MountPath), c.formatMount(outer), path.Clean(outer.MountPath), outer.Name)
+}
+
+// isNested returns true if inner path is within outer path
+func (c *MountPathConflict) isNested(outer, inner string) bool {
+	return strings.HasPrefix(inner, strings.TrimSuffix(outer, "/")+"/")
+}
+
+// isProjected returns true for volumes whose content is projected from
+// API objects (and therefore mounts nested within them shadow their keys)
+func (c *MountPathConflict) isProjected(vol corev1.Volume) bool {
+	return vol.ConfigMap != nil || vol.Secret != nil || vol.Projected != nil || vol.DownwardAPI != nil
+}
+
+func (c *MountPathConflict) formatMount(mount corev1.VolumeMount) string {
+	if len(mount.SubPath) > 0 {
+		return fmt.Sprintf("volume %q (subPath %q)", mount.Name, mount.SubPath)
+	}
+	return fmt.Sprintf("volume %q", mount.Name)
+}
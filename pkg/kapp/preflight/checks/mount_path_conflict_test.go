@@ -0,0 +1,70 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+func TestMountPathConflict(t *testing.T) {
+	deployment := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: default
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: init
+        volumeMounts:
+        - name: config
+          mountPath: /etc/app
+        - name: secrets
+          mountPath: /etc/app/
+      containers:
+      - name: app
+        image: app
+        volumeMounts:
+        - name: config
+          mountPath: /etc/app
+        - name: secrets
+          mountPath: /etc/app/app.conf
+          subPath: app.conf
+        - name: data
+          mountPath: /data
+        - name: config
+          mountPath: /data/config
+        - name: config
+          mountPath: /etc/application
+      volumes:
+      - name: config
+        configMap:
+          name: app-config
+      - name: secrets
+        secret:
+          secretName: app-secrets
+      - name: data
+        emptyDir: {}
+`))
+
+	findings := preflighttest.RunCheckOnResources(t, checks.NewMountPathConflict(true), []ctlres.Resource{deployment})
+	require.Equal(t, []string{
+		`deployment/app (apps/v1) namespace: default: container "init" mounts volume "config" and volume "secrets" ` +
+			`at the same path "/etc/app"`,
+		`deployment/app (apps/v1) namespace: default: container "app" mounts volume "secrets" (subPath "app.conf") ` +
+			`at "/etc/app/app.conf" inside volume "config" mounted at "/etc/app", it shadows content of volume "config" at that path`,
+	}, preflighttest.Messages(findings))
+
+	require.Equal(t, []preflight.Severity{preflight.SeverityError, preflight.SeverityWarning},
+		[]preflight.Severity{findings[0].Severity, findings[1].Severity})
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+)
+
+const (
+	NamespaceOrderingName = "NamespaceOrdering"
+
+	// Same as used by kapp default configuration
+	disableDefaultChangeGroupAnnKey = "kapp.k14s.io/disable-default-change-group-and-rules"
+)
+
+// NamespaceOrdering is an implementation of preflight.Check
+// that warns about resources targeting a Namespace created in the
+// same change that are not ordered to be applied after it. By
+// default kapp orders namespaced resources after their Namespace,
+// however default ordering may be disabled via annotation, in which
+// case resources may be applied before their Namespace exists.
+type NamespaceOrdering struct {
+	enabled bool
+}
+
+var _ preflight.DescribedCheck = &NamespaceOrdering{}
+
+func NewNamespaceOrdering(enabled bool) preflight.Check {
+	return &NamespaceOrdering{enabled: enabled}
+}
+
+func (c *NamespaceOrdering) Description() string {
+	return "Warns about resources that may be applied before their Namespace created in the same change"
+}
+
+func (c *NamespaceOrdering) Enabled() bool {
+	return c.enabled
+}
+
+func (c *NamespaceOrdering) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *NamespaceOrdering) Run(_ context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	createdNamespaces := map[string]*ctldgraph.Change{}
+
+	for _, change := range changeGraph.All() {
+		res := change.Change.Resource()
+		if change.Change.Op() == ctldgraph.ActualChangeOpUpsert && res.GroupKind() == namespaceGK &&
+			clusterOriginalResource(change.Change) == nil {
+			createdNamespaces[res.Name()] = change
+		}
+	}
+
+	if len(createdNamespaces) == 0 {
+		return nil
+	}
+
+	var findings []error
+
+	for _, change := range changeGraph.All() {
+		res := change.Change.Resource()
+
+		if change.Change.Op() != ctldgraph.ActualChangeOpUpsert || len(res.Namespace()) == 0 {
+			continue
+		}
+
+		nsChange, found := createdNamespaces[res.Namespace()]
+		if !found || change.IsTransitivelyWaitingFor(nsChange) {
+			continue
+		}
+
+		var hint string
+		if _, found := res.Annotations()[disableDefaultChangeGroupAnnKey]; found {
+			hint = " (default ordering is disabled via annotation " + disableDefaultChangeGroupAnnKey + ")"
+		} else if _, found := nsChange.Change.Resource().Annotations()[disableDefaultChangeGroupAnnKey]; found {
+			hint = " (default ordering of the namespace is disabled via annotation " + disableDefaultChangeGroupAnnKey + ")"
+		}
+
+		findings = append(findings, preflight.NewWarning(res,
+			"namespace %q is created in the same change but resource is not ordered to be applied after it%s",
+			res.Namespace(), hint))
+	}
+
+	return errors.Join(findings...)
+}
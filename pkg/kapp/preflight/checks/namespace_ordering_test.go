@@ -0,0 +1,76 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+func TestNamespaceOrdering(t *testing.T) {
+	newNamespace := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: team
+  annotations:
+    kapp.k14s.io/change-group: team-ns
+`))
+	existingNamespace := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: existing
+`))
+	ordered := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: ordered
+  namespace: team
+  annotations:
+    kapp.k14s.io/change-rule: upsert after upserting team-ns
+`))
+	unordered := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unordered
+  namespace: team
+  annotations:
+    kapp.k14s.io/disable-default-change-group-and-rules: ""
+`))
+	inExistingNamespace := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cfg
+  namespace: existing
+`))
+	clusterScoped := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: reader
+`))
+
+	findings := preflighttest.RunCheckOnChanges(t, checks.NewNamespaceOrdering(true),
+		preflighttest.Change{Res: newNamespace, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+		preflighttest.Change{Res: existingNamespace, ExistingRes: existingNamespace, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+		preflighttest.Change{Res: ordered, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+		preflighttest.Change{Res: unordered, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+		preflighttest.Change{Res: inExistingNamespace, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+		preflighttest.Change{Res: clusterScoped, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+	)
+
+	require.Equal(t, []string{
+		`configmap/unordered (v1) namespace: team: namespace "team" is created in the same change but resource ` +
+			`is not ordered to be applied after it (default ordering is disabled via annotation kapp.k14s.io/disable-default-change-group-and-rules)`,
+	}, preflighttest.Messages(findings))
+}
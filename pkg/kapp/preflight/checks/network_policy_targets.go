@@ -0,0 +1,141 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	NetworkPolicyTargetsName = "NetworkPolicyTargets"
+)
+
+var (
+	networkPolicyGK = schema.GroupKind{Group: "networking.k8s.io", Kind: "NetworkPolicy"}
+)
+
+// NetworkPolicyTargets is an implementation of preflight.Check
+// that evaluates podSelectors of NetworkPolicies against workloads
+// in the same namespace of the change. It warns about policies
+// selecting no workloads (which have no effect unless pods outside
+// of the change match) and about policies that allow traffic with
+// an empty podSelector (which selects all pods in the namespace).
+// Default deny policies (without any rules) are expected to select
+// all pods. Workloads targeted by each policy are reported as info.
+type NetworkPolicyTargets struct {
+	enabled bool
+}
+
+var _ preflight.DescribedCheck = &NetworkPolicyTargets{}
+
+func NewNetworkPolicyTargets(enabled bool) preflight.Check {
+	return &NetworkPolicyTargets{enabled: enabled}
+}
+
+func (c *NetworkPolicyTargets) Description() string {
+	return "Warns about NetworkPolicies selecting no workloads or unintentionally selecting all pods"
+}
+
+func (c *NetworkPolicyTargets) Enabled() bool {
+	return c.enabled
+}
+
+func (c *NetworkPolicyTargets) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *NetworkPolicyTargets) Run(_ context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	workloads, err := upsertedWorkloads(changeGraph)
+	if err != nil {
+		return err
+	}
+
+	var findings []error
+
+	for _, change := range changeGraph.All() {
+		res := change.Change.Resource()
+
+		if change.Change.Op() != ctldgraph.ActualChangeOpUpsert || res.GroupKind() != networkPolicyGK {
+			continue
+		}
+
+		var policy networkingv1.NetworkPolicy
+
+		err := res.AsUncheckedTypedObj(&policy)
+		if err != nil {
+			return fmt.Errorf("Resource %s: %w", res.Description(), err)
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.PodSelector)
+		if err != nil {
+			// Invalid selectors are rejected by the API server
+			continue
+		}
+
+		targets := c.targets(workloads, res.Namespace(), selector)
+
+		switch {
+		case selector.Empty():
+			if c.isDefaultDeny(policy) {
+				continue
+			}
+			findings = append(findings, preflight.NewWarning(res,
+				"empty podSelector selects all pods in namespace %q, rules allowing traffic apply to all of them "+
+					"(targets %d workload(s) in the change%s)", res.Namespace(), len(targets), formatTargets(targets)))
+
+		case len(targets) == 0:
+			findings = append(findings, preflight.NewWarning(res,
+				"podSelector %q matches no workloads in the change, policy has no effect unless matching pods exist in the cluster",
+				selector.String()))
+
+		default:
+			findings = append(findings, preflight.NewInfo(res,
+				"podSelector %q targets %d workload(s) in the change%s", selector.String(), len(targets), formatTargets(targets)))
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+// targets returns workloads in the namespace whose pods match selector
+func (c *NetworkPolicyTargets) targets(workloads []workload, namespace string, selector labels.Selector) []ctlres.Resource {
+	var result []ctlres.Resource
+
+	for _, wl := range workloads {
+		if wl.Resource.Namespace() == namespace && selector.Matches(labels.Set(wl.Template.Labels)) {
+			result = append(result, wl.Resource)
+		}
+	}
+
+	return result
+}
+
+// isDefaultDeny returns true for policies that do not allow any traffic
+func (c *NetworkPolicyTargets) isDefaultDeny(policy networkingv1.NetworkPolicy) bool {
+	return len(policy.Spec.Ingress) == 0 && len(policy.Spec.Egress) == 0
+}
+
+func formatTargets(targets []ctlres.Resource) string {
+	if len(targets) == 0 {
+		return ""
+	}
+
+	var descs []string
+	for _, res := range targets {
+		descs = append(descs, res.Description())
+	}
+
+	return ": " + strings.Join(descs, ", ")
+}
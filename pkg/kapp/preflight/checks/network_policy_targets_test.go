@@ -0,0 +1,119 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+func TestNetworkPolicyTargets(t *testing.T) {
+	resources := []ctlres.Resource{
+		ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+  namespace: default
+spec:
+  template:
+    metadata:
+      labels:
+        app: api
+`)),
+		ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+  namespace: default
+spec:
+  template:
+    metadata:
+      labels:
+        app: db
+`)),
+		ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+  namespace: other
+spec:
+  template:
+    metadata:
+      labels:
+        app: api
+`)),
+		ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: allow-api
+  namespace: default
+spec:
+  podSelector:
+    matchLabels:
+      app: api
+  ingress:
+  - {}
+`)),
+		ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: allow-typo
+  namespace: default
+spec:
+  podSelector:
+    matchLabels:
+      app: apii
+  ingress:
+  - {}
+`)),
+		ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: allow-all
+  namespace: default
+spec:
+  podSelector: {}
+  ingress:
+  - {}
+`)),
+		ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: default-deny
+  namespace: default
+spec:
+  podSelector: {}
+  policyTypes:
+  - Ingress
+  - Egress
+`)),
+	}
+
+	findings := preflighttest.RunCheckOnResources(t, checks.NewNetworkPolicyTargets(true), resources)
+
+	require.Equal(t, []string{
+		`networkpolicy/allow-api (networking.k8s.io/v1) namespace: default: podSelector "app=api" targets 1 workload(s) ` +
+			`in the change: deployment/api (apps/v1) namespace: default`,
+		`networkpolicy/allow-typo (networking.k8s.io/v1) namespace: default: podSelector "app=apii" matches no workloads ` +
+			`in the change, policy has no effect unless matching pods exist in the cluster`,
+		`networkpolicy/allow-all (networking.k8s.io/v1) namespace: default: empty podSelector selects all pods in namespace "default", ` +
+			`rules allowing traffic apply to all of them (targets 2 workload(s) in the change: ` +
+			`deployment/api (apps/v1) namespace: default, statefulset/db (apps/v1) namespace: default)`,
+	}, preflighttest.Messages(findings))
+
+	require.Equal(t, []preflight.Severity{preflight.SeverityInfo, preflight.SeverityWarning, preflight.SeverityWarning},
+		[]preflight.Severity{findings[0].Severity, findings[1].Severity, findings[2].Severity})
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+)
+
+const (
+	NoDirectNodeNameName = "NoDirectNodeName"
+)
+
+// NoDirectNodeNameConfig is the configuration accepted
+// by the NoDirectNodeName preflight check
+type NoDirectNodeNameConfig struct {
+	// Strict reports errors instead of warnings
+	Strict bool `json:"strict" description:"Report errors instead of warnings"`
+	// ExemptionAnnotation marks workloads (or their pod
+	// templates) that intentionally run on a specific node
+	ExemptionAnnotation string `json:"exemptionAnnotation" description:"Annotation marking workloads that intentionally run on a specific node"`
+}
+
+// NoDirectNodeName is an implementation of preflight.Check
+// that warns about workloads whose pod spec sets nodeName.
+// Such pods bypass the scheduler (including resource fit and
+// taints) and fail once the node is replaced or renamed.
+// Node selectors or affinity should be used instead.
+type NoDirectNodeName struct {
+	enabled bool
+	config  NoDirectNodeNameConfig
+}
+
+var _ preflight.ConfigSchemaCheck = &NoDirectNodeName{}
+var _ preflight.DescribedCheck = &NoDirectNodeName{}
+
+func NewNoDirectNodeName(enabled bool) preflight.Check {
+	return &NoDirectNodeName{
+		enabled: enabled,
+		config: NoDirectNodeNameConfig{
+			ExemptionAnnotation: "preflight.kapp.k14s.io/node-name-allowed",
+		},
+	}
+}
+
+func (c *NoDirectNodeName) Description() string {
+	return "Warns about workloads that bypass the scheduler by setting nodeName"
+}
+
+func (c *NoDirectNodeName) Enabled() bool {
+	return c.enabled
+}
+
+func (c *NoDirectNodeName) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *NoDirectNodeName) SetConfig(config preflight.CheckConfig) error {
+	newConfig := c.config
+
+	err := config.Decode(&newConfig)
+	if err != nil {
+		return err
+	}
+	if len(newConfig.ExemptionAnnotation) == 0 {
+		return fmt.Errorf("expected exemptionAnnotation to be non-empty")
+	}
+
+	c.config = newConfig
+	return nil
+}
+
+func (c *NoDirectNodeName) Config() preflight.CheckConfig {
+	return preflight.NewCheckConfig(c.config)
+}
+
+func (c *NoDirectNodeName) ConfigSchema() []preflight.ConfigField {
+	return preflight.NewConfigSchema(NoDirectNodeNameConfig{})
+}
+
+func (c *NoDirectNodeName) Run(_ context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	workloads, err := upsertedWorkloads(changeGraph)
+	if err != nil {
+		return err
+	}
+
+	newFinding := preflight.NewWarning
+	if c.config.Strict {
+		newFinding = preflight.NewError
+	}
+
+	var findings []error
+
+	for _, wl := range workloads {
+		nodeName := wl.Template.Spec.NodeName
+		if len(nodeName) == 0 || c.exempt(wl) {
+			continue
+		}
+
+		findings = append(findings, newFinding(wl.Resource,
+			"pod template sets nodeName %q which bypasses the scheduler, use nodeSelector or node affinity instead "+
+				"(annotate with %q if pods intentionally run on this node)", nodeName, c.config.ExemptionAnnotation))
+	}
+
+	return errors.Join(findings...)
+}
+
+func (c *NoDirectNodeName) exempt(wl workload) bool {
+	if _, found := wl.Resource.Annotations()[c.config.ExemptionAnnotation]; found {
+		return true
+	}
+	_, found := wl.Template.Annotations[c.config.ExemptionAnnotation]
+	return found
+}
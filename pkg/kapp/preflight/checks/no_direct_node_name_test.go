@@ -0,0 +1,105 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+func TestNoDirectNodeName(t *testing.T) {
+	pod := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: Pod
+metadata:
+  name: pinned
+  namespace: default
+spec:
+  nodeName: worker-1
+  containers:
+  - name: app
+    image: app
+`))
+	deployment := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: pinned
+  namespace: default
+spec:
+  template:
+    spec:
+      nodeName: worker-2
+      containers:
+      - name: app
+        image: app
+`))
+	exempt := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: exempt
+  namespace: default
+spec:
+  template:
+    metadata:
+      annotations:
+        preflight.kapp.k14s.io/node-name-allowed: ""
+    spec:
+      nodeName: worker-1
+      containers:
+      - name: agent
+        image: agent
+`))
+	scheduled := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: scheduled
+  namespace: default
+spec:
+  template:
+    spec:
+      nodeSelector:
+        kubernetes.io/hostname: worker-1
+      containers:
+      - name: app
+        image: app
+`))
+
+	resources := []ctlres.Resource{pod, deployment, exempt, scheduled}
+
+	expectedMessages := []string{
+		`pod/pinned (v1) namespace: default: pod template sets nodeName "worker-1" which bypasses the scheduler, ` +
+			`use nodeSelector or node affinity instead (annotate with "preflight.kapp.k14s.io/node-name-allowed" if pods intentionally run on this node)`,
+		`deployment/pinned (apps/v1) namespace: default: pod template sets nodeName "worker-2" which bypasses the scheduler, ` +
+			`use nodeSelector or node affinity instead (annotate with "preflight.kapp.k14s.io/node-name-allowed" if pods intentionally run on this node)`,
+	}
+
+	t.Run("default config reports warnings", func(t *testing.T) {
+		findings := preflighttest.RunCheckOnResources(t, checks.NewNoDirectNodeName(true), resources)
+		require.Equal(t, expectedMessages, preflighttest.Messages(findings))
+		require.Equal(t, 0, preflight.CountAtLeast(findings, preflight.SeverityError))
+	})
+
+	t.Run("strict mode reports errors", func(t *testing.T) {
+		check := checks.NewNoDirectNodeName(true).(preflight.ConfigurableCheck)
+		require.NoError(t, check.SetConfig(preflight.CheckConfig{"strict": true}))
+
+		findings := preflighttest.RunCheckOnResources(t, check, resources)
+		require.Equal(t, expectedMessages, preflighttest.Messages(findings))
+		require.Equal(t, 2, preflight.CountAtLeast(findings, preflight.SeverityError))
+	})
+
+	t.Run("empty exemption annotation, error returned", func(t *testing.T) {
+		check := checks.NewNoDirectNodeName(true).(preflight.ConfigurableCheck)
+		require.EqualError(t, check.SetConfig(preflight.CheckConfig{"exemptionAnnotation": ""}),
+			"expected exemptionAnnotation to be non-empty")
+	})
+}
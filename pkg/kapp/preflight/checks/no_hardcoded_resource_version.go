@@ -0,0 +1,97 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+const (
+	NoHardcodedResourceVersionName = "NoHardcodedResourceVersion"
+)
+
+var (
+	// serverManagedMetadataFields are set by the API server
+	// and are typically left over from exporting resources
+	serverManagedMetadataFields = []string{"resourceVersion", "uid", "creationTimestamp"}
+)
+
+// NoHardcodedResourceVersion is an implementation of preflight.Check
+// that warns about resources whose manifests carry metadata managed
+// by the API server (resourceVersion, uid and creationTimestamp).
+// Such fields are typically left over from exporting resources with
+// kubectl get and make creation fail or conflict with other updates.
+// Since kapp copies these fields from existing resources before
+// updating them, only values that differ from the cluster are
+// reported for resources that already exist.
+type NoHardcodedResourceVersion struct {
+	enabled bool
+}
+
+var _ preflight.DescribedCheck = &NoHardcodedResourceVersion{}
+
+func NewNoHardcodedResourceVersion(enabled bool) preflight.Check {
+	return &NoHardcodedResourceVersion{enabled: enabled}
+}
+
+func (c *NoHardcodedResourceVersion) Description() string {
+	return "Warns about resources carrying server managed metadata (resourceVersion, uid, creationTimestamp) in their manifests"
+}
+
+func (c *NoHardcodedResourceVersion) Enabled() bool {
+	return c.enabled
+}
+
+func (c *NoHardcodedResourceVersion) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *NoHardcodedResourceVersion) Run(_ context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	var findings []error
+
+	for _, change := range changeGraph.All() {
+		res := change.Change.Resource()
+
+		if change.Change.Op() != ctldgraph.ActualChangeOpUpsert {
+			continue
+		}
+
+		existingRes := clusterOriginalResource(change.Change)
+
+		for _, field := range serverManagedMetadataFields {
+			val, found := c.metadataField(res, field)
+			if !found {
+				continue
+			}
+			if existingRes != nil {
+				existingVal, _ := c.metadataField(existingRes, field)
+				if reflect.DeepEqual(val, existingVal) {
+					continue
+				}
+			}
+			findings = append(findings, preflight.NewWarning(res,
+				"metadata.%s is managed by the API server and should not be hardcoded, remove it from the manifest", field))
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+func (c *NoHardcodedResourceVersion) metadataField(res ctlres.Resource, field string) (interface{}, bool) {
+	metadata, ok := res.UnstructuredObject()["metadata"].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	val, found := metadata[field]
+	if !found || val == nil || val == "" {
+		return nil, false
+	}
+	return val, true
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+func TestNoHardcodedResourceVersion(t *testing.T) {
+	exported := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: exported
+  namespace: default
+  resourceVersion: "12345"
+  uid: 0d6b5f0e-7c1a-4d1b-9d8e-2f7c0b1a2b3c
+  creationTimestamp: "2024-01-01T00:00:00Z"
+`))
+	// Metadata copied over from the cluster resource by kapp
+	rebased := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: rebased
+  namespace: default
+  resourceVersion: "200"
+  uid: 1d6b5f0e-7c1a-4d1b-9d8e-2f7c0b1a2b3c
+`))
+	rebasedExisting := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: rebased
+  namespace: default
+  resourceVersion: "200"
+  uid: 1d6b5f0e-7c1a-4d1b-9d8e-2f7c0b1a2b3c
+`))
+	stale := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: stale
+  namespace: default
+  resourceVersion: "100"
+`))
+	staleExisting := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: stale
+  namespace: default
+  resourceVersion: "300"
+`))
+	deleted := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: deleted
+  namespace: default
+  resourceVersion: "400"
+`))
+	clean := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: clean
+  namespace: default
+  creationTimestamp: null
+`))
+
+	findings := preflighttest.RunCheckOnChanges(t, checks.NewNoHardcodedResourceVersion(true),
+		preflighttest.Change{Res: exported, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+		preflighttest.Change{Res: rebased, ExistingRes: rebasedExisting, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+		preflighttest.Change{Res: stale, ExistingRes: staleExisting, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+		preflighttest.Change{Res: deleted, ChangeOp: ctldgraph.ActualChangeOpDelete},
+		preflighttest.Change{Res: clean, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+	)
+
+	require.Equal(t, []string{
+		`configmap/exported (v1) namespace: default: metadata.resourceVersion is managed by the API server ` +
+			`and should not be hardcoded, remove it from the manifest`,
+		`configmap/exported (v1) namespace: default: metadata.uid is managed by the API server ` +
+			`and should not be hardcoded, remove it from the manifest`,
+		`configmap/exported (v1) namespace: default: metadata.creationTimestamp is managed by the API server ` +
+			`and should not be hardcoded, remove it from the manifest`,
+		`configmap/stale (v1) namespace: default: metadata.resourceVersion is managed by the API server ` +
+			`and should not be hardcoded, remove it from the manifest`,
+	}, preflighttest.Messages(findings))
+}
@@ -0,0 +1,46 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"fmt"
+
+	cmdcore "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/core"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func listNodes(ctx context.Context, depsFactory cmdcore.DepsFactory) ([]corev1.Node, error) {
+	client, err := depsFactory.CoreClient()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("Listing nodes: %w", err)
+	}
+
+	// Distinguish between "not listed" and "no nodes"
+	if nodes.Items == nil {
+		return []corev1.Node{}, nil
+	}
+	return nodes.Items, nil
+}
+
+// schedulableNodes returns nodes that are schedulable
+// and match the node selector of a pod spec
+func schedulableNodes(nodes []corev1.Node, nodeSelector map[string]string) []corev1.Node {
+	selector := labels.SelectorFromSet(nodeSelector)
+
+	var result []corev1.Node
+	for _, node := range nodes {
+		if !node.Spec.Unschedulable && selector.Matches(labels.Set(node.Labels)) {
+			result = append(result, node)
+		}
+	}
+	return result
+}
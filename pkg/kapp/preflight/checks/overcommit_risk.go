@@ -0,0 +1,117 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	OvercommitRiskName = "OvercommitRisk"
+)
+
+// OvercommitRiskConfig is the configuration accepted
+// by the OvercommitRisk preflight check
+type OvercommitRiskConfig struct {
+	// MaxRatio is the largest acceptable ratio of limit to request
+	MaxRatio float64 `json:"maxRatio" description:"Largest acceptable ratio of limit to request"`
+	// Resources lists compute resources (e.g. cpu, memory) to check
+	Resources []string `json:"resources" description:"Compute resources to check (e.g. cpu, memory)"`
+}
+
+// OvercommitRisk is an implementation of preflight.Check
+// that warns about containers whose resource limits are far above
+// their requests. Scheduling is based on requests, so such containers
+// may overcommit nodes and result in throttling or OOM kills.
+type OvercommitRisk struct {
+	enabled bool
+	config  OvercommitRiskConfig
+}
+
+var _ preflight.ConfigSchemaCheck = &OvercommitRisk{}
+var _ preflight.DescribedCheck = &OvercommitRisk{}
+
+func NewOvercommitRisk(enabled bool) preflight.Check {
+	return &OvercommitRisk{
+		enabled: enabled,
+		config: OvercommitRiskConfig{
+			MaxRatio:  4,
+			Resources: []string{string(corev1.ResourceCPU), string(corev1.ResourceMemory)},
+		},
+	}
+}
+
+func (c *OvercommitRisk) Description() string {
+	return "Warns about containers whose resource limits exceed their requests by more than a configured ratio"
+}
+
+func (c *OvercommitRisk) Enabled() bool {
+	return c.enabled
+}
+
+func (c *OvercommitRisk) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *OvercommitRisk) SetConfig(config preflight.CheckConfig) error {
+	newConfig := c.config
+
+	err := config.Decode(&newConfig)
+	if err != nil {
+		return err
+	}
+	if newConfig.MaxRatio < 1 {
+		return fmt.Errorf("expected maxRatio to be at least 1")
+	}
+
+	c.config = newConfig
+	return nil
+}
+
+func (c *OvercommitRisk) Config() preflight.CheckConfig {
+	return preflight.NewCheckConfig(c.config)
+}
+
+func (c *OvercommitRisk) ConfigSchema() []preflight.ConfigField {
+	return preflight.NewConfigSchema(OvercommitRiskConfig{})
+}
+
+func (c *OvercommitRisk) Run(_ context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	workloads, err := upsertedWorkloads(changeGraph)
+	if err != nil {
+		return err
+	}
+
+	var findings []error
+
+	for _, wl := range workloads {
+		for _, container := range allContainers(wl.Template.Spec) {
+			for _, resourceName := range c.config.Resources {
+				request, found := container.Resources.Requests[corev1.ResourceName(resourceName)]
+				if !found || request.IsZero() {
+					continue
+				}
+				limit, found := container.Resources.Limits[corev1.ResourceName(resourceName)]
+				if !found {
+					continue
+				}
+
+				ratio := limit.AsApproximateFloat64() / request.AsApproximateFloat64()
+				if ratio > c.config.MaxRatio {
+					findings = append(findings, preflight.NewWarning(wl.Resource,
+						"container %q %s limit %s is %.1fx its request %s (maximum %gx)",
+						container.Name, resourceName, &limit, ratio, &request, c.config.MaxRatio))
+				}
+			}
+		}
+	}
+
+	return errors.Join(findings...)
+}
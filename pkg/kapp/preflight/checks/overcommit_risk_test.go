@@ -0,0 +1,106 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+func TestOvercommitRisk(t *testing.T) {
+	deploymentYAML := func(resources string) string {
+		return `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: default
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        resources: ` + resources + `
+`
+	}
+
+	testCases := []struct {
+		name             string
+		resYAML          string
+		config           preflight.CheckConfig
+		expectedWarnings []string
+	}{
+		{
+			name:    "limits within ratio",
+			resYAML: deploymentYAML(`{requests: {cpu: 500m, memory: 1Gi}, limits: {cpu: "2", memory: 2Gi}}`),
+		},
+		{
+			name:    "limits exceed ratio",
+			resYAML: deploymentYAML(`{requests: {cpu: 100m, memory: 256Mi}, limits: {cpu: "1", memory: 4Gi}}`),
+			expectedWarnings: []string{
+				`deployment/app (apps/v1) namespace: default: container "app" cpu limit 1 is 10.0x its request 100m (maximum 4x)`,
+				`deployment/app (apps/v1) namespace: default: container "app" memory limit 4Gi is 16.0x its request 256Mi (maximum 4x)`,
+			},
+		},
+		{
+			name:    "ratio and resources are configurable",
+			resYAML: deploymentYAML(`{requests: {cpu: 100m, memory: 1Gi}, limits: {cpu: "1", memory: 2Gi}}`),
+			config:  preflight.CheckConfig{"maxRatio": 1.5, "resources": []interface{}{"memory"}},
+			expectedWarnings: []string{
+				`deployment/app (apps/v1) namespace: default: container "app" memory limit 2Gi is 2.0x its request 1Gi (maximum 1.5x)`,
+			},
+		},
+		{
+			name:    "containers without requests or limits are ignored",
+			resYAML: deploymentYAML(`{requests: {cpu: 100m}, limits: {memory: 4Gi}}`),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := ctlres.MustNewResourceFromBytes([]byte(tc.resYAML))
+
+			check := checks.NewOvercommitRisk(true).(preflight.ConfigurableCheck)
+			require.NoError(t, check.SetConfig(tc.config))
+
+			findings := preflighttest.RunCheckOnResources(t, check, []ctlres.Resource{res})
+			require.Equal(t, tc.expectedWarnings, preflighttest.Messages(findings))
+		})
+	}
+}
+
+func TestOvercommitRiskInvalidConfig(t *testing.T) {
+	check := checks.NewOvercommitRisk(true).(preflight.ConfigurableCheck)
+	require.Error(t, check.SetConfig(preflight.CheckConfig{"unknownKey": 1}))
+	require.Error(t, check.SetConfig(preflight.CheckConfig{"maxRatio": 0.5}))
+}
+
+func TestOvercommitRiskGolden(t *testing.T) {
+	res := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+  namespace: default
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: migrate
+        resources: {requests: {memory: 64Mi}, limits: {memory: 1Gi}}
+      containers:
+      - name: db
+        resources: {requests: {cpu: 250m, memory: 1Gi}, limits: {cpu: "4", memory: 2Gi}}
+      - name: sidecar
+        resources: {requests: {cpu: 10m}, limits: {cpu: 500m}}
+`))
+
+	findings := preflighttest.RunCheckOnResources(t, checks.NewOvercommitRisk(true), []ctlres.Resource{res})
+	preflighttest.AssertFindingsGolden(t, "testdata/overcommit_risk.golden", findings)
+}
@@ -0,0 +1,365 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	cmdcore "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/core"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	PodPVCTopologyConsistentName = "PodPVCTopologyConsistent"
+
+	defaultStorageClassAnnKey = "storageclass.kubernetes.io/is-default-class"
+)
+
+var (
+	pvcGK          = schema.GroupKind{Group: "", Kind: "PersistentVolumeClaim"}
+	storageClassGK = schema.GroupKind{Group: "storage.k8s.io", Kind: "StorageClass"}
+)
+
+// PodPVCTopologyConsistent is an implementation of preflight.Check
+// that warns about workloads whose node selector or required node
+// affinity contradicts allowed topologies of storage classes of
+// PVCs they mount. Volumes cannot be provisioned (or attached) on
+// nodes the pods are allowed to run on, hence pods never schedule.
+// PVCs and StorageClasses are looked up in the change first
+// and in the cluster otherwise.
+type PodPVCTopologyConsistent struct {
+	depsFactory cmdcore.DepsFactory
+	enabled     bool
+}
+
+var _ preflight.DescribedCheck = &PodPVCTopologyConsistent{}
+
+func NewPodPVCTopologyConsistent(depsFactory cmdcore.DepsFactory, enabled bool) preflight.Check {
+	return &PodPVCTopologyConsistent{depsFactory: depsFactory, enabled: enabled}
+}
+
+func (c *PodPVCTopologyConsistent) Description() string {
+	return "Warns about pods whose node constraints contradict allowed topologies of their PVCs' storage classes"
+}
+
+func (c *PodPVCTopologyConsistent) Enabled() bool {
+	return c.enabled
+}
+
+func (c *PodPVCTopologyConsistent) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *PodPVCTopologyConsistent) Run(ctx context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	workloads, err := upsertedWorkloads(changeGraph)
+	if err != nil {
+		return err
+	}
+
+	lookup, err := newStorageLookup(ctx, c.depsFactory, changeGraph)
+	if err != nil {
+		return err
+	}
+
+	var findings []error
+
+	for _, wl := range workloads {
+		constraints := c.nodeConstraints(wl.Template.Spec)
+		if len(constraints) == 0 {
+			continue
+		}
+
+		claims, err := c.claims(wl, lookup)
+		if err != nil {
+			return err
+		}
+
+		for _, claim := range claims {
+			sc, err := lookup.StorageClass(claim.StorageClassName)
+			if err != nil {
+				return err
+			}
+			if sc == nil || len(sc.AllowedTopologies) == 0 || c.compatible(constraints, sc.AllowedTopologies) {
+				continue
+			}
+
+			findings = append(findings, preflight.NewWarning(wl.Resource,
+				"node constraints (%s) contradict allowed topologies (%s) of storage class %q used by PVC %q",
+				c.formatConstraints(constraints), c.formatTopologies(sc.AllowedTopologies), sc.Name, claim.Name))
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+type podClaim struct {
+	Name string
+	// StorageClassName is nil when default storage class is used
+	StorageClassName *string
+}
+
+// claims returns PVCs mounted by workload pods including PVCs
+// created from StatefulSet volume claim templates. Claims that
+// are not found neither in the change nor in the cluster are skipped.
+func (c *PodPVCTopologyConsistent) claims(wl workload, lookup *storageLookup) ([]podClaim, error) {
+	var result []podClaim
+
+	templates := map[string]corev1.PersistentVolumeClaim{}
+
+	if wl.Resource.GroupKind() == statefulSetGK {
+		var sts appsv1.StatefulSet
+
+		err := wl.Resource.AsUncheckedTypedObj(&sts)
+		if err != nil {
+			return nil, fmt.Errorf("Resource %s: %w", wl.Resource.Description(), err)
+		}
+
+		for _, tpl := range sts.Spec.VolumeClaimTemplates {
+			templates[tpl.Name] = tpl
+			result = append(result, podClaim{Name: tpl.Name + "-" + sts.Name + "-*", StorageClassName: tpl.Spec.StorageClassName})
+		}
+	}
+
+	for _, vol := range wl.Template.Spec.Volumes {
+		if vol.PersistentVolumeClaim == nil {
+			continue
+		}
+		if _, found := templates[vol.Name]; found {
+			// Volume of the same name refers to claims created from template
+			continue
+		}
+
+		pvc, err := lookup.PVC(wl.Resource.Namespace(), vol.PersistentVolumeClaim.ClaimName)
+		if err != nil {
+			return nil, err
+		}
+		if pvc != nil {
+			result = append(result, podClaim{Name: pvc.Name, StorageClassName: pvc.Spec.StorageClassName})
+		}
+	}
+
+	return result, nil
+}
+
+// nodeConstraints returns allowed values of node labels based on
+// node selector and required node affinity (only when it has a single
+// term since multiple terms are alternatives)
+func (c *PodPVCTopologyConsistent) nodeConstraints(podSpec corev1.PodSpec) map[string][]string {
+	result := map[string][]string{}
+
+	for key, val := range podSpec.NodeSelector {
+		result[key] = []string{val}
+	}
+
+	affinity := podSpec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return result
+	}
+
+	terms := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) != 1 {
+		return result
+	}
+
+	for _, expr := range terms[0].MatchExpressions {
+		if expr.Operator != corev1.NodeSelectorOpIn {
+			continue
+		}
+		if existing, found := result[expr.Key]; found {
+			result[expr.Key] = intersectStrings(existing, expr.Values)
+		} else {
+			result[expr.Key] = expr.Values
+		}
+	}
+
+	return result
+}
+
+// compatible returns true if at least one allowed topology
+// term can be satisfied along with node constraints
+func (c *PodPVCTopologyConsistent) compatible(constraints map[string][]string, topologies []corev1.TopologySelectorTerm) bool {
+	for _, term := range topologies {
+		termCompatible := true
+
+		for _, expr := range term.MatchLabelExpressions {
+			if allowed, found := constraints[expr.Key]; found && len(intersectStrings(allowed, expr.Values)) == 0 {
+				termCompatible = false
+				break
+			}
+		}
+
+		if termCompatible {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *PodPVCTopologyConsistent) formatConstraints(constraints map[string][]string) string {
+	var result []string
+	for key, vals := range constraints {
+		result = append(result, fmt.Sprintf("%s in [%s]", key, strings.Join(vals, ", ")))
+	}
+	sort.Strings(result)
+	return strings.Join(result, ", ")
+}
+
+func (c *PodPVCTopologyConsistent) formatTopologies(topologies []corev1.TopologySelectorTerm) string {
+	var result []string
+	for _, term := range topologies {
+		var exprs []string
+		for _, expr := range term.MatchLabelExpressions {
+			exprs = append(exprs, fmt.Sprintf("%s in [%s]", expr.Key, strings.Join(expr.Values, ", ")))
+		}
+		result = append(result, strings.Join(exprs, " and "))
+	}
+	return strings.Join(result, " or ")
+}
+
+func intersectStrings(a, b []string) []string {
+	var result []string
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				result = append(result, x)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// storageLookup finds PVCs and StorageClasses in the change
+// graph or in the cluster (listed lazily on first lookup)
+type storageLookup struct {
+	ctx         context.Context
+	depsFactory cmdcore.DepsFactory
+
+	changePVCs           map[string]corev1.PersistentVolumeClaim
+	changeStorageClasses []storagev1.StorageClass
+
+	clusterPVCs           map[string]map[string]corev1.PersistentVolumeClaim
+	clusterStorageClasses []storagev1.StorageClass
+}
+
+func newStorageLookup(ctx context.Context, depsFactory cmdcore.DepsFactory,
+	changeGraph *ctldgraph.ChangeGraph) (*storageLookup, error) {
+
+	lookup := &storageLookup{
+		ctx:         ctx,
+		depsFactory: depsFactory,
+		changePVCs:  map[string]corev1.PersistentVolumeClaim{},
+		clusterPVCs: map[string]map[string]corev1.PersistentVolumeClaim{},
+	}
+
+	for _, change := range changeGraph.All() {
+		res := change.Change.Resource()
+
+		if change.Change.Op() != ctldgraph.ActualChangeOpUpsert {
+			continue
+		}
+
+		switch res.GroupKind() {
+		case pvcGK:
+			var pvc corev1.PersistentVolumeClaim
+			err := res.AsUncheckedTypedObj(&pvc)
+			if err != nil {
+				return nil, fmt.Errorf("Resource %s: %w", res.Description(), err)
+			}
+			lookup.changePVCs[res.Namespace()+"/"+res.Name()] = pvc
+
+		case storageClassGK:
+			var sc storagev1.StorageClass
+			err := res.AsUncheckedTypedObj(&sc)
+			if err != nil {
+				return nil, fmt.Errorf("Resource %s: %w", res.Description(), err)
+			}
+			lookup.changeStorageClasses = append(lookup.changeStorageClasses, sc)
+		}
+	}
+
+	return lookup, nil
+}
+
+// PVC returns nil if PVC is not found
+func (l *storageLookup) PVC(namespace, name string) (*corev1.PersistentVolumeClaim, error) {
+	if pvc, found := l.changePVCs[namespace+"/"+name]; found {
+		return &pvc, nil
+	}
+
+	pvcs, found := l.clusterPVCs[namespace]
+	if !found {
+		client, err := l.depsFactory.CoreClient()
+		if err != nil {
+			return nil, err
+		}
+
+		list, err := client.CoreV1().PersistentVolumeClaims(namespace).List(l.ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("Listing persistent volume claims in namespace %s: %w", namespace, err)
+		}
+
+		pvcs = map[string]corev1.PersistentVolumeClaim{}
+		for _, pvc := range list.Items {
+			pvcs[pvc.Name] = pvc
+		}
+		l.clusterPVCs[namespace] = pvcs
+	}
+
+	if pvc, found := pvcs[name]; found {
+		return &pvc, nil
+	}
+	return nil, nil
+}
+
+// StorageClass returns the named (or default, if name is nil)
+// storage class. Returns nil if storage class is not found.
+func (l *storageLookup) StorageClass(name *string) (*storagev1.StorageClass, error) {
+	if name != nil && len(*name) == 0 {
+		// Empty storage class name disables dynamic provisioning
+		return nil, nil
+	}
+
+	if sc := l.findStorageClass(l.changeStorageClasses, name); sc != nil {
+		return sc, nil
+	}
+
+	if l.clusterStorageClasses == nil {
+		client, err := l.depsFactory.CoreClient()
+		if err != nil {
+			return nil, err
+		}
+
+		list, err := client.StorageV1().StorageClasses().List(l.ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("Listing storage classes: %w", err)
+		}
+
+		l.clusterStorageClasses = append([]storagev1.StorageClass{}, list.Items...)
+	}
+
+	return l.findStorageClass(l.clusterStorageClasses, name), nil
+}
+
+func (l *storageLookup) findStorageClass(scs []storagev1.StorageClass, name *string) *storagev1.StorageClass {
+	for i, sc := range scs {
+		if name == nil && sc.Annotations[defaultStorageClassAnnKey] == "true" {
+			return &scs[i]
+		}
+		if name != nil && sc.Name == *name {
+			return &scs[i]
+		}
+	}
+	return nil
+}
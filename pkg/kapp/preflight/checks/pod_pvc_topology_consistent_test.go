@@ -0,0 +1,149 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodPVCTopologyConsistent(t *testing.T) {
+	zonalClass := func(name string, isDefault bool, zones ...string) storagev1.StorageClass {
+		sc := storagev1.StorageClass{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			AllowedTopologies: []corev1.TopologySelectorTerm{{
+				MatchLabelExpressions: []corev1.TopologySelectorLabelRequirement{{Key: "topology.kubernetes.io/zone", Values: zones}},
+			}},
+		}
+		if isDefault {
+			sc.Annotations = map[string]string{"storageclass.kubernetes.io/is-default-class": "true"}
+		}
+		return sc
+	}
+
+	storageClasses := []storagev1.StorageClass{
+		zonalClass("zone-a", true, "a"),
+		zonalClass("zone-ab", false, "a", "b"),
+		{ObjectMeta: metav1.ObjectMeta{Name: "anywhere"}},
+	}
+
+	storageClassName := "zone-ab"
+	pvcs := []corev1.PersistentVolumeClaim{{
+		ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "default"},
+		Spec:       corev1.PersistentVolumeClaimSpec{StorageClassName: &storageClassName},
+	}}
+
+	deploymentYAML := func(nodeSelector, claimName string) string {
+		return `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: default
+spec:
+  template:
+    spec:
+      nodeSelector: ` + nodeSelector + `
+      volumes:
+      - name: data
+        persistentVolumeClaim:
+          claimName: ` + claimName + `
+`
+	}
+
+	pvcYAML := func(storageClassName string) string {
+		return `
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+  namespace: default
+spec:
+  storageClassName: ` + storageClassName + `
+`
+	}
+
+	testCases := []struct {
+		name             string
+		resYAMLs         []string
+		expectedWarnings []string
+	}{
+		{
+			name:     "node selector matches allowed topology",
+			resYAMLs: []string{deploymentYAML("{topology.kubernetes.io/zone: a}", "data"), pvcYAML("zone-ab")},
+		},
+		{
+			name:     "node selector contradicts allowed topology of PVC in the change",
+			resYAMLs: []string{deploymentYAML("{topology.kubernetes.io/zone: c}", "data"), pvcYAML("zone-ab")},
+			expectedWarnings: []string{
+				`deployment/app (apps/v1) namespace: default: node constraints (topology.kubernetes.io/zone in [c]) contradict ` +
+					`allowed topologies (topology.kubernetes.io/zone in [a, b]) of storage class "zone-ab" used by PVC "data"`,
+			},
+		},
+		{
+			name:     "node selector contradicts allowed topology of PVC in the cluster",
+			resYAMLs: []string{deploymentYAML("{topology.kubernetes.io/zone: c}", "existing")},
+			expectedWarnings: []string{
+				`deployment/app (apps/v1) namespace: default: node constraints (topology.kubernetes.io/zone in [c]) contradict ` +
+					`allowed topologies (topology.kubernetes.io/zone in [a, b]) of storage class "zone-ab" used by PVC "existing"`,
+			},
+		},
+		{
+			name:     "storage classes without allowed topologies are ignored",
+			resYAMLs: []string{deploymentYAML("{topology.kubernetes.io/zone: c}", "data"), pvcYAML("anywhere")},
+		},
+		{
+			name:     "unknown PVCs are ignored",
+			resYAMLs: []string{deploymentYAML("{topology.kubernetes.io/zone: c}", "unknown")},
+		},
+		{
+			name: "required node affinity contradicts default storage class of statefulset claim template",
+			resYAMLs: []string{`
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+  namespace: default
+spec:
+  template:
+    spec:
+      affinity:
+        nodeAffinity:
+          requiredDuringSchedulingIgnoredDuringExecution:
+            nodeSelectorTerms:
+            - matchExpressions:
+              - key: topology.kubernetes.io/zone
+                operator: In
+                values: [b, c]
+  volumeClaimTemplates:
+  - metadata:
+      name: data
+`},
+			expectedWarnings: []string{
+				`statefulset/db (apps/v1) namespace: default: node constraints (topology.kubernetes.io/zone in [b, c]) contradict ` +
+					`allowed topologies (topology.kubernetes.io/zone in [a]) of storage class "zone-a" used by PVC "data-db-*"`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var resources []ctlres.Resource
+			for _, resYAML := range tc.resYAMLs {
+				resources = append(resources, ctlres.MustNewResourceFromBytes([]byte(resYAML)))
+			}
+			depsFactory := fakeDepsFactory{coreClient: &fakeCoreClient{pvcs: pvcs, storageClasses: storageClasses}}
+
+			findings := preflighttest.RunCheckOnResources(t, checks.NewPodPVCTopologyConsistent(depsFactory, true), resources)
+			require.Equal(t, tc.expectedWarnings, preflighttest.Messages(findings))
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	ProgressDeadlineSaneName = "ProgressDeadlineSane"
+
+	// Kubernetes default for spec.progressDeadlineSeconds
+	defaultProgressDeadlineSeconds = 600
+)
+
+// ProgressDeadlineSaneConfig is the configuration accepted
+// by the ProgressDeadlineSane preflight check
+type ProgressDeadlineSaneConfig struct {
+	// MinSeconds is the minimum acceptable progressDeadlineSeconds
+	MinSeconds int32 `json:"minSeconds" description:"Minimum acceptable progressDeadlineSeconds"`
+	// StartupAllowanceSeconds is the estimated time it takes to
+	// pull images and start containers, in addition to probe delays
+	StartupAllowanceSeconds int32 `json:"startupAllowanceSeconds" description:"Estimated time to pull images and start containers in addition to probe delays"`
+}
+
+// ProgressDeadlineSane is an implementation of preflight.Check
+// that warns about Deployments whose progressDeadlineSeconds is too
+// short for pods to become ready (based on their probes and estimated
+// startup time), which would result in rollouts being reported as failed.
+type ProgressDeadlineSane struct {
+	enabled bool
+	config  ProgressDeadlineSaneConfig
+}
+
+var _ preflight.ConfigSchemaCheck = &ProgressDeadlineSane{}
+var _ preflight.DescribedCheck = &ProgressDeadlineSane{}
+
+func NewProgressDeadlineSane(enabled bool) preflight.Check {
+	return &ProgressDeadlineSane{
+		enabled: enabled,
+		config: ProgressDeadlineSaneConfig{
+			MinSeconds:              60,
+			StartupAllowanceSeconds: 60,
+		},
+	}
+}
+
+func (c *ProgressDeadlineSane) Description() string {
+	return "Warns about Deployments whose progressDeadlineSeconds is too short for pods to start"
+}
+
+func (c *ProgressDeadlineSane) Enabled() bool {
+	return c.enabled
+}
+
+func (c *ProgressDeadlineSane) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *ProgressDeadlineSane) SetConfig(config preflight.CheckConfig) error {
+	newConfig := c.config
+
+	err := config.Decode(&newConfig)
+	if err != nil {
+		return err
+	}
+	if newConfig.MinSeconds < 0 || newConfig.StartupAllowanceSeconds < 0 {
+		return fmt.Errorf("expected minSeconds and startupAllowanceSeconds to be non-negative")
+	}
+
+	c.config = newConfig
+	return nil
+}
+
+func (c *ProgressDeadlineSane) Config() preflight.CheckConfig {
+	return preflight.NewCheckConfig(c.config)
+}
+
+func (c *ProgressDeadlineSane) ConfigSchema() []preflight.ConfigField {
+	return preflight.NewConfigSchema(ProgressDeadlineSaneConfig{})
+}
+
+func (c *ProgressDeadlineSane) Run(_ context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	var findings []error
+
+	for _, change := range changeGraph.All() {
+		res := change.Change.Resource()
+
+		if change.Change.Op() != ctldgraph.ActualChangeOpUpsert || res.GroupKind() != deploymentGK {
+			continue
+		}
+
+		var dep appsv1.Deployment
+
+		err := res.AsUncheckedTypedObj(&dep)
+		if err != nil {
+			return fmt.Errorf("Resource %s: %w", res.Description(), err)
+		}
+
+		deadline := int32(defaultProgressDeadlineSeconds)
+		if dep.Spec.ProgressDeadlineSeconds != nil {
+			deadline = *dep.Spec.ProgressDeadlineSeconds
+		}
+
+		startupDelay := maxStartupDelay(dep.Spec.Template.Spec)
+		required := startupDelay + c.config.StartupAllowanceSeconds
+		if required < c.config.MinSeconds {
+			required = c.config.MinSeconds
+		}
+
+		if deadline < required {
+			findings = append(findings, preflight.NewWarning(res,
+				"progressDeadlineSeconds %d is below %ds (probe startup delay %ds, startup allowance %ds, minimum %ds)",
+				deadline, required, startupDelay, c.config.StartupAllowanceSeconds, c.config.MinSeconds))
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+// maxStartupDelay returns the longest time (in seconds) any container could
+// take to be considered ready based on its startup and readiness probes
+func maxStartupDelay(podSpec corev1.PodSpec) int32 {
+	var result int32
+
+	for _, container := range podSpec.Containers {
+		var delay int32
+
+		if probe := container.StartupProbe; probe != nil {
+			delay += probe.InitialDelaySeconds + probeDefault(probe.PeriodSeconds, 10)*probeDefault(probe.FailureThreshold, 3)
+		}
+		if probe := container.ReadinessProbe; probe != nil {
+			delay += probe.InitialDelaySeconds
+		}
+
+		if delay > result {
+			result = delay
+		}
+	}
+
+	return result
+}
+
+func probeDefault(val, defaultVal int32) int32 {
+	if val == 0 {
+		return defaultVal
+	}
+	return val
+}
@@ -0,0 +1,113 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+func TestProgressDeadlineSane(t *testing.T) {
+	testCases := []struct {
+		name            string
+		resYAML         string
+		config          preflight.CheckConfig
+		expectedWarning string
+	}{
+		{
+			name: "default progress deadline is sufficient",
+			resYAML: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        readinessProbe:
+          initialDelaySeconds: 30
+`,
+		},
+		{
+			name: "progress deadline below probe delays and startup allowance",
+			resYAML: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  progressDeadlineSeconds: 90
+  template:
+    spec:
+      containers:
+      - name: app
+        startupProbe:
+          periodSeconds: 5
+          failureThreshold: 6
+        readinessProbe:
+          initialDelaySeconds: 10
+`,
+			expectedWarning: "deployment/app (apps/v1) cluster: progressDeadlineSeconds 90 is below 100s " +
+				"(probe startup delay 40s, startup allowance 60s, minimum 60s)",
+		},
+		{
+			name: "progress deadline below configured minimum",
+			resYAML: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  progressDeadlineSeconds: 90
+  template:
+    spec:
+      containers:
+      - name: app
+`,
+			config: preflight.CheckConfig{"minSeconds": 120},
+			expectedWarning: "deployment/app (apps/v1) cluster: progressDeadlineSeconds 90 is below 120s " +
+				"(probe startup delay 0s, startup allowance 60s, minimum 120s)",
+		},
+		{
+			name: "non deployments are ignored",
+			resYAML: `
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: app
+spec:
+  progressDeadlineSeconds: 1
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := ctlres.MustNewResourceFromBytes([]byte(tc.resYAML))
+
+			check := checks.NewProgressDeadlineSane(true).(preflight.ConfigurableCheck)
+			require.NoError(t, check.SetConfig(tc.config))
+
+			findings := preflighttest.RunCheckOnResources(t, check, []ctlres.Resource{res})
+			if len(tc.expectedWarning) == 0 {
+				require.Empty(t, findings)
+				return
+			}
+			require.Equal(t, []string{tc.expectedWarning}, preflighttest.Messages(findings))
+		})
+	}
+}
+
+func TestProgressDeadlineSaneInvalidConfig(t *testing.T) {
+	check := checks.NewProgressDeadlineSane(true).(preflight.ConfigurableCheck)
+	require.Error(t, check.SetConfig(preflight.CheckConfig{"unknownKey": 1}))
+	require.Error(t, check.SetConfig(preflight.CheckConfig{"minSeconds": -1}))
+}
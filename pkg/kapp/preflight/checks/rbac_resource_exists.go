@@ -0,0 +1,328 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	cmdcore "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/core"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+const (
+	RBACResourceExistsName = "RBACResourceExists"
+
+	// maxSuggestionDistance is the maximum edit distance
+	// between an unknown name and a suggested one
+	maxSuggestionDistance = 3
+)
+
+var (
+	roleGK        = schema.GroupKind{Group: "rbac.authorization.k8s.io", Kind: "Role"}
+	clusterRoleGK = schema.GroupKind{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}
+)
+
+// RBACResourceExists is an implementation of preflight.Check
+// that warns about Role and ClusterRole rules referring to API groups
+// or resources that are neither served by the cluster nor defined by
+// CRDs in the change. Such rules are typically typos or stale RBAC.
+// When simulating a target Kubernetes version, versions of built-in
+// resources removed in that version are considered not served.
+type RBACResourceExists struct {
+	depsFactory   cmdcore.DepsFactory
+	enabled       bool
+	targetVersion *preflight.KubernetesVersion
+}
+
+var _ preflight.DescribedCheck = &RBACResourceExists{}
+var _ preflight.VersionAwareCheck = &RBACResourceExists{}
+
+func NewRBACResourceExists(depsFactory cmdcore.DepsFactory, enabled bool) preflight.Check {
+	return &RBACResourceExists{depsFactory: depsFactory, enabled: enabled}
+}
+
+func (c *RBACResourceExists) Description() string {
+	return "Warns about RBAC rules referring to API groups or resources unknown to the cluster"
+}
+
+func (c *RBACResourceExists) Enabled() bool {
+	return c.enabled
+}
+
+func (c *RBACResourceExists) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *RBACResourceExists) SetTargetVersion(version preflight.KubernetesVersion) {
+	c.targetVersion = &version
+}
+
+func (c *RBACResourceExists) Run(ctx context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	var roles []ctlres.Resource
+	var crds []ctlres.Resource
+
+	for _, change := range changeGraph.All() {
+		res := change.Change.Resource()
+
+		if change.Change.Op() != ctldgraph.ActualChangeOpUpsert {
+			continue
+		}
+
+		switch res.GroupKind() {
+		case roleGK, clusterRoleGK:
+			roles = append(roles, res)
+		case crdGK:
+			crds = append(crds, res)
+		}
+	}
+
+	if len(roles) == 0 {
+		return nil
+	}
+
+	known, err := c.knownResources(ctx, crds)
+	if err != nil {
+		return err
+	}
+
+	var findings []error
+
+	for _, res := range roles {
+		// Role and ClusterRole rules have the same structure
+		var role rbacv1.ClusterRole
+
+		err := res.AsUncheckedTypedObj(&role)
+		if err != nil {
+			return fmt.Errorf("Resource %s: %w", res.Description(), err)
+		}
+
+		for i, rule := range role.Rules {
+			for _, group := range rule.APIGroups {
+				if group == rbacv1.APIGroupAll || known.failedGroups[group] {
+					continue
+				}
+
+				resources, found := known.groups[group]
+				if !found && known.removedGroups[group] {
+					findings = append(findings, preflight.NewWarning(res,
+						"rule %d: apiGroup %q is not served by Kubernetes %s", i, group, c.targetVersion))
+					continue
+				}
+				if !found {
+					findings = append(findings, preflight.NewWarning(res,
+						"rule %d: unknown apiGroup %q%s", i, group, suggestion(group, known.groupNames())))
+					continue
+				}
+
+				for _, resource := range rule.Resources {
+					if resource == rbacv1.ResourceAll || resources[resource] || c.matchesWildcardSubresource(resource, resources) {
+						continue
+					}
+					if removedIn, found := known.removed[group][resource]; found {
+						findings = append(findings, preflight.NewWarning(res,
+							"rule %d: resource %q in apiGroup %q is not served by Kubernetes %s (removed in %s)",
+							i, resource, group, c.targetVersion, removedIn))
+						continue
+					}
+					findings = append(findings, preflight.NewWarning(res,
+						"rule %d: unknown resource %q in apiGroup %q%s", i, resource, group, known.resourceSuggestion(group, resource)))
+				}
+			}
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+// matchesWildcardSubresource handles rules such as */scale
+func (c *RBACResourceExists) matchesWildcardSubresource(resource string, resources map[string]bool) bool {
+	if !strings.HasPrefix(resource, "*/") {
+		return false
+	}
+	for name := range resources {
+		if strings.HasSuffix(name, resource[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+type knownRBACResources struct {
+	// groups maps API groups to their resource names (including subresources)
+	groups map[string]map[string]bool
+	// failedGroups could not be discovered and are not checked
+	failedGroups map[string]bool
+	// removed maps resources only served in versions removed in target
+	// Kubernetes version to the latest version they were removed in
+	removed map[string]map[string]preflight.KubernetesVersion
+	// removedGroups only serve resources removed in target Kubernetes version
+	removedGroups map[string]bool
+}
+
+func (c *RBACResourceExists) knownResources(ctx context.Context, crds []ctlres.Resource) (knownRBACResources, error) {
+	known := knownRBACResources{groups: map[string]map[string]bool{}, failedGroups: map[string]bool{},
+		removed: map[string]map[string]preflight.KubernetesVersion{}, removedGroups: map[string]bool{}}
+
+	groups, err := c.depsFactory.APIGroupResources(ctx)
+	if err != nil {
+		failedGroups, ok := discovery.GroupDiscoveryFailedErrorGroups(err)
+		if !ok {
+			return known, fmt.Errorf("Discovering API resources: %w", err)
+		}
+		for gv := range failedGroups {
+			known.failedGroups[gv.Group] = true
+		}
+	}
+
+	for _, group := range groups {
+		for version, apiResources := range group.VersionedResources {
+			for _, apiRes := range apiResources {
+				gvr := schema.GroupVersionResource{Group: group.Group.Name, Version: version, Resource: apiRes.Name}
+				if c.targetVersion != nil && !c.targetVersion.ServesAPI(gvr) {
+					known.addRemoved(gvr)
+					continue
+				}
+				known.add(group.Group.Name, apiRes.Name)
+			}
+		}
+	}
+
+	for group, resources := range known.removed {
+		for resource := range resources {
+			if known.groups[group][resource] {
+				// Resource is still served in another version
+				delete(resources, resource)
+			}
+		}
+		if _, found := known.groups[group]; !found {
+			known.removedGroups[group] = true
+		}
+	}
+
+	for _, res := range crds {
+		obj := res.UnstructuredObject()
+
+		group, _, _ := unstructured.NestedString(obj, "spec", "group")
+		plural, _, _ := unstructured.NestedString(obj, "spec", "names", "plural")
+		known.add(group, plural)
+
+		versions, _, _ := unstructured.NestedSlice(obj, "spec", "versions")
+		for _, version := range versions {
+			typedVersion, ok := version.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			subresources, _, _ := unstructured.NestedMap(typedVersion, "subresources")
+			for subresource := range subresources {
+				known.add(group, plural+"/"+subresource)
+			}
+		}
+	}
+
+	return known, nil
+}
+
+func (k knownRBACResources) add(group, resource string) {
+	if k.groups[group] == nil {
+		k.groups[group] = map[string]bool{}
+	}
+	k.groups[group][resource] = true
+}
+
+func (k knownRBACResources) addRemoved(gvr schema.GroupVersionResource) {
+	removedIn, _ := preflight.APIRemovedIn(gvr)
+	if k.removed[gvr.Group] == nil {
+		k.removed[gvr.Group] = map[string]preflight.KubernetesVersion{}
+	}
+	if latest, found := k.removed[gvr.Group][gvr.Resource]; !found || removedIn.AtLeast(latest) {
+		k.removed[gvr.Group][gvr.Resource] = removedIn
+	}
+}
+
+func (k knownRBACResources) groupNames() []string {
+	var result []string
+	for group := range k.groups {
+		result = append(result, group)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// resourceSuggestion suggests a similarly named resource in the
+// same group or API groups that serve the resource
+func (k knownRBACResources) resourceSuggestion(group, resource string) string {
+	var names []string
+	for name := range k.groups[group] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if result := suggestion(resource, names); len(result) > 0 {
+		return result
+	}
+
+	var groups []string
+	for otherGroup, resources := range k.groups {
+		if resources[resource] {
+			groups = append(groups, fmt.Sprintf("%q", otherGroup))
+		}
+	}
+	sort.Strings(groups)
+
+	if len(groups) > 0 {
+		return fmt.Sprintf(" (served by apiGroup %s)", strings.Join(groups, ", "))
+	}
+	return ""
+}
+
+// suggestion returns a "did you mean" note for the closest candidate
+func suggestion(name string, candidates []string) string {
+	var best string
+	bestDistance := maxSuggestionDistance + 1
+
+	for _, candidate := range candidates {
+		if distance := editDistance(name, candidate); distance < bestDistance {
+			best = candidate
+			bestDistance = distance
+		}
+	}
+
+	if bestDistance > maxSuggestionDistance {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %q?)", best)
+}
+
+// editDistance returns Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr := make([]int, len(b)+1)
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev = curr
+	}
+
+	return prev[len(b)]
+}
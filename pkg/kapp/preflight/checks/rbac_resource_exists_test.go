@@ -0,0 +1,176 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRBACResourceExists(t *testing.T) {
+	apiGroups := newFakeAPIGroupResources(&metav1.APIResourceList{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{{Name: "pods"}, {Name: "pods/log"}, {Name: "configmaps"}, {Name: "secrets"}},
+	}, &metav1.APIResourceList{
+		GroupVersion: "apps/v1",
+		APIResources: []metav1.APIResource{{Name: "deployments"}, {Name: "deployments/scale"}},
+	}, &metav1.APIResourceList{
+		GroupVersion: "networking.k8s.io/v1",
+		APIResources: []metav1.APIResource{{Name: "ingresses"}},
+	})
+
+	roleYAML := func(rules string) string {
+		return `
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: app
+  namespace: default
+rules: ` + rules + `
+`
+	}
+
+	crdYAML := `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    plural: widgets
+  versions:
+  - name: v1
+    subresources:
+      status: {}
+`
+
+	testCases := []struct {
+		name             string
+		resYAMLs         []string
+		expectedWarnings []string
+	}{
+		{
+			name: "known groups and resources",
+			resYAMLs: []string{roleYAML(`
+- apiGroups: [""]
+  resources: [pods, pods/log, configmaps]
+  verbs: [get]
+- apiGroups: [apps]
+  resources: ["*/scale", deployments]
+  verbs: [get]
+- apiGroups: ["*"]
+  resources: [anything]
+  verbs: [get]
+- apiGroups: [networking.k8s.io]
+  resources: ["*"]
+  verbs: [get]
+`)},
+		},
+		{
+			name: "unknown groups with suggestions",
+			resYAMLs: []string{roleYAML(`
+- apiGroups: [app, totally.unknown.io]
+  resources: [deployments]
+  verbs: [get]
+`)},
+			expectedWarnings: []string{
+				`role/app (rbac.authorization.k8s.io/v1) namespace: default: rule 0: unknown apiGroup "app" (did you mean "apps"?)`,
+				`role/app (rbac.authorization.k8s.io/v1) namespace: default: rule 0: unknown apiGroup "totally.unknown.io"`,
+			},
+		},
+		{
+			name: "unknown resources with suggestions",
+			resYAMLs: []string{roleYAML(`
+- apiGroups: [""]
+  resources: [pod, deployments]
+  verbs: [get]
+`)},
+			expectedWarnings: []string{
+				`role/app (rbac.authorization.k8s.io/v1) namespace: default: rule 0: unknown resource "pod" in apiGroup "" (did you mean "pods"?)`,
+				`role/app (rbac.authorization.k8s.io/v1) namespace: default: rule 0: unknown resource "deployments" in apiGroup "" (served by apiGroup "apps")`,
+			},
+		},
+		{
+			name: "resources of CRDs in the change are known",
+			resYAMLs: []string{crdYAML, roleYAML(`
+- apiGroups: [example.com]
+  resources: [widgets, widgets/status, widgets/scale]
+  verbs: [get]
+`)},
+			expectedWarnings: []string{
+				`role/app (rbac.authorization.k8s.io/v1) namespace: default: rule 0: unknown resource "widgets/scale" in apiGroup "example.com"`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var resources []ctlres.Resource
+			for _, resYAML := range tc.resYAMLs {
+				resources = append(resources, ctlres.MustNewResourceFromBytes([]byte(resYAML)))
+			}
+			depsFactory := fakeDepsFactory{apiGroups: apiGroups}
+
+			findings := preflighttest.RunCheckOnResources(t, checks.NewRBACResourceExists(depsFactory, true), resources)
+			require.Equal(t, tc.expectedWarnings, preflighttest.Messages(findings))
+		})
+	}
+}
+
+func TestRBACResourceExistsTargetVersion(t *testing.T) {
+	apiGroups := newFakeAPIGroupResources(&metav1.APIResourceList{
+		GroupVersion: "extensions/v1beta1",
+		APIResources: []metav1.APIResource{{Name: "ingresses"}},
+	}, &metav1.APIResourceList{
+		GroupVersion: "batch/v1",
+		APIResources: []metav1.APIResource{{Name: "jobs"}},
+	}, &metav1.APIResourceList{
+		GroupVersion: "batch/v1beta1",
+		APIResources: []metav1.APIResource{{Name: "cronjobs"}, {Name: "cronjobs/status"}},
+	}, &metav1.APIResourceList{
+		GroupVersion: "policy/v1",
+		APIResources: []metav1.APIResource{{Name: "poddisruptionbudgets"}},
+	}, &metav1.APIResourceList{
+		GroupVersion: "policy/v1beta1",
+		APIResources: []metav1.APIResource{{Name: "poddisruptionbudgets"}},
+	})
+
+	role := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: app
+rules:
+- apiGroups: [extensions]
+  resources: [ingresses]
+  verbs: [get]
+- apiGroups: [batch]
+  resources: [jobs, cronjobs, cronjobs/status]
+  verbs: [get]
+- apiGroups: [policy]
+  resources: [poddisruptionbudgets]
+  verbs: [get]
+`))
+
+	check := checks.NewRBACResourceExists(fakeDepsFactory{apiGroups: apiGroups}, true)
+
+	findings := preflighttest.RunCheckOnResources(t, check, []ctlres.Resource{role})
+	require.Empty(t, preflighttest.Messages(findings))
+
+	check.(preflight.VersionAwareCheck).SetTargetVersion(preflight.KubernetesVersion{Major: 1, Minor: 25})
+
+	findings = preflighttest.RunCheckOnResources(t, check, []ctlres.Resource{role})
+	require.Equal(t, []string{
+		`clusterrole/app (rbac.authorization.k8s.io/v1) cluster: rule 0: apiGroup "extensions" is not served by Kubernetes 1.25`,
+		`clusterrole/app (rbac.authorization.k8s.io/v1) cluster: rule 1: resource "cronjobs" in apiGroup "batch" is not served by Kubernetes 1.25 (removed in 1.25)`,
+		`clusterrole/app (rbac.authorization.k8s.io/v1) cluster: rule 1: resource "cronjobs/status" in apiGroup "batch" is not served by Kubernetes 1.25 (removed in 1.25)`,
+	}, preflighttest.Messages(findings))
+}
@@ -0,0 +1,182 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strings"
+
+	cmdcore "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/core"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	ReconciliationLoopRiskName = "ReconciliationLoopRisk"
+
+	dryRunFieldManager = "kapp-preflight"
+
+	secretStringDataPath = ".stringData."
+	secretDataPath       = ".data."
+)
+
+// ReconciliationLoopRisk is an implementation of preflight.Check
+// that performs a server-side dry-run of upserted resources and warns
+// about fields whose values are changed by the API server (defaulting,
+// mutating webhooks). Such fields will show up as a diff on every deploy
+// and may result in kapp and other controllers fighting over them.
+type ReconciliationLoopRisk struct {
+	depsFactory cmdcore.DepsFactory
+	enabled     bool
+}
+
+var _ preflight.DescribedCheck = &ReconciliationLoopRisk{}
+
+func NewReconciliationLoopRisk(depsFactory cmdcore.DepsFactory, enabled bool) preflight.Check {
+	return &ReconciliationLoopRisk{depsFactory: depsFactory, enabled: enabled}
+}
+
+func (c *ReconciliationLoopRisk) Description() string {
+	return "Warns about fields that are overridden by the API server or mutating webhooks on apply"
+}
+
+func (c *ReconciliationLoopRisk) Enabled() bool {
+	return c.enabled
+}
+
+func (c *ReconciliationLoopRisk) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *ReconciliationLoopRisk) Run(ctx context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	var dynamicClient dynamic.Interface
+	var mapper meta.RESTMapper
+	var findings []error
+
+	for _, change := range changeGraph.All() {
+		res := change.Change.Resource()
+
+		if change.Change.Op() != ctldgraph.ActualChangeOpUpsert || len(res.Name()) == 0 {
+			continue
+		}
+
+		if dynamicClient == nil {
+			var err error
+
+			dynamicClient, err = c.depsFactory.DynamicClient(cmdcore.DynamicClientOpts{})
+			if err != nil {
+				return err
+			}
+
+			mapper, err = c.depsFactory.RESTMapper()
+			if err != nil {
+				return err
+			}
+		}
+
+		dryRunRes, err := c.dryRun(ctx, dynamicClient, mapper, res)
+		if err != nil {
+			// Namespace or CRD may be created as part of the same change
+			if kerrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				continue
+			}
+			findings = append(findings, preflight.NewWarning(res, "server-side dry-run failed: %s", err))
+			continue
+		}
+
+		overridden := c.overriddenFields(res, dryRunRes)
+		if len(overridden) > 0 {
+			findings = append(findings, preflight.NewWarning(res,
+				"fields are changed by the API server on apply and are likely to cause reconcile loops: %s",
+				strings.Join(overridden, ", ")))
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+func (c *ReconciliationLoopRisk) dryRun(ctx context.Context, dynamicClient dynamic.Interface,
+	mapper meta.RESTMapper, res ctlres.Resource) (ctlres.Resource, error) {
+
+	gvk := res.GroupVersion().WithKind(res.Kind())
+
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	bs, err := res.AsCompactBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var client dynamic.ResourceInterface = dynamicClient.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		client = dynamicClient.Resource(mapping.Resource).Namespace(res.Namespace())
+	}
+
+	force := true
+	opts := metav1.PatchOptions{
+		DryRun:       []string{metav1.DryRunAll},
+		FieldManager: dryRunFieldManager,
+		Force:        &force,
+	}
+
+	result, err := client.Patch(ctx, res.Name(), types.ApplyPatchType, bs, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return ctlres.NewResourceUnstructured(*result, ctlres.ResourceType{}), nil
+}
+
+// overriddenFields returns fields set in res that have
+// different values (or were removed) in dryRunRes
+func (c *ReconciliationLoopRisk) overriddenFields(res, dryRunRes ctlres.Resource) []string {
+	var result []string
+
+	dryRunFields := settableFields(dryRunRes.UnstructuredObject())
+	isSecret := res.APIVersion() == "v1" && res.Kind() == "Secret"
+
+	for path, val := range settableFields(res.UnstructuredObject()) {
+		// Secret's stringData is write-only: API server folds it into data
+		if isSecret && strings.HasPrefix(path, secretStringDataPath) {
+			dataVal, found := dryRunFields[secretDataPath+strings.TrimPrefix(path, secretStringDataPath)]
+			if !found || !isEncodedSecretValue(val, dataVal) {
+				result = append(result, path)
+			}
+			continue
+		}
+
+		dryRunVal, found := dryRunFields[path]
+		if !found || !isSubset(val, dryRunVal) {
+			result = append(result, path)
+		}
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// isEncodedSecretValue returns true if dataVal
+// is base64 encoded version of stringDataVal
+func isEncodedSecretValue(stringDataVal, dataVal interface{}) bool {
+	typedStringDataVal, ok1 := stringDataVal.(string)
+	typedDataVal, ok2 := dataVal.(string)
+	if !ok1 || !ok2 {
+		return false
+	}
+	decodedVal, err := base64.StdEncoding.DecodeString(typedDataVal)
+	return err == nil && string(decodedVal) == typedStringDataVal
+}
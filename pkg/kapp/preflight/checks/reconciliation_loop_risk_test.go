@@ -0,0 +1,141 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestReconciliationLoopRisk(t *testing.T) {
+	res := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+  namespace: default
+spec:
+  type: ClusterIP
+  ports:
+  - port: 80
+    targetPort: 8080
+`))
+
+	testCases := []struct {
+		name             string
+		op               ctldgraph.ActualChangeOp
+		mutate           func(obj *unstructured.Unstructured) error
+		expectedWarnings []string
+	}{
+		{
+			name: "server only adds defaulted fields, no warnings",
+			op:   ctldgraph.ActualChangeOpUpsert,
+			mutate: func(obj *unstructured.Unstructured) error {
+				return unstructured.SetNestedField(obj.Object, "10.0.0.1", "spec", "clusterIP")
+			},
+		},
+		{
+			name: "server overrides set fields, warning returned",
+			op:   ctldgraph.ActualChangeOpUpsert,
+			mutate: func(obj *unstructured.Unstructured) error {
+				unstructured.RemoveNestedField(obj.Object, "spec", "ports")
+				return unstructured.SetNestedField(obj.Object, "NodePort", "spec", "type")
+			},
+			expectedWarnings: []string{
+				"service/app (v1) namespace: default: fields are changed by the API server on apply " +
+					"and are likely to cause reconcile loops: .spec.ports, .spec.type",
+			},
+		},
+		{
+			name: "resources in namespaces that do not exist yet are skipped",
+			op:   ctldgraph.ActualChangeOpUpsert,
+			mutate: func(_ *unstructured.Unstructured) error {
+				return kerrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, "default")
+			},
+		},
+		{
+			name: "deleted resources are not checked",
+			op:   ctldgraph.ActualChangeOpDelete,
+			mutate: func(_ *unstructured.Unstructured) error {
+				panic("Expected no dry-run")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			depsFactory := fakeDepsFactory{
+				dynamicClient: &fakeDynamicClient{mutate: tc.mutate},
+				mapper:        newFakeRESTMapper(schema.GroupVersionKind{Version: "v1", Kind: "Service"}),
+			}
+
+			findings := preflighttest.RunCheckOnChanges(t, checks.NewReconciliationLoopRisk(depsFactory, true),
+				preflighttest.Change{Res: res, ChangeOp: tc.op})
+			require.Equal(t, tc.expectedWarnings, preflighttest.Messages(findings))
+		})
+	}
+}
+
+func TestReconciliationLoopRiskSecretStringData(t *testing.T) {
+	res := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: Secret
+metadata:
+  name: creds
+  namespace: default
+stringData:
+  username: admin
+  password: secret
+`))
+
+	testCases := []struct {
+		name             string
+		password         string
+		expectedWarnings []string
+	}{
+		{
+			name:     "stringData folded into data, no warnings",
+			password: "secret",
+		},
+		{
+			name:     "data differs from stringData, warning returned",
+			password: "changed",
+			expectedWarnings: []string{
+				"secret/creds (v1) namespace: default: fields are changed by the API server on apply " +
+					"and are likely to cause reconcile loops: .stringData.password",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// API server does not persist stringData and returns it as data instead
+			mutate := func(obj *unstructured.Unstructured) error {
+				unstructured.RemoveNestedField(obj.Object, "stringData")
+				return unstructured.SetNestedStringMap(obj.Object, map[string]string{
+					"username": base64.StdEncoding.EncodeToString([]byte("admin")),
+					"password": base64.StdEncoding.EncodeToString([]byte(tc.password)),
+				}, "data")
+			}
+
+			depsFactory := fakeDepsFactory{
+				dynamicClient: &fakeDynamicClient{mutate: mutate},
+				mapper:        newFakeRESTMapper(schema.GroupVersionKind{Version: "v1", Kind: "Secret"}),
+			}
+
+			findings := preflighttest.RunCheckOnChanges(t, checks.NewReconciliationLoopRisk(depsFactory, true),
+				preflighttest.Change{Res: res, ChangeOp: ctldgraph.ActualChangeOpUpsert})
+			require.Equal(t, tc.expectedWarnings, preflighttest.Messages(findings))
+		})
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+const (
+	RevisionHistorySaneName = "RevisionHistorySane"
+
+	// Kubernetes default for spec.revisionHistoryLimit
+	defaultRevisionHistoryLimit = 10
+)
+
+// RevisionHistorySaneConfig is the configuration accepted
+// by the RevisionHistorySane preflight check
+type RevisionHistorySaneConfig struct {
+	// MaxLimit is the maximum acceptable revisionHistoryLimit
+	MaxLimit int32 `json:"maxLimit" description:"Maximum acceptable revisionHistoryLimit"`
+	// RequireExplicit warns about workloads that rely
+	// on the default revisionHistoryLimit
+	RequireExplicit bool `json:"requireExplicit" description:"Warn about workloads relying on the default revisionHistoryLimit"`
+}
+
+// RevisionHistorySane is an implementation of preflight.Check
+// that warns about Deployments, StatefulSets and DaemonSets with
+// an excessive (or unset) revisionHistoryLimit. Old ReplicaSets and
+// ControllerRevisions are kept around for each revision and
+// accumulate in etcd for frequently updated workloads.
+type RevisionHistorySane struct {
+	enabled bool
+	config  RevisionHistorySaneConfig
+}
+
+var _ preflight.ConfigSchemaCheck = &RevisionHistorySane{}
+var _ preflight.DescribedCheck = &RevisionHistorySane{}
+
+func NewRevisionHistorySane(enabled bool) preflight.Check {
+	return &RevisionHistorySane{
+		enabled: enabled,
+		config: RevisionHistorySaneConfig{
+			MaxLimit:        defaultRevisionHistoryLimit,
+			RequireExplicit: true,
+		},
+	}
+}
+
+func (c *RevisionHistorySane) Description() string {
+	return "Warns about workloads with an excessive or unset revisionHistoryLimit"
+}
+
+func (c *RevisionHistorySane) Enabled() bool {
+	return c.enabled
+}
+
+func (c *RevisionHistorySane) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *RevisionHistorySane) SetConfig(config preflight.CheckConfig) error {
+	newConfig := c.config
+
+	err := config.Decode(&newConfig)
+	if err != nil {
+		return err
+	}
+	if newConfig.MaxLimit < 0 {
+		return fmt.Errorf("expected maxLimit to be non-negative")
+	}
+
+	c.config = newConfig
+	return nil
+}
+
+func (c *RevisionHistorySane) Config() preflight.CheckConfig {
+	return preflight.NewCheckConfig(c.config)
+}
+
+func (c *RevisionHistorySane) ConfigSchema() []preflight.ConfigField {
+	return preflight.NewConfigSchema(RevisionHistorySaneConfig{})
+}
+
+func (c *RevisionHistorySane) Run(_ context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	var findings []error
+
+	for _, change := range changeGraph.All() {
+		res := change.Change.Resource()
+
+		if change.Change.Op() != ctldgraph.ActualChangeOpUpsert {
+			continue
+		}
+
+		limit, found, err := c.revisionHistoryLimit(res)
+		if err != nil {
+			return fmt.Errorf("Resource %s: %w", res.Description(), err)
+		}
+		if !found {
+			continue
+		}
+
+		switch {
+		case limit == nil:
+			if c.config.RequireExplicit {
+				findings = append(findings, preflight.NewWarning(res,
+					"revisionHistoryLimit is not set (defaults to %d)", defaultRevisionHistoryLimit))
+			}
+		case *limit > c.config.MaxLimit:
+			findings = append(findings, preflight.NewWarning(res,
+				"revisionHistoryLimit %d exceeds %d", *limit, c.config.MaxLimit))
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+// revisionHistoryLimit returns revisionHistoryLimit of supported workloads
+func (c *RevisionHistorySane) revisionHistoryLimit(res ctlres.Resource) (*int32, bool, error) {
+	switch res.GroupKind() {
+	case deploymentGK:
+		var obj appsv1.Deployment
+		err := res.AsUncheckedTypedObj(&obj)
+		return obj.Spec.RevisionHistoryLimit, true, err
+
+	case statefulSetGK:
+		var obj appsv1.StatefulSet
+		err := res.AsUncheckedTypedObj(&obj)
+		return obj.Spec.RevisionHistoryLimit, true, err
+
+	case daemonSetGK:
+		var obj appsv1.DaemonSet
+		err := res.AsUncheckedTypedObj(&obj)
+		return obj.Spec.RevisionHistoryLimit, true, err
+
+	default:
+		return nil, false, nil
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+func TestRevisionHistorySane(t *testing.T) {
+	testCases := []struct {
+		name             string
+		resYAML          string
+		config           preflight.CheckConfig
+		expectedWarnings []string
+	}{
+		{
+			name: "limit within threshold",
+			resYAML: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  revisionHistoryLimit: 3
+`,
+		},
+		{
+			name: "limit exceeds threshold",
+			resYAML: `
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  revisionHistoryLimit: 100
+`,
+			expectedWarnings: []string{"statefulset/db (apps/v1) cluster: revisionHistoryLimit 100 exceeds 10"},
+		},
+		{
+			name: "threshold is configurable",
+			resYAML: `
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: agent
+spec:
+  revisionHistoryLimit: 5
+`,
+			config:           preflight.CheckConfig{"maxLimit": 2},
+			expectedWarnings: []string{"daemonset/agent (apps/v1) cluster: revisionHistoryLimit 5 exceeds 2"},
+		},
+		{
+			name: "limit not set",
+			resYAML: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec: {}
+`,
+			expectedWarnings: []string{"deployment/app (apps/v1) cluster: revisionHistoryLimit is not set (defaults to 10)"},
+		},
+		{
+			name: "limit not set is allowed via config",
+			resYAML: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec: {}
+`,
+			config: preflight.CheckConfig{"requireExplicit": false},
+		},
+		{
+			name: "other kinds are ignored",
+			resYAML: `
+apiVersion: apps/v1
+kind: ReplicaSet
+metadata:
+  name: app
+spec: {}
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := ctlres.MustNewResourceFromBytes([]byte(tc.resYAML))
+
+			check := checks.NewRevisionHistorySane(true).(preflight.ConfigurableCheck)
+			require.NoError(t, check.SetConfig(tc.config))
+
+			findings := preflighttest.RunCheckOnResources(t, check, []ctlres.Resource{res})
+			require.Equal(t, tc.expectedWarnings, preflighttest.Messages(findings))
+		})
+	}
+}
+
+func TestRevisionHistorySaneInvalidConfig(t *testing.T) {
+	check := checks.NewRevisionHistorySane(true).(preflight.ConfigurableCheck)
+	require.Error(t, check.SetConfig(preflight.CheckConfig{"unknownKey": 1}))
+	require.Error(t, check.SetConfig(preflight.CheckConfig{"maxLimit": -1}))
+}
@@ -0,0 +1,330 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	cmdcore "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/core"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	RolloutPDBCompatibleName = "RolloutPDBCompatible"
+)
+
+var (
+	podDisruptionBudgetGK = schema.GroupKind{Group: "policy", Kind: "PodDisruptionBudget"}
+)
+
+// RolloutPDBCompatibleConfig is the configuration accepted
+// by the RolloutPDBCompatible preflight check
+type RolloutPDBCompatibleConfig struct {
+	// CheckCluster enables matching workloads against
+	// PodDisruptionBudgets that already exist in the cluster
+	CheckCluster bool `json:"checkCluster" description:"Match workloads against PodDisruptionBudgets that already exist in the cluster"`
+}
+
+// RolloutPDBCompatible is an implementation of preflight.Check that
+// cross-references update strategies of Deployments and StatefulSets
+// with PodDisruptionBudgets selecting their pods (from the change
+// and, unless disabled, from the cluster). It warns about budgets
+// that allow no disruptions at all (evictions, e.g. by node drains,
+// are blocked indefinitely) and about rollouts taking down more pods
+// than the budget allows (the budget is violated during rollouts
+// and evictions are blocked until they complete). Budgets are
+// evaluated against desired replicas of the workload only.
+type RolloutPDBCompatible struct {
+	depsFactory cmdcore.DepsFactory
+	enabled     bool
+	config      RolloutPDBCompatibleConfig
+}
+
+var _ preflight.ConfigSchemaCheck = &RolloutPDBCompatible{}
+var _ preflight.DescribedCheck = &RolloutPDBCompatible{}
+
+func NewRolloutPDBCompatible(depsFactory cmdcore.DepsFactory, enabled bool) preflight.Check {
+	return &RolloutPDBCompatible{
+		depsFactory: depsFactory,
+		enabled:     enabled,
+		config:      RolloutPDBCompatibleConfig{CheckCluster: true},
+	}
+}
+
+func (c *RolloutPDBCompatible) Description() string {
+	return "Warns about rollouts of workloads that are incompatible with their PodDisruptionBudgets"
+}
+
+func (c *RolloutPDBCompatible) Enabled() bool {
+	return c.enabled
+}
+
+func (c *RolloutPDBCompatible) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *RolloutPDBCompatible) SetConfig(config preflight.CheckConfig) error {
+	newConfig := c.config
+
+	err := config.Decode(&newConfig)
+	if err != nil {
+		return err
+	}
+
+	c.config = newConfig
+	return nil
+}
+
+func (c *RolloutPDBCompatible) Config() preflight.CheckConfig {
+	return preflight.NewCheckConfig(c.config)
+}
+
+func (c *RolloutPDBCompatible) ConfigSchema() []preflight.ConfigField {
+	return preflight.NewConfigSchema(RolloutPDBCompatibleConfig{})
+}
+
+func (c *RolloutPDBCompatible) Run(ctx context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	workloads, err := upsertedWorkloads(changeGraph)
+	if err != nil {
+		return err
+	}
+
+	changePDBs, deletedPDBs, err := c.changePDBs(changeGraph)
+	if err != nil {
+		return err
+	}
+
+	// PDBs by namespace, listed on first use
+	pdbs := map[string][]policyv1.PodDisruptionBudget{}
+
+	var findings []error
+
+	for _, wl := range workloads {
+		replicas := int(wl.ReplicaCount())
+		if replicas == 0 {
+			continue
+		}
+
+		unavailable, strategy, found, err := c.rolloutUnavailable(wl)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+
+		namespace := wl.Resource.Namespace()
+
+		nsPDBs, found := pdbs[namespace]
+		if !found {
+			nsPDBs, err = c.namespacePDBs(ctx, namespace, changePDBs, deletedPDBs)
+			if err != nil {
+				return err
+			}
+			pdbs[namespace] = nsPDBs
+		}
+
+		for _, pdb := range nsPDBs {
+			if pdb.Spec.Selector == nil {
+				continue
+			}
+			selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+			if err != nil || !selector.Matches(labels.Set(wl.Template.Labels)) {
+				// Invalid selectors are rejected by the API server
+				continue
+			}
+
+			allowed, budget, err := c.allowedDisruptions(pdb, replicas)
+			if err != nil {
+				return fmt.Errorf("PodDisruptionBudget %s/%s: %w", pdb.Namespace, pdb.Name, err)
+			}
+
+			switch {
+			case allowed <= 0:
+				findings = append(findings, preflight.NewWarning(wl.Resource,
+					"PodDisruptionBudget %q (%s) allows no disruptions of %d replica(s), "+
+						"evictions (e.g. by node drains) are blocked", pdb.Name, budget, replicas))
+
+			case unavailable > allowed:
+				findings = append(findings, preflight.NewWarning(wl.Resource,
+					"rollout takes down up to %d pod(s) (%s) while PodDisruptionBudget %q (%s) allows %d disruption(s), "+
+						"budget is violated during rollouts and evictions are blocked until they complete",
+					unavailable, strategy, pdb.Name, budget, allowed))
+			}
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+// rolloutUnavailable returns number of pods a rollout of the workload
+// may take down at the same time and description of its strategy
+func (c *RolloutPDBCompatible) rolloutUnavailable(wl workload) (int, string, bool, error) {
+	res := wl.Resource
+	replicas := int(wl.ReplicaCount())
+
+	switch res.GroupKind() {
+	case deploymentGK:
+		var deployment appsv1.Deployment
+
+		err := res.AsUncheckedTypedObj(&deployment)
+		if err != nil {
+			return 0, "", false, fmt.Errorf("Resource %s: %w", res.Description(), err)
+		}
+
+		if deployment.Spec.Strategy.Type == appsv1.RecreateDeploymentStrategyType {
+			return replicas, "strategy Recreate", true, nil
+		}
+
+		maxUnavailable := intstr.FromString("25%")
+		if deployment.Spec.Strategy.RollingUpdate != nil && deployment.Spec.Strategy.RollingUpdate.MaxUnavailable != nil {
+			maxUnavailable = *deployment.Spec.Strategy.RollingUpdate.MaxUnavailable
+		}
+
+		// Deployments round maxUnavailable down
+		unavailable, err := intstr.GetScaledValueFromIntOrPercent(&maxUnavailable, replicas, false)
+		if err != nil {
+			return 0, "", false, fmt.Errorf("Resource %s: %w", res.Description(), err)
+		}
+		return unavailable, "maxUnavailable " + maxUnavailable.String(), true, nil
+
+	case statefulSetGK:
+		var statefulSet appsv1.StatefulSet
+
+		err := res.AsUncheckedTypedObj(&statefulSet)
+		if err != nil {
+			return 0, "", false, fmt.Errorf("Resource %s: %w", res.Description(), err)
+		}
+
+		// Pods are only replaced when deleted manually
+		if statefulSet.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+			return 0, "", false, nil
+		}
+
+		maxUnavailable := intstr.FromInt(1)
+		if statefulSet.Spec.UpdateStrategy.RollingUpdate != nil && statefulSet.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable != nil {
+			maxUnavailable = *statefulSet.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable
+		}
+
+		// StatefulSets round maxUnavailable up and update at least one pod at a time
+		unavailable, err := intstr.GetScaledValueFromIntOrPercent(&maxUnavailable, replicas, true)
+		if err != nil {
+			return 0, "", false, fmt.Errorf("Resource %s: %w", res.Description(), err)
+		}
+		if unavailable < 1 {
+			unavailable = 1
+		}
+		return unavailable, "maxUnavailable " + maxUnavailable.String(), true, nil
+
+	default:
+		return 0, "", false, nil
+	}
+}
+
+// allowedDisruptions returns number of disruptions PDB allows when
+// all replicas are healthy and description of the budget
+func (c *RolloutPDBCompatible) allowedDisruptions(pdb policyv1.PodDisruptionBudget, replicas int) (int, string, error) {
+	switch {
+	case pdb.Spec.MaxUnavailable != nil:
+		maxUnavailable, err := intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MaxUnavailable, replicas, true)
+		if err != nil {
+			return 0, "", err
+		}
+		return maxUnavailable, "maxUnavailable " + pdb.Spec.MaxUnavailable.String(), nil
+
+	case pdb.Spec.MinAvailable != nil:
+		minAvailable, err := intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MinAvailable, replicas, true)
+		if err != nil {
+			return 0, "", err
+		}
+		return replicas - minAvailable, "minAvailable " + pdb.Spec.MinAvailable.String(), nil
+
+	default:
+		// Budget without either field does not restrict disruptions
+		return replicas, "", nil
+	}
+}
+
+// changePDBs returns PDBs upserted by the change and
+// names (namespace/name) of PDBs deleted by the change
+func (c *RolloutPDBCompatible) changePDBs(changeGraph *ctldgraph.ChangeGraph) ([]policyv1.PodDisruptionBudget, map[string]struct{}, error) {
+	var upserted []policyv1.PodDisruptionBudget
+	deleted := map[string]struct{}{}
+
+	for _, change := range changeGraph.All() {
+		res := change.Change.Resource()
+		if res.GroupKind() != podDisruptionBudgetGK {
+			continue
+		}
+
+		switch change.Change.Op() {
+		case ctldgraph.ActualChangeOpUpsert:
+			var pdb policyv1.PodDisruptionBudget
+
+			err := res.AsUncheckedTypedObj(&pdb)
+			if err != nil {
+				return nil, nil, fmt.Errorf("Resource %s: %w", res.Description(), err)
+			}
+			upserted = append(upserted, pdb)
+
+		case ctldgraph.ActualChangeOpDelete:
+			deleted[res.Namespace()+"/"+res.Name()] = struct{}{}
+		}
+	}
+
+	return upserted, deleted, nil
+}
+
+// namespacePDBs returns PDBs of the namespace as they will be
+// once the change is applied (cluster PDBs replaced or deleted
+// by the change are excluded)
+func (c *RolloutPDBCompatible) namespacePDBs(ctx context.Context, namespace string,
+	changePDBs []policyv1.PodDisruptionBudget, deletedPDBs map[string]struct{}) ([]policyv1.PodDisruptionBudget, error) {
+
+	var result []policyv1.PodDisruptionBudget
+	inChange := map[string]struct{}{}
+
+	for _, pdb := range changePDBs {
+		if pdb.Namespace == namespace {
+			result = append(result, pdb)
+			inChange[pdb.Name] = struct{}{}
+		}
+	}
+
+	if c.config.CheckCluster {
+		coreClient, err := c.depsFactory.CoreClient()
+		if err != nil {
+			return nil, err
+		}
+
+		list, err := coreClient.PolicyV1().PodDisruptionBudgets(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("Listing PodDisruptionBudgets in namespace %q: %w", namespace, err)
+		}
+
+		for _, pdb := range list.Items {
+			if _, found := inChange[pdb.Name]; found {
+				continue
+			}
+			if _, found := deletedPDBs[namespace+"/"+pdb.Name]; found {
+				continue
+			}
+			result = append(result, pdb)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	return result, nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestRolloutPDBCompatible(t *testing.T) {
+	web := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: default
+spec:
+  replicas: 4
+  template:
+    metadata:
+      labels:
+        app: web
+`))
+	api := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+  namespace: default
+spec:
+  replicas: 4
+  strategy:
+    rollingUpdate:
+      maxUnavailable: 50%
+  template:
+    metadata:
+      labels:
+        app: api
+`))
+	batch := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: batch
+  namespace: default
+spec:
+  replicas: 2
+  strategy:
+    type: Recreate
+  template:
+    metadata:
+      labels:
+        app: batch
+`))
+	// Pods of OnDelete StatefulSets are not replaced by rollouts
+	db := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+  namespace: default
+spec:
+  replicas: 3
+  updateStrategy:
+    type: OnDelete
+  template:
+    metadata:
+      labels:
+        app: db
+`))
+	webPDB := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: web
+  namespace: default
+spec:
+  minAvailable: 3
+  selector:
+    matchLabels:
+      app: web
+`))
+	batchPDB := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: batch
+  namespace: default
+spec:
+  minAvailable: 1
+  selector:
+    matchLabels:
+      app: batch
+`))
+	dbPDB := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: db
+  namespace: default
+spec:
+  maxUnavailable: 0
+  selector:
+    matchLabels:
+      app: db
+`))
+	deletedPDB := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: deleted
+  namespace: default
+`))
+
+	intOrStr := func(val intstr.IntOrString) *intstr.IntOrString { return &val }
+	newPDB := func(name string, app string, spec policyv1.PodDisruptionBudgetSpec) policyv1.PodDisruptionBudget {
+		spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}}
+		return policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}, Spec: spec}
+	}
+
+	clusterPDBs := []policyv1.PodDisruptionBudget{
+		newPDB("web-strict", "web", policyv1.PodDisruptionBudgetSpec{MaxUnavailable: intOrStr(intstr.FromInt(0))}),
+		newPDB("api", "api", policyv1.PodDisruptionBudgetSpec{MinAvailable: intOrStr(intstr.FromString("75%"))}),
+		newPDB("deleted", "web", policyv1.PodDisruptionBudgetSpec{MinAvailable: intOrStr(intstr.FromString("100%"))}),
+		// Replaced by PDB in the change
+		newPDB("web", "web", policyv1.PodDisruptionBudgetSpec{MinAvailable: intOrStr(intstr.FromInt(4))}),
+	}
+
+	changes := []preflighttest.Change{
+		{Res: web, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+		{Res: api, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+		{Res: batch, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+		{Res: db, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+		{Res: webPDB, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+		{Res: batchPDB, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+		{Res: dbPDB, ChangeOp: ctldgraph.ActualChangeOpUpsert},
+		{Res: deletedPDB, ChangeOp: ctldgraph.ActualChangeOpDelete},
+	}
+
+	batchWarning := `deployment/batch (apps/v1) namespace: default: rollout takes down up to 2 pod(s) (strategy Recreate) ` +
+		`while PodDisruptionBudget "batch" (minAvailable 1) allows 1 disruption(s), ` +
+		`budget is violated during rollouts and evictions are blocked until they complete`
+
+	t.Run("change and cluster PDBs", func(t *testing.T) {
+		depsFactory := fakeDepsFactory{coreClient: &fakeCoreClient{pdbs: clusterPDBs}}
+
+		findings := preflighttest.RunCheckOnChanges(t, checks.NewRolloutPDBCompatible(depsFactory, true), changes...)
+		require.Equal(t, []string{
+			`deployment/web (apps/v1) namespace: default: PodDisruptionBudget "web-strict" (maxUnavailable 0) ` +
+				`allows no disruptions of 4 replica(s), evictions (e.g. by node drains) are blocked`,
+			`deployment/api (apps/v1) namespace: default: rollout takes down up to 2 pod(s) (maxUnavailable 50%) ` +
+				`while PodDisruptionBudget "api" (minAvailable 75%) allows 1 disruption(s), ` +
+				`budget is violated during rollouts and evictions are blocked until they complete`,
+			batchWarning,
+		}, preflighttest.Messages(findings))
+	})
+
+	t.Run("cluster PDBs are not checked if disabled", func(t *testing.T) {
+		check := checks.NewRolloutPDBCompatible(fakeDepsFactory{}, true).(preflight.ConfigurableCheck)
+		require.NoError(t, check.SetConfig(preflight.CheckConfig{"checkCluster": false}))
+
+		findings := preflighttest.RunCheckOnChanges(t, check, changes...)
+		require.Equal(t, []string{batchWarning}, preflighttest.Messages(findings))
+	})
+}
@@ -0,0 +1,140 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+
+	cmdcore "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/core"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	SelfAntiAffinityName = "SelfAntiAffinity"
+)
+
+// SelfAntiAffinity is an implementation of preflight.Check
+// that warns about workloads with a required pod anti-affinity
+// matching their own pods when there are fewer topology domains
+// (e.g. nodes for kubernetes.io/hostname) than replicas.
+// Replicas beyond the number of domains cannot be scheduled.
+type SelfAntiAffinity struct {
+	depsFactory cmdcore.DepsFactory
+	enabled     bool
+}
+
+var _ preflight.DescribedCheck = &SelfAntiAffinity{}
+
+func NewSelfAntiAffinity(depsFactory cmdcore.DepsFactory, enabled bool) preflight.Check {
+	return &SelfAntiAffinity{depsFactory: depsFactory, enabled: enabled}
+}
+
+func (c *SelfAntiAffinity) Description() string {
+	return "Warns about workloads whose required pod anti-affinity prevents scheduling all replicas"
+}
+
+func (c *SelfAntiAffinity) Enabled() bool {
+	return c.enabled
+}
+
+func (c *SelfAntiAffinity) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *SelfAntiAffinity) Run(ctx context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	workloads, err := upsertedWorkloads(changeGraph)
+	if err != nil {
+		return err
+	}
+
+	var nodes []corev1.Node
+	var findings []error
+
+	for _, wl := range workloads {
+		if wl.Replicas == nil || wl.ReplicaCount() < 2 {
+			continue
+		}
+
+		for _, term := range c.selfAntiAffinityTerms(wl) {
+			// Only list nodes once a relevant workload is found
+			if nodes == nil {
+				nodes, err = listNodes(ctx, c.depsFactory)
+				if err != nil {
+					return err
+				}
+			}
+
+			numDomains := c.numTopologyDomains(nodes, wl.Template.Spec.NodeSelector, term.TopologyKey)
+			if int(wl.ReplicaCount()) > numDomains {
+				findings = append(findings, preflight.NewWarning(wl.Resource,
+					"required pod anti-affinity on topology key %q matches its own pods: %d replicas but only %d eligible topology domain(s), %d replica(s) will not be scheduled",
+					term.TopologyKey, wl.ReplicaCount(), numDomains, int(wl.ReplicaCount())-numDomains))
+			}
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+// selfAntiAffinityTerms returns required pod anti-affinity
+// terms that select pods of the workload itself
+func (c *SelfAntiAffinity) selfAntiAffinityTerms(wl workload) []corev1.PodAffinityTerm {
+	affinity := wl.Template.Spec.Affinity
+	if affinity == nil || affinity.PodAntiAffinity == nil {
+		return nil
+	}
+
+	podLabels := labels.Set(wl.Template.Labels)
+
+	var result []corev1.PodAffinityTerm
+
+	for _, term := range affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+		if term.LabelSelector == nil || !c.includesOwnNamespace(term, wl.Resource.Namespace()) {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(term.LabelSelector)
+		if err != nil {
+			// Invalid selectors are rejected by the API server
+			continue
+		}
+		if selector.Matches(podLabels) {
+			result = append(result, term)
+		}
+	}
+
+	return result
+}
+
+func (c *SelfAntiAffinity) includesOwnNamespace(term corev1.PodAffinityTerm, namespace string) bool {
+	if len(term.Namespaces) == 0 && term.NamespaceSelector == nil {
+		return true
+	}
+	for _, ns := range term.Namespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	// Empty namespace selector selects all namespaces
+	return term.NamespaceSelector != nil &&
+		len(term.NamespaceSelector.MatchLabels) == 0 && len(term.NamespaceSelector.MatchExpressions) == 0
+}
+
+// numTopologyDomains counts distinct values of the topology key
+// across schedulable nodes matching the node selector
+func (c *SelfAntiAffinity) numTopologyDomains(nodes []corev1.Node, nodeSelector map[string]string, topologyKey string) int {
+	domains := map[string]struct{}{}
+
+	for _, node := range schedulableNodes(nodes, nodeSelector) {
+		if val, found := node.Labels[topologyKey]; found {
+			domains[val] = struct{}{}
+		}
+	}
+
+	return len(domains)
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSelfAntiAffinity(t *testing.T) {
+	nodes := []corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"kubernetes.io/hostname": "node1", "pool": "a"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: map[string]string{"kubernetes.io/hostname": "node2", "pool": "a"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node3", Labels: map[string]string{"kubernetes.io/hostname": "node3", "pool": "b"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node4", Labels: map[string]string{"kubernetes.io/hostname": "node4", "pool": "a"}},
+			Spec: corev1.NodeSpec{Unschedulable: true}},
+	}
+
+	deploymentYAML := func(replicas, nodeSelector, selectorLabel string) string {
+		return `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: default
+spec:
+  replicas: ` + replicas + `
+  template:
+    metadata:
+      labels:
+        app: app
+    spec:
+      nodeSelector: ` + nodeSelector + `
+      affinity:
+        podAntiAffinity:
+          requiredDuringSchedulingIgnoredDuringExecution:
+          - topologyKey: kubernetes.io/hostname
+            labelSelector:
+              matchLabels:
+                app: ` + selectorLabel + `
+`
+	}
+
+	testCases := []struct {
+		name            string
+		resYAML         string
+		expectedWarning string
+	}{
+		{
+			name:    "replicas fit into schedulable nodes",
+			resYAML: deploymentYAML("3", "{}", "app"),
+		},
+		{
+			name:    "anti-affinity selecting other pods is ignored",
+			resYAML: deploymentYAML("5", "{}", "other"),
+		},
+		{
+			name:    "more replicas than schedulable nodes",
+			resYAML: deploymentYAML("4", "{}", "app"),
+			expectedWarning: `deployment/app (apps/v1) namespace: default: required pod anti-affinity on topology key "kubernetes.io/hostname" ` +
+				`matches its own pods: 4 replicas but only 3 eligible topology domain(s), 1 replica(s) will not be scheduled`,
+		},
+		{
+			name:    "more replicas than nodes matching node selector",
+			resYAML: deploymentYAML("3", "{pool: a}", "app"),
+			expectedWarning: `deployment/app (apps/v1) namespace: default: required pod anti-affinity on topology key "kubernetes.io/hostname" ` +
+				`matches its own pods: 3 replicas but only 2 eligible topology domain(s), 1 replica(s) will not be scheduled`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := ctlres.MustNewResourceFromBytes([]byte(tc.resYAML))
+			depsFactory := fakeDepsFactory{coreClient: &fakeCoreClient{nodes: nodes}}
+
+			findings := preflighttest.RunCheckOnResources(t, checks.NewSelfAntiAffinity(depsFactory, true), []ctlres.Resource{res})
+			if len(tc.expectedWarning) == 0 {
+				require.Empty(t, findings)
+				return
+			}
+			require.Equal(t, []string{tc.expectedWarning}, preflighttest.Messages(findings))
+		})
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	ServiceAccountTokenAutomountName = "ServiceAccountTokenAutomount"
+
+	defaultServiceAccountName = "default"
+)
+
+var (
+	serviceAccountGK = schema.GroupKind{Group: "", Kind: "ServiceAccount"}
+)
+
+// ServiceAccountTokenAutomountConfig is the configuration accepted
+// by the ServiceAccountTokenAutomount preflight check
+type ServiceAccountTokenAutomountConfig struct {
+	// ExemptionAnnotation marks workloads (or their pod templates)
+	// that need to access the Kubernetes API
+	ExemptionAnnotation string `json:"exemptionAnnotation" description:"Annotation marking workloads that need to access the Kubernetes API"`
+	// Namespaces limits the check to workloads in specified
+	// namespaces. All namespaces are checked if empty.
+	Namespaces []string `json:"namespaces" description:"Namespaces of workloads to check (all namespaces if empty)"`
+}
+
+// ServiceAccountTokenAutomount is an implementation of preflight.Check
+// that warns about workloads whose pods get a service account token
+// mounted, either explicitly or by default, unless they are annotated
+// as needing it. Workloads that do not access the Kubernetes API
+// should not have a token that could be misused once compromised.
+// Service accounts are only inspected if they are part of the change.
+type ServiceAccountTokenAutomount struct {
+	enabled bool
+	config  ServiceAccountTokenAutomountConfig
+}
+
+var _ preflight.ConfigSchemaCheck = &ServiceAccountTokenAutomount{}
+var _ preflight.DescribedCheck = &ServiceAccountTokenAutomount{}
+
+func NewServiceAccountTokenAutomount(enabled bool) preflight.Check {
+	return &ServiceAccountTokenAutomount{
+		enabled: enabled,
+		config: ServiceAccountTokenAutomountConfig{
+			ExemptionAnnotation: "preflight.kapp.k14s.io/service-account-token-required",
+		},
+	}
+}
+
+func (c *ServiceAccountTokenAutomount) Description() string {
+	return "Warns about workloads that automount a service account token without being marked as needing it"
+}
+
+func (c *ServiceAccountTokenAutomount) Enabled() bool {
+	return c.enabled
+}
+
+func (c *ServiceAccountTokenAutomount) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *ServiceAccountTokenAutomount) SetConfig(config preflight.CheckConfig) error {
+	newConfig := c.config
+
+	err := config.Decode(&newConfig)
+	if err != nil {
+		return err
+	}
+	if len(newConfig.ExemptionAnnotation) == 0 {
+		return fmt.Errorf("expected exemptionAnnotation to be non-empty")
+	}
+
+	c.config = newConfig
+	return nil
+}
+
+func (c *ServiceAccountTokenAutomount) Config() preflight.CheckConfig {
+	return preflight.NewCheckConfig(c.config)
+}
+
+func (c *ServiceAccountTokenAutomount) ConfigSchema() []preflight.ConfigField {
+	return preflight.NewConfigSchema(ServiceAccountTokenAutomountConfig{})
+}
+
+func (c *ServiceAccountTokenAutomount) Run(_ context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	workloads, err := upsertedWorkloads(changeGraph)
+	if err != nil {
+		return err
+	}
+
+	serviceAccounts := map[string]corev1.ServiceAccount{}
+
+	for _, change := range changeGraph.All() {
+		res := change.Change.Resource()
+
+		if change.Change.Op() != ctldgraph.ActualChangeOpUpsert || res.GroupKind() != serviceAccountGK {
+			continue
+		}
+
+		var sa corev1.ServiceAccount
+
+		err := res.AsUncheckedTypedObj(&sa)
+		if err != nil {
+			return fmt.Errorf("Resource %s: %w", res.Description(), err)
+		}
+
+		serviceAccounts[res.Namespace()+"/"+res.Name()] = sa
+	}
+
+	var findings []error
+
+	for _, wl := range workloads {
+		if c.exempt(wl) {
+			continue
+		}
+
+		podSpec := wl.Template.Spec
+
+		saName := podSpec.ServiceAccountName
+		if len(saName) == 0 {
+			saName = defaultServiceAccountName
+		}
+
+		switch {
+		case podSpec.AutomountServiceAccountToken != nil:
+			if *podSpec.AutomountServiceAccountToken {
+				findings = append(findings, preflight.NewWarning(wl.Resource,
+					"pod template sets automountServiceAccountToken to true%s", c.hint()))
+			}
+		default:
+			sa, found := serviceAccounts[wl.Resource.Namespace()+"/"+saName]
+			switch {
+			case !found:
+				findings = append(findings, preflight.NewWarning(wl.Resource,
+					"service account %q token is automounted by default (set automountServiceAccountToken to false on the pod template)%s",
+					saName, c.hint()))
+			case sa.AutomountServiceAccountToken == nil || *sa.AutomountServiceAccountToken:
+				findings = append(findings, preflight.NewWarning(wl.Resource,
+					"service account %q token is automounted (set automountServiceAccountToken to false on the pod template or service account)%s",
+					saName, c.hint()))
+			}
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+func (c *ServiceAccountTokenAutomount) exempt(wl workload) bool {
+	if len(c.config.Namespaces) > 0 && !containsString(c.config.Namespaces, wl.Resource.Namespace()) {
+		return true
+	}
+	if _, found := wl.Resource.Annotations()[c.config.ExemptionAnnotation]; found {
+		return true
+	}
+	_, found := wl.Template.Annotations[c.config.ExemptionAnnotation]
+	return found
+}
+
+func (c *ServiceAccountTokenAutomount) hint() string {
+	return fmt.Sprintf(", annotate with %q if workload needs to access the Kubernetes API", c.config.ExemptionAnnotation)
+}
@@ -0,0 +1,178 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+func TestServiceAccountTokenAutomount(t *testing.T) {
+	resources := []ctlres.Resource{
+		ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: default-sa
+  namespace: default
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app
+`)),
+		ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: explicit
+  namespace: default
+spec:
+  template:
+    spec:
+      serviceAccountName: no-automount
+      automountServiceAccountToken: true
+      containers:
+      - name: app
+        image: app
+`)),
+		ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: disabled
+  namespace: default
+spec:
+  template:
+    spec:
+      automountServiceAccountToken: false
+      containers:
+      - name: app
+        image: app
+`)),
+		ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: sa-disabled
+  namespace: default
+spec:
+  template:
+    spec:
+      serviceAccountName: no-automount
+      containers:
+      - name: app
+        image: app
+`)),
+		ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: sa-enabled
+  namespace: default
+spec:
+  template:
+    spec:
+      serviceAccountName: automount
+      containers:
+      - name: app
+        image: app
+`)),
+		ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: exempt
+  namespace: default
+spec:
+  template:
+    metadata:
+      annotations:
+        preflight.kapp.k14s.io/service-account-token-required: ""
+    spec:
+      containers:
+      - name: app
+        image: app
+`)),
+		ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: controller
+  namespace: system
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app
+`)),
+		ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: no-automount
+  namespace: default
+automountServiceAccountToken: false
+`)),
+		ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: automount
+  namespace: default
+`)),
+	}
+
+	hint := `, annotate with "preflight.kapp.k14s.io/service-account-token-required" if workload needs to access the Kubernetes API`
+
+	testCases := []struct {
+		name             string
+		config           preflight.CheckConfig
+		expectedWarnings []string
+	}{
+		{
+			name: "default config",
+			expectedWarnings: []string{
+				`deployment/default-sa (apps/v1) namespace: default: service account "default" token is automounted by default ` +
+					`(set automountServiceAccountToken to false on the pod template)` + hint,
+				`deployment/explicit (apps/v1) namespace: default: pod template sets automountServiceAccountToken to true` + hint,
+				`deployment/sa-enabled (apps/v1) namespace: default: service account "automount" token is automounted ` +
+					`(set automountServiceAccountToken to false on the pod template or service account)` + hint,
+				`deployment/controller (apps/v1) namespace: system: service account "default" token is automounted by default ` +
+					`(set automountServiceAccountToken to false on the pod template)` + hint,
+			},
+		},
+		{
+			name:   "limited to namespaces",
+			config: preflight.CheckConfig{"namespaces": []interface{}{"system"}},
+			expectedWarnings: []string{
+				`deployment/controller (apps/v1) namespace: system: service account "default" token is automounted by default ` +
+					`(set automountServiceAccountToken to false on the pod template)` + hint,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			check := checks.NewServiceAccountTokenAutomount(true).(preflight.ConfigurableCheck)
+			require.NoError(t, check.SetConfig(tc.config))
+
+			findings := preflighttest.RunCheckOnResources(t, check, resources)
+			require.Equal(t, tc.expectedWarnings, preflighttest.Messages(findings))
+		})
+	}
+
+	t.Run("empty exemption annotation, error returned", func(t *testing.T) {
+		check := checks.NewServiceAccountTokenAutomount(true).(preflight.ConfigurableCheck)
+		require.EqualError(t, check.SetConfig(preflight.CheckConfig{"exemptionAnnotation": ""}),
+			"expected exemptionAnnotation to be non-empty")
+	})
+}
@@ -0,0 +1,244 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	cmdcore "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/cmd/core"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	ServiceConflictsName = "ServiceConflicts"
+)
+
+// ServiceConflictsConfig is the configuration accepted
+// by the ServiceConflicts preflight check
+type ServiceConflictsConfig struct {
+	// CheckCluster enables checking nodePorts against
+	// Services that already exist in the cluster
+	CheckCluster bool `json:"checkCluster" description:"Check nodePorts against Services that already exist in the cluster"`
+}
+
+// ServiceConflicts is an implementation of preflight.Check
+// that warns about Services with ports that would be rejected
+// by the API server (duplicate ports, port names or nodePorts
+// within a Service) and about nodePorts claimed by more than
+// one Service (nodePorts are allocated cluster wide).
+type ServiceConflicts struct {
+	depsFactory cmdcore.DepsFactory
+	enabled     bool
+	config      ServiceConflictsConfig
+}
+
+var _ preflight.ConfigSchemaCheck = &ServiceConflicts{}
+var _ preflight.DescribedCheck = &ServiceConflicts{}
+
+func NewServiceConflicts(depsFactory cmdcore.DepsFactory, enabled bool) preflight.Check {
+	return &ServiceConflicts{
+		depsFactory: depsFactory,
+		enabled:     enabled,
+		config:      ServiceConflictsConfig{CheckCluster: true},
+	}
+}
+
+func (c *ServiceConflicts) Description() string {
+	return "Warns about duplicate Service ports and conflicting nodePorts"
+}
+
+func (c *ServiceConflicts) Enabled() bool {
+	return c.enabled
+}
+
+func (c *ServiceConflicts) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *ServiceConflicts) SetConfig(config preflight.CheckConfig) error {
+	newConfig := c.config
+
+	err := config.Decode(&newConfig)
+	if err != nil {
+		return err
+	}
+
+	c.config = newConfig
+	return nil
+}
+
+func (c *ServiceConflicts) Config() preflight.CheckConfig {
+	return preflight.NewCheckConfig(c.config)
+}
+
+func (c *ServiceConflicts) ConfigSchema() []preflight.ConfigField {
+	return preflight.NewConfigSchema(ServiceConflictsConfig{})
+}
+
+func (c *ServiceConflicts) Run(ctx context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	services, err := upsertedServices(changeGraph)
+	if err != nil {
+		return err
+	}
+
+	var findings []error
+
+	servicesByName := map[types.NamespacedName]service{}
+	// Value is the Service that claims the nodePort. nodePorts are
+	// allocated by number regardless of protocol (only ports of
+	// the same Service may share a nodePort).
+	nodePorts := map[int32]ctlres.Resource{}
+
+	for _, svc := range services {
+		servicesByName[types.NamespacedName{Namespace: svc.Resource.Namespace(), Name: svc.Resource.Name()}] = svc
+
+		findings = append(findings, c.duplicatePorts(svc)...)
+
+		svcNodePorts := map[int32]struct{}{}
+
+		for _, port := range svc.Service.Spec.Ports {
+			if port.NodePort == 0 {
+				continue
+			}
+			// Shared by ports of the Service (or reported as their duplicate)
+			if _, found := svcNodePorts[port.NodePort]; found {
+				continue
+			}
+			svcNodePorts[port.NodePort] = struct{}{}
+
+			if claimedBy, found := nodePorts[port.NodePort]; found {
+				findings = append(findings, preflight.NewWarning(svc.Resource,
+					"nodePort %d conflicts with %s", port.NodePort, claimedBy.Description()))
+				continue
+			}
+			nodePorts[port.NodePort] = svc.Resource
+		}
+	}
+
+	if c.config.CheckCluster && len(nodePorts) > 0 {
+		clusterFindings, err := c.checkClusterNodePorts(ctx, changeGraph, nodePorts, servicesByName)
+		if err != nil {
+			return err
+		}
+		findings = append(findings, clusterFindings...)
+	}
+
+	return errors.Join(findings...)
+}
+
+// checkClusterNodePorts compares nodePorts against Services in the cluster
+// excluding Services that are updated or deleted as part of the change
+func (c *ServiceConflicts) checkClusterNodePorts(ctx context.Context, changeGraph *ctldgraph.ChangeGraph,
+	nodePorts map[int32]ctlres.Resource, servicesByName map[types.NamespacedName]service) ([]error, error) {
+
+	changed := map[types.NamespacedName]struct{}{}
+
+	for name := range servicesByName {
+		changed[name] = struct{}{}
+	}
+	for _, change := range changeGraph.All() {
+		res := change.Change.Resource()
+		if change.Change.Op() == ctldgraph.ActualChangeOpDelete && res.GroupKind() == serviceGK {
+			changed[types.NamespacedName{Namespace: res.Namespace(), Name: res.Name()}] = struct{}{}
+		}
+	}
+
+	client, err := c.depsFactory.CoreClient()
+	if err != nil {
+		return nil, err
+	}
+
+	clusterServices, err := client.CoreV1().Services("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("Listing services: %w", err)
+	}
+
+	var findings []error
+
+	for _, clusterSvc := range clusterServices.Items {
+		name := types.NamespacedName{Namespace: clusterSvc.Namespace, Name: clusterSvc.Name}
+		if _, found := changed[name]; found {
+			continue
+		}
+
+		reported := map[int32]struct{}{}
+
+		for _, port := range clusterSvc.Spec.Ports {
+			if _, found := reported[port.NodePort]; found || port.NodePort == 0 {
+				continue
+			}
+			if claimedBy, found := nodePorts[port.NodePort]; found {
+				findings = append(findings, preflight.NewWarning(claimedBy,
+					"nodePort %d is already allocated to existing service %s", port.NodePort, name))
+				reported[port.NodePort] = struct{}{}
+			}
+		}
+	}
+
+	return findings, nil
+}
+
+// duplicatePorts returns findings for ports of the Service that share
+// a port number, name or nodePort (with the same protocol) with
+// a preceding port of the Service
+func (c *ServiceConflicts) duplicatePorts(svc service) []error {
+	var findings []error
+
+	ports := map[servicePortKey]struct{}{}
+	names := map[string]struct{}{}
+	nodePorts := map[servicePortKey]struct{}{}
+
+	for i, port := range svc.Service.Spec.Ports {
+		key := newServicePortKey(port.Port, port.Protocol)
+		if _, found := ports[key]; found {
+			findings = append(findings, preflight.NewWarning(svc.Resource,
+				"port %d: port %s is specified more than once", i, key))
+		}
+		ports[key] = struct{}{}
+
+		if len(port.Name) > 0 {
+			if _, found := names[port.Name]; found {
+				findings = append(findings, preflight.NewWarning(svc.Resource,
+					"port %d: port name %q is specified more than once", i, port.Name))
+			}
+			names[port.Name] = struct{}{}
+		}
+
+		if port.NodePort != 0 {
+			key := newServicePortKey(port.NodePort, port.Protocol)
+			if _, found := nodePorts[key]; found {
+				findings = append(findings, preflight.NewWarning(svc.Resource,
+					"port %d: nodePort %s is specified more than once", i, key))
+			}
+			nodePorts[key] = struct{}{}
+		}
+	}
+
+	return findings
+}
+
+// servicePortKey identifies a port together
+// with its protocol (TCP if not specified)
+type servicePortKey struct {
+	Port     int32
+	Protocol corev1.Protocol
+}
+
+func newServicePortKey(port int32, protocol corev1.Protocol) servicePortKey {
+	if len(protocol) == 0 {
+		protocol = corev1.ProtocolTCP
+	}
+	return servicePortKey{Port: port, Protocol: protocol}
+}
+
+func (k servicePortKey) String() string {
+	return fmt.Sprintf("%d/%s", k.Port, k.Protocol)
+}
@@ -0,0 +1,167 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestServiceConflicts(t *testing.T) {
+	serviceYAML := func(name, namespace string, nodePort int) string {
+		return fmt.Sprintf(`
+apiVersion: v1
+kind: Service
+metadata:
+  name: %s
+  namespace: %s
+spec:
+  type: NodePort
+  ports:
+  - port: 80
+    nodePort: %d
+`, name, namespace, nodePort)
+	}
+
+	clusterServices := []corev1.Service{{
+		ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "other"},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80, NodePort: 30100}}},
+	}, {
+		ObjectMeta: metav1.ObjectMeta{Name: "updated", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80, NodePort: 30200}}},
+	}}
+
+	testCases := []struct {
+		name             string
+		changes          []preflighttest.Change
+		config           preflight.CheckConfig
+		expectedWarnings []string
+	}{
+		{
+			name: "services with distinct names and nodePorts, no warnings",
+			changes: []preflighttest.Change{
+				{Res: ctlres.MustNewResourceFromBytes([]byte(serviceYAML("app1", "default", 30001))), ChangeOp: ctldgraph.ActualChangeOpUpsert},
+				{Res: ctlres.MustNewResourceFromBytes([]byte(serviceYAML("app2", "default", 30002))), ChangeOp: ctldgraph.ActualChangeOpUpsert},
+			},
+		},
+		{
+			name: "nodePorts claimed by multiple services within the change",
+			changes: []preflighttest.Change{
+				{Res: ctlres.MustNewResourceFromBytes([]byte(serviceYAML("app1", "default", 30001))), ChangeOp: ctldgraph.ActualChangeOpUpsert},
+				{Res: ctlres.MustNewResourceFromBytes([]byte(serviceYAML("app1", "other", 30001))), ChangeOp: ctldgraph.ActualChangeOpUpsert},
+			},
+			expectedWarnings: []string{
+				"service/app1 (v1) namespace: other: nodePort 30001 conflicts with service/app1 (v1) namespace: default",
+			},
+		},
+		{
+			name: "nodePorts claimed by services with different protocols",
+			changes: []preflighttest.Change{
+				{Res: ctlres.MustNewResourceFromBytes([]byte(serviceYAML("app1", "default", 30001))), ChangeOp: ctldgraph.ActualChangeOpUpsert},
+				{Res: ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: Service
+metadata:
+  name: dns
+  namespace: default
+spec:
+  type: NodePort
+  ports:
+  - name: dns-udp
+    port: 53
+    protocol: UDP
+    nodePort: 30001
+  - name: dns-tcp
+    port: 53
+    protocol: TCP
+    nodePort: 30001
+  - name: other-udp
+    port: 54
+    protocol: UDP
+    nodePort: 30100
+`)), ChangeOp: ctldgraph.ActualChangeOpUpsert},
+			},
+			expectedWarnings: []string{
+				"service/dns (v1) namespace: default: nodePort 30001 conflicts with service/app1 (v1) namespace: default",
+				"service/dns (v1) namespace: default: nodePort 30100 is already allocated to existing service other/existing",
+			},
+		},
+		{
+			name: "duplicate ports within a service",
+			changes: []preflighttest.Change{
+				{Res: ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: Service
+metadata:
+  name: app1
+  namespace: default
+spec:
+  type: NodePort
+  ports:
+  - name: http
+    port: 80
+    nodePort: 30001
+  - name: http
+    port: 80
+    protocol: TCP
+    nodePort: 30001
+  - name: dns
+    port: 80
+    protocol: UDP
+    nodePort: 30001
+`)), ChangeOp: ctldgraph.ActualChangeOpUpsert},
+			},
+			expectedWarnings: []string{
+				"service/app1 (v1) namespace: default: port 1: port 80/TCP is specified more than once",
+				"service/app1 (v1) namespace: default: port 1: port name \"http\" is specified more than once",
+				"service/app1 (v1) namespace: default: port 1: nodePort 30001/TCP is specified more than once",
+			},
+		},
+		{
+			name: "nodePort allocated to existing service in the cluster",
+			changes: []preflighttest.Change{
+				{Res: ctlres.MustNewResourceFromBytes([]byte(serviceYAML("app1", "default", 30100))), ChangeOp: ctldgraph.ActualChangeOpUpsert},
+			},
+			expectedWarnings: []string{
+				"service/app1 (v1) namespace: default: nodePort 30100 is already allocated to existing service other/existing",
+			},
+		},
+		{
+			name: "nodePort of services updated or deleted in the change are not considered allocated",
+			changes: []preflighttest.Change{
+				{Res: ctlres.MustNewResourceFromBytes([]byte(serviceYAML("updated", "default", 30200))), ChangeOp: ctldgraph.ActualChangeOpUpsert},
+				{Res: ctlres.MustNewResourceFromBytes([]byte(serviceYAML("existing", "other", 30101))), ChangeOp: ctldgraph.ActualChangeOpDelete},
+				{Res: ctlres.MustNewResourceFromBytes([]byte(serviceYAML("app1", "default", 30100))), ChangeOp: ctldgraph.ActualChangeOpUpsert},
+			},
+		},
+		{
+			name: "cluster check disabled",
+			changes: []preflighttest.Change{
+				{Res: ctlres.MustNewResourceFromBytes([]byte(serviceYAML("app1", "default", 30100))), ChangeOp: ctldgraph.ActualChangeOpUpsert},
+			},
+			config: preflight.CheckConfig{"checkCluster": false},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			depsFactory := fakeDepsFactory{coreClient: &fakeCoreClient{services: clusterServices}}
+
+			check := checks.NewServiceConflicts(depsFactory, true).(preflight.ConfigurableCheck)
+			require.NoError(t, check.SetConfig(tc.config))
+
+			findings := preflighttest.RunCheckOnChanges(t, check, tc.changes...)
+			require.Equal(t, tc.expectedWarnings, preflighttest.Messages(findings))
+		})
+	}
+}
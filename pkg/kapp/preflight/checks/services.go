@@ -0,0 +1,47 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"fmt"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	serviceGK = schema.GroupKind{Group: "", Kind: "Service"}
+)
+
+type service struct {
+	Resource ctlres.Resource
+	Service  corev1.Service
+}
+
+// upsertedServices returns all Services that are upserted
+// as part of the change graph in graph order
+func upsertedServices(changeGraph *ctldgraph.ChangeGraph) ([]service, error) {
+	var result []service
+
+	for _, change := range changeGraph.All() {
+		res := change.Change.Resource()
+
+		if change.Change.Op() != ctldgraph.ActualChangeOpUpsert || res.GroupKind() != serviceGK {
+			continue
+		}
+
+		var svc corev1.Service
+
+		err := res.AsUncheckedTypedObj(&svc)
+		if err != nil {
+			return nil, fmt.Errorf("Resource %s: %w", res.Description(), err)
+		}
+
+		result = append(result, service{Resource: res, Service: svc})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,152 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	StartupProbeAdequateName = "StartupProbeAdequate"
+)
+
+// StartupProbeAdequateConfig is the configuration accepted
+// by the StartupProbeAdequate preflight check
+type StartupProbeAdequateConfig struct {
+	// SlowStartingImages are glob patterns (e.g. *keycloak*) of image
+	// names (last path component of the repository, without registry
+	// or tag) that are expected to start slowly
+	SlowStartingImages []string `json:"slowStartingImages" description:"Glob patterns of image names (e.g. *keycloak*) expected to start slowly"`
+	// MinStartupSeconds is the minimum time slow starting
+	// containers are given to start before liveness probe
+	// failures result in restarts
+	MinStartupSeconds int32 `json:"minStartupSeconds" description:"Minimum time slow starting containers are given to start before liveness probe failures restart them"`
+}
+
+// StartupProbeAdequate is an implementation of preflight.Check
+// that warns about containers with a liveness probe running slow
+// starting images (per configuration) that are not given enough time
+// to start, either because they have no startupProbe or because their
+// startupProbe is too short. Such containers are killed by the liveness
+// probe before they become ready and end up in a restart loop.
+type StartupProbeAdequate struct {
+	enabled bool
+	config  StartupProbeAdequateConfig
+}
+
+var _ preflight.ConfigSchemaCheck = &StartupProbeAdequate{}
+var _ preflight.DescribedCheck = &StartupProbeAdequate{}
+
+func NewStartupProbeAdequate(enabled bool) preflight.Check {
+	return &StartupProbeAdequate{
+		enabled: enabled,
+		config: StartupProbeAdequateConfig{
+			SlowStartingImages: []string{"elasticsearch", "opensearch", "keycloak", "jenkins", "sonarqube", "nexus3"},
+			MinStartupSeconds:  120,
+		},
+	}
+}
+
+func (c *StartupProbeAdequate) Description() string {
+	return "Warns about slow starting containers that may be killed by their liveness probe before they start"
+}
+
+func (c *StartupProbeAdequate) Enabled() bool {
+	return c.enabled
+}
+
+func (c *StartupProbeAdequate) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *StartupProbeAdequate) SetConfig(config preflight.CheckConfig) error {
+	newConfig := c.config
+
+	err := config.Decode(&newConfig)
+	if err != nil {
+		return err
+	}
+	if newConfig.MinStartupSeconds < 0 {
+		return fmt.Errorf("expected minStartupSeconds to be non-negative")
+	}
+	for _, pattern := range newConfig.SlowStartingImages {
+		if _, err := path.Match(pattern, ""); err != nil || len(pattern) == 0 {
+			return fmt.Errorf("expected slowStartingImages to be valid non-empty glob patterns, got %q", pattern)
+		}
+	}
+
+	c.config = newConfig
+	return nil
+}
+
+func (c *StartupProbeAdequate) Config() preflight.CheckConfig {
+	return preflight.NewCheckConfig(c.config)
+}
+
+func (c *StartupProbeAdequate) ConfigSchema() []preflight.ConfigField {
+	return preflight.NewConfigSchema(StartupProbeAdequateConfig{})
+}
+
+func (c *StartupProbeAdequate) Run(_ context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	workloads, err := upsertedWorkloads(changeGraph)
+	if err != nil {
+		return err
+	}
+
+	var findings []error
+
+	for _, wl := range workloads {
+		for _, container := range wl.Template.Spec.Containers {
+			if container.LivenessProbe == nil || !c.isSlowStarting(container.Image) {
+				continue
+			}
+
+			if container.StartupProbe == nil {
+				allowed := probeAllowedSeconds(container.LivenessProbe)
+				if allowed < c.config.MinStartupSeconds {
+					findings = append(findings, preflight.NewWarning(wl.Resource,
+						"container %q runs slow starting image %q with a liveness probe but no startupProbe: "+
+							"it is restarted if not live within %ds, expected at least %ds",
+						container.Name, container.Image, allowed, c.config.MinStartupSeconds))
+				}
+				continue
+			}
+
+			allowed := probeAllowedSeconds(container.StartupProbe)
+			if allowed < c.config.MinStartupSeconds {
+				findings = append(findings, preflight.NewWarning(wl.Resource,
+					"container %q runs slow starting image %q with a startupProbe allowing %ds to start, expected at least %ds",
+					container.Name, container.Image, allowed, c.config.MinStartupSeconds))
+			}
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+func (c *StartupProbeAdequate) isSlowStarting(image string) bool {
+	repo := normalizeImageRepository(image)
+	name := repo[strings.LastIndex(repo, "/")+1:]
+
+	for _, pattern := range c.config.SlowStartingImages {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// probeAllowedSeconds returns the time (in seconds) after which
+// a probe that never succeeds reaches its failure threshold
+func probeAllowedSeconds(probe *corev1.Probe) int32 {
+	return probe.InitialDelaySeconds + probeDefault(probe.PeriodSeconds, 10)*probeDefault(probe.FailureThreshold, 3)
+}
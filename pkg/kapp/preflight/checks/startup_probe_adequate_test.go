@@ -0,0 +1,106 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+func TestStartupProbeAdequate(t *testing.T) {
+	deployment := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: search
+  namespace: default
+spec:
+  template:
+    spec:
+      containers:
+      - name: no-startup-probe
+        image: docker.elastic.co/elasticsearch/elasticsearch:8.13.0
+        livenessProbe:
+          httpGet:
+            port: 9200
+      - name: short-startup-probe
+        image: quay.io/keycloak/keycloak:24.0
+        livenessProbe:
+          httpGet:
+            port: 8080
+        startupProbe:
+          httpGet:
+            port: 8080
+          periodSeconds: 5
+          failureThreshold: 6
+      - name: adequate-startup-probe
+        image: quay.io/keycloak/keycloak:24.0
+        livenessProbe:
+          httpGet:
+            port: 8080
+        startupProbe:
+          httpGet:
+            port: 8080
+          failureThreshold: 30
+      - name: long-liveness-delay
+        image: jenkins/jenkins:lts
+        livenessProbe:
+          httpGet:
+            port: 8080
+          initialDelaySeconds: 300
+      - name: no-liveness-probe
+        image: sonarqube
+      - name: fast-starting
+        image: nginx
+        livenessProbe:
+          httpGet:
+            port: 80
+`))
+
+	testCases := []struct {
+		name             string
+		config           preflight.CheckConfig
+		expectedWarnings []string
+	}{
+		{
+			name: "default config",
+			expectedWarnings: []string{
+				`deployment/search (apps/v1) namespace: default: container "no-startup-probe" runs slow starting image ` +
+					`"docker.elastic.co/elasticsearch/elasticsearch:8.13.0" with a liveness probe but no startupProbe: ` +
+					`it is restarted if not live within 30s, expected at least 120s`,
+				`deployment/search (apps/v1) namespace: default: container "short-startup-probe" runs slow starting image ` +
+					`"quay.io/keycloak/keycloak:24.0" with a startupProbe allowing 30s to start, expected at least 120s`,
+			},
+		},
+		{
+			name:   "custom slow starting images and minimum",
+			config: preflight.CheckConfig{"slowStartingImages": []interface{}{"ngin*"}, "minStartupSeconds": 60},
+			expectedWarnings: []string{
+				`deployment/search (apps/v1) namespace: default: container "fast-starting" runs slow starting image ` +
+					`"nginx" with a liveness probe but no startupProbe: it is restarted if not live within 30s, expected at least 60s`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			check := checks.NewStartupProbeAdequate(true).(preflight.ConfigurableCheck)
+			require.NoError(t, check.SetConfig(tc.config))
+
+			findings := preflighttest.RunCheckOnResources(t, check, []ctlres.Resource{deployment})
+			require.Equal(t, tc.expectedWarnings, preflighttest.Messages(findings))
+		})
+	}
+
+	t.Run("invalid pattern, error returned", func(t *testing.T) {
+		check := checks.NewStartupProbeAdequate(true).(preflight.ConfigurableCheck)
+		require.EqualError(t, check.SetConfig(preflight.CheckConfig{"slowStartingImages": []interface{}{"["}}),
+			`expected slowStartingImages to be valid non-empty glob patterns, got "["`)
+	})
+}
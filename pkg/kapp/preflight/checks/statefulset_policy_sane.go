@@ -0,0 +1,180 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	StatefulSetPolicySaneName = "StatefulSetPolicySane"
+)
+
+// StatefulSetPolicySaneConfig is the configuration accepted
+// by the StatefulSetPolicySane preflight check
+type StatefulSetPolicySaneConfig struct {
+	// MaxOrderedStartupSeconds is the maximum acceptable estimated time
+	// for all replicas of an OrderedReady StatefulSet to become ready
+	MaxOrderedStartupSeconds int32 `json:"maxOrderedStartupSeconds" description:"Maximum acceptable estimated time for all replicas of an OrderedReady StatefulSet to become ready"`
+	// StartupAllowanceSeconds is the estimated time it takes to
+	// pull images and start containers, in addition to probe delays
+	StartupAllowanceSeconds int32 `json:"startupAllowanceSeconds" description:"Estimated time to pull images and start containers in addition to probe delays"`
+	// AllowOnDelete disables warnings about the OnDelete update strategy
+	AllowOnDelete bool `json:"allowOnDelete" description:"Do not warn about the OnDelete update strategy"`
+}
+
+// StatefulSetPolicySane is an implementation of preflight.Check
+// that warns about questionable combinations of podManagementPolicy,
+// update strategy and pod startup behaviour of StatefulSets:
+// ordered startup that is estimated to take too long, parallel startup
+// of pods that reference their peers, and update strategies that
+// do not roll out template changes.
+type StatefulSetPolicySane struct {
+	enabled bool
+	config  StatefulSetPolicySaneConfig
+}
+
+var _ preflight.ConfigSchemaCheck = &StatefulSetPolicySane{}
+var _ preflight.DescribedCheck = &StatefulSetPolicySane{}
+
+func NewStatefulSetPolicySane(enabled bool) preflight.Check {
+	return &StatefulSetPolicySane{
+		enabled: enabled,
+		config: StatefulSetPolicySaneConfig{
+			MaxOrderedStartupSeconds: 600,
+			StartupAllowanceSeconds:  60,
+		},
+	}
+}
+
+func (c *StatefulSetPolicySane) Description() string {
+	return "Warns about StatefulSets with questionable pod management policy and update strategy combinations"
+}
+
+func (c *StatefulSetPolicySane) Enabled() bool {
+	return c.enabled
+}
+
+func (c *StatefulSetPolicySane) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *StatefulSetPolicySane) SetConfig(config preflight.CheckConfig) error {
+	newConfig := c.config
+
+	err := config.Decode(&newConfig)
+	if err != nil {
+		return err
+	}
+	if newConfig.MaxOrderedStartupSeconds < 0 || newConfig.StartupAllowanceSeconds < 0 {
+		return fmt.Errorf("expected maxOrderedStartupSeconds and startupAllowanceSeconds to be non-negative")
+	}
+
+	c.config = newConfig
+	return nil
+}
+
+func (c *StatefulSetPolicySane) Config() preflight.CheckConfig {
+	return preflight.NewCheckConfig(c.config)
+}
+
+func (c *StatefulSetPolicySane) ConfigSchema() []preflight.ConfigField {
+	return preflight.NewConfigSchema(StatefulSetPolicySaneConfig{})
+}
+
+func (c *StatefulSetPolicySane) Run(_ context.Context, changeGraph *ctldgraph.ChangeGraph) error {
+	var findings []error
+
+	for _, change := range changeGraph.All() {
+		res := change.Change.Resource()
+
+		if change.Change.Op() != ctldgraph.ActualChangeOpUpsert || res.GroupKind() != statefulSetGK {
+			continue
+		}
+
+		var sts appsv1.StatefulSet
+
+		err := res.AsUncheckedTypedObj(&sts)
+		if err != nil {
+			return fmt.Errorf("Resource %s: %w", res.Description(), err)
+		}
+
+		replicas := *defaultReplicas(sts.Spec.Replicas)
+
+		switch sts.Spec.PodManagementPolicy {
+		case "", appsv1.OrderedReadyPodManagement:
+			if replicas < 2 || c.config.MaxOrderedStartupSeconds == 0 {
+				break
+			}
+			estimated := replicas * (maxStartupDelay(sts.Spec.Template.Spec) + c.config.StartupAllowanceSeconds)
+			if estimated > c.config.MaxOrderedStartupSeconds {
+				findings = append(findings, preflight.NewWarning(res,
+					"podManagementPolicy OrderedReady starts %d replicas one at a time: estimated startup of %ds exceeds %ds "+
+						"(consider Parallel if pods do not depend on each other)",
+					replicas, estimated, c.config.MaxOrderedStartupSeconds))
+			}
+
+		case appsv1.ParallelPodManagement:
+			if peer, found := c.referencedPeer(sts); found {
+				findings = append(findings, preflight.NewWarning(res,
+					"podManagementPolicy Parallel starts all pods at once but containers reference peer %q: "+
+						"startup dependencies between pods may race", peer))
+			}
+		}
+
+		switch sts.Spec.UpdateStrategy.Type {
+		case appsv1.OnDeleteStatefulSetStrategyType:
+			if !c.config.AllowOnDelete {
+				findings = append(findings, preflight.NewWarning(res,
+					"updateStrategy OnDelete does not roll out template changes until pods are deleted manually"))
+			}
+
+		case "", appsv1.RollingUpdateStatefulSetStrategyType:
+			rollingUpdate := sts.Spec.UpdateStrategy.RollingUpdate
+			if rollingUpdate != nil && rollingUpdate.Partition != nil && *rollingUpdate.Partition >= replicas {
+				findings = append(findings, preflight.NewWarning(res,
+					"updateStrategy partition %d is not less than %d replica(s): template changes will not be rolled out",
+					*rollingUpdate.Partition, replicas))
+			}
+		}
+	}
+
+	return errors.Join(findings...)
+}
+
+// referencedPeer returns the first pod name of the StatefulSet
+// (e.g. "db-0") referenced from container commands, args or env values
+func (c *StatefulSetPolicySane) referencedPeer(sts appsv1.StatefulSet) (string, bool) {
+	peer := sts.Name + "-0"
+	// Avoid matching names that merely end with peer (e.g. "mydb-0" or "db-01")
+	peerRegexp := regexp.MustCompile(`(^|[^a-z0-9.-])` + regexp.QuoteMeta(peer) + `($|[^a-z0-9-])`)
+
+	references := func(container corev1.Container) bool {
+		vals := append(append([]string{}, container.Command...), container.Args...)
+		for _, env := range container.Env {
+			vals = append(vals, env.Value)
+		}
+		for _, val := range vals {
+			if peerRegexp.MatchString(val) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, container := range allContainers(sts.Spec.Template.Spec) {
+		if references(container) {
+			return peer, true
+		}
+	}
+	return "", false
+}
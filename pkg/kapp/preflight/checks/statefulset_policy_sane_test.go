@@ -0,0 +1,216 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/checks"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+func TestStatefulSetPolicySane(t *testing.T) {
+	testCases := []struct {
+		name             string
+		resYAML          string
+		config           preflight.CheckConfig
+		expectedWarnings []string
+	}{
+		{
+			name: "ordered startup within limit",
+			resYAML: `
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: db
+        readinessProbe:
+          initialDelaySeconds: 30
+`,
+		},
+		{
+			name: "ordered startup exceeds limit",
+			resYAML: `
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  replicas: 5
+  template:
+    spec:
+      containers:
+      - name: db
+        readinessProbe:
+          initialDelaySeconds: 90
+`,
+			expectedWarnings: []string{
+				"statefulset/db (apps/v1) cluster: podManagementPolicy OrderedReady starts 5 replicas one at a time: " +
+					"estimated startup of 750s exceeds 600s (consider Parallel if pods do not depend on each other)",
+			},
+		},
+		{
+			name: "ordered startup limit is configurable",
+			resYAML: `
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  replicas: 3
+  podManagementPolicy: OrderedReady
+  template:
+    spec:
+      containers:
+      - name: db
+`,
+			config: preflight.CheckConfig{"maxOrderedStartupSeconds": 120},
+			expectedWarnings: []string{
+				"statefulset/db (apps/v1) cluster: podManagementPolicy OrderedReady starts 3 replicas one at a time: " +
+					"estimated startup of 180s exceeds 120s (consider Parallel if pods do not depend on each other)",
+			},
+		},
+		{
+			name: "parallel startup with containers referencing first peer",
+			resYAML: `
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  replicas: 3
+  podManagementPolicy: Parallel
+  template:
+    spec:
+      initContainers:
+      - name: wait
+        command: ["sh", "-c", "until nc -z db-0.db 5432; do sleep 1; done"]
+      containers:
+      - name: db
+`,
+			expectedWarnings: []string{
+				`statefulset/db (apps/v1) cluster: podManagementPolicy Parallel starts all pods at once but ` +
+					`containers reference peer "db-0": startup dependencies between pods may race`,
+			},
+		},
+		{
+			name: "parallel startup with containers referencing similarly named resources",
+			resYAML: `
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  replicas: 3
+  podManagementPolicy: Parallel
+  template:
+    spec:
+      containers:
+      - name: db
+        env:
+        - name: UPSTREAM
+          value: mydb-0.mydb
+        - name: OTHER
+          value: db-01
+`,
+		},
+		{
+			name: "on delete update strategy",
+			resYAML: `
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  replicas: 1
+  updateStrategy:
+    type: OnDelete
+  template:
+    spec:
+      containers:
+      - name: db
+`,
+			expectedWarnings: []string{
+				"statefulset/db (apps/v1) cluster: updateStrategy OnDelete does not roll out template changes until pods are deleted manually",
+			},
+		},
+		{
+			name: "on delete update strategy allowed via config",
+			resYAML: `
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  replicas: 1
+  updateStrategy:
+    type: OnDelete
+  template:
+    spec:
+      containers:
+      - name: db
+`,
+			config: preflight.CheckConfig{"allowOnDelete": true},
+		},
+		{
+			name: "rolling update partition covers all replicas",
+			resYAML: `
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  replicas: 2
+  podManagementPolicy: Parallel
+  updateStrategy:
+    rollingUpdate:
+      partition: 2
+  template:
+    spec:
+      containers:
+      - name: db
+`,
+			expectedWarnings: []string{
+				"statefulset/db (apps/v1) cluster: updateStrategy partition 2 is not less than 2 replica(s): template changes will not be rolled out",
+			},
+		},
+		{
+			name: "non statefulsets are ignored",
+			resYAML: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: db
+spec:
+  replicas: 100
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := ctlres.MustNewResourceFromBytes([]byte(tc.resYAML))
+
+			check := checks.NewStatefulSetPolicySane(true).(preflight.ConfigurableCheck)
+			require.NoError(t, check.SetConfig(tc.config))
+
+			findings := preflighttest.RunCheckOnResources(t, check, []ctlres.Resource{res})
+			require.Equal(t, tc.expectedWarnings, preflighttest.Messages(findings))
+		})
+	}
+}
+
+func TestStatefulSetPolicySaneInvalidConfig(t *testing.T) {
+	check := checks.NewStatefulSetPolicySane(true).(preflight.ConfigurableCheck)
+	require.Error(t, check.SetConfig(preflight.CheckConfig{"unknownKey": 1}))
+	require.Error(t, check.SetConfig(preflight.CheckConfig{"maxOrderedStartupSeconds": -1}))
+}
@@ -0,0 +1,136 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package checks
+
+import (
+	"fmt"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	podGK         = schema.GroupKind{Group: "", Kind: "Pod"}
+	deploymentGK  = schema.GroupKind{Group: "apps", Kind: "Deployment"}
+	replicaSetGK  = schema.GroupKind{Group: "apps", Kind: "ReplicaSet"}
+	statefulSetGK = schema.GroupKind{Group: "apps", Kind: "StatefulSet"}
+	daemonSetGK   = schema.GroupKind{Group: "apps", Kind: "DaemonSet"}
+	jobGK         = schema.GroupKind{Group: "batch", Kind: "Job"}
+	cronJobGK     = schema.GroupKind{Group: "batch", Kind: "CronJob"}
+)
+
+// workload is a resource that results in pods being created
+type workload struct {
+	Resource ctlres.Resource
+	// Replicas is nil for workloads that are not scaled
+	// via replica count (e.g. DaemonSets, Jobs, Pods)
+	Replicas *int32
+	Template corev1.PodTemplateSpec
+}
+
+// ReplicaCount returns number of desired replicas
+// (defaulting to 1 when not specified)
+func (w workload) ReplicaCount() int32 {
+	if w.Replicas == nil {
+		return 1
+	}
+	return *w.Replicas
+}
+
+// upsertedWorkloads returns workloads that are being
+// created or updated as part of the change graph
+func upsertedWorkloads(changeGraph *ctldgraph.ChangeGraph) ([]workload, error) {
+	var result []workload
+
+	for _, change := range changeGraph.All() {
+		if change.Change.Op() != ctldgraph.ActualChangeOpUpsert {
+			continue
+		}
+
+		wl, found, err := newWorkload(change.Change.Resource())
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			result = append(result, wl)
+		}
+	}
+
+	return result, nil
+}
+
+func newWorkload(res ctlres.Resource) (workload, bool, error) {
+	wl := workload{Resource: res}
+
+	var err error
+
+	switch res.GroupKind() {
+	case podGK:
+		var pod corev1.Pod
+		err = res.AsUncheckedTypedObj(&pod)
+		wl.Template = corev1.PodTemplateSpec{ObjectMeta: pod.ObjectMeta, Spec: pod.Spec}
+
+	case deploymentGK:
+		var dep appsv1.Deployment
+		err = res.AsUncheckedTypedObj(&dep)
+		wl.Replicas = defaultReplicas(dep.Spec.Replicas)
+		wl.Template = dep.Spec.Template
+
+	case replicaSetGK:
+		var rs appsv1.ReplicaSet
+		err = res.AsUncheckedTypedObj(&rs)
+		wl.Replicas = defaultReplicas(rs.Spec.Replicas)
+		wl.Template = rs.Spec.Template
+
+	case statefulSetGK:
+		var sts appsv1.StatefulSet
+		err = res.AsUncheckedTypedObj(&sts)
+		wl.Replicas = defaultReplicas(sts.Spec.Replicas)
+		wl.Template = sts.Spec.Template
+
+	case daemonSetGK:
+		var ds appsv1.DaemonSet
+		err = res.AsUncheckedTypedObj(&ds)
+		wl.Template = ds.Spec.Template
+
+	case jobGK:
+		var job batchv1.Job
+		err = res.AsUncheckedTypedObj(&job)
+		wl.Template = job.Spec.Template
+
+	case cronJobGK:
+		var cronJob batchv1.CronJob
+		err = res.AsUncheckedTypedObj(&cronJob)
+		wl.Template = cronJob.Spec.JobTemplate.Spec.Template
+
+	default:
+		return workload{}, false, nil
+	}
+
+	if err != nil {
+		return workload{}, false, fmt.Errorf("Resource %s: %w", res.Description(), err)
+	}
+
+	return wl, true, nil
+}
+
+func defaultReplicas(replicas *int32) *int32 {
+	if replicas != nil {
+		return replicas
+	}
+	one := int32(1)
+	return &one
+}
+
+// allContainers returns init containers followed by containers
+func allContainers(podSpec corev1.PodSpec) []corev1.Container {
+	var result []corev1.Container
+	result = append(result, podSpec.InitContainers...)
+	result = append(result, podSpec.Containers...)
+	return result
+}
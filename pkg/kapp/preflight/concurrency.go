@@ -0,0 +1,241 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+)
+
+const (
+	configParallelismKey   = "parallelism"
+	configMaxGoroutinesKey = "maxGoroutines"
+
+	// defaultParallelism is the maximum number of
+	// parallel checks that run at the same time
+	defaultParallelism = 4
+
+	// goroutineSettleTimeout is the time given to goroutines
+	// that are about to exit before they are counted
+	goroutineSettleTimeout = 100 * time.Millisecond
+)
+
+// ConcurrencyClass determines whether a preflight
+// check may run at the same time as other checks
+type ConcurrencyClass string
+
+const (
+	// ConcurrencyClassSerial checks run one at a time after
+	// all parallel checks finished. It is the default since
+	// checks are not required to be safe for concurrent use
+	// of shared clients or to limit load they put on the API server.
+	ConcurrencyClassSerial ConcurrencyClass = ""
+	// ConcurrencyClassParallel checks run concurrently with
+	// each other, up to the parallelism of the registry
+	ConcurrencyClassParallel ConcurrencyClass = "parallel"
+)
+
+// SetParallelism sets maximum number of parallel checks that
+// run at the same time. Serial checks always run one at a time.
+func (c *Registry) SetParallelism(parallelism int) error {
+	if parallelism < 1 {
+		return fmt.Errorf("expected %s to be at least 1", configParallelismKey)
+	}
+	c.parallelism = parallelism
+	return nil
+}
+
+// Parallelism returns maximum number of parallel checks that run at the same time
+func (c *Registry) Parallelism() int {
+	if c.parallelism == 0 {
+		return defaultParallelism
+	}
+	return c.parallelism
+}
+
+// SetMaxGoroutines limits number of goroutines each check may run at
+// the same time via its WorkGroup (zero means no limit). Starting more
+// goroutines fails (see WorkGroup.Go). Regardless of the limit, checks
+// that leave goroutines of their WorkGroup running once they finish
+// fail as well. Goroutines
+// started by checks directly (and by clients they use, e.g. HTTP/2
+// connections of Kubernetes clients) are not counted since Go does
+// not attribute goroutines to their creators. Memory used by checks
+// is not limited for the same reason.
+func (c *Registry) SetMaxGoroutines(maxGoroutines int) error {
+	if maxGoroutines < 0 {
+		return fmt.Errorf("expected %s to be non-negative", configMaxGoroutinesKey)
+	}
+	c.maxGoroutines = maxGoroutines
+	return nil
+}
+
+// WorkGroup runs goroutines on behalf of a single preflight check
+// so that the registry can bound them (see SetMaxGoroutines)
+type WorkGroup struct {
+	limit int
+
+	lock    sync.Mutex
+	running int
+	panics  []error
+	wg      sync.WaitGroup
+}
+
+type workGroupCtxKey struct{}
+
+// sharedWorkGroup is an unbounded WorkGroup used
+// by checks that are not run by the registry
+var sharedWorkGroup = &WorkGroup{}
+
+// WorkGroupFromContext returns WorkGroup of the check running with
+// the context (unbounded if the registry does not limit goroutines).
+// Returns a shared unbounded WorkGroup if the check is not run by
+// the registry.
+func WorkGroupFromContext(ctx context.Context) *WorkGroup {
+	if ctx != nil {
+		if group, ok := ctx.Value(workGroupCtxKey{}).(*WorkGroup); ok {
+			return group
+		}
+	}
+	return sharedWorkGroup
+}
+
+// Go runs f in a new goroutine unless the check already runs as many
+// goroutines as allowed, in which case an error is returned instead.
+// Panics in f are recovered and reported as an error of the check.
+func (g *WorkGroup) Go(f func()) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if g.limit > 0 && g.running >= g.limit {
+		return fmt.Errorf("starting goroutine: %d goroutine(s) already running, reaching limit of %d", g.running, g.limit)
+	}
+
+	g.running++
+	g.wg.Add(1)
+
+	go func() {
+		defer func() {
+			r := recover()
+
+			g.lock.Lock()
+			if r != nil {
+				g.panics = append(g.panics, fmt.Errorf("goroutine panicked: %v", r))
+			}
+			g.running--
+			g.lock.Unlock()
+			g.wg.Done()
+		}()
+		f()
+	}()
+
+	return nil
+}
+
+// Wait waits for goroutines started via Go to finish
+func (g *WorkGroup) Wait() {
+	g.wg.Wait()
+}
+
+// errs returns panics recovered from goroutines that finished
+func (g *WorkGroup) errs() []error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.panics
+}
+
+func (g *WorkGroup) numRunning() int {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.running
+}
+
+// leftRunning returns number of goroutines that are still running
+// after giving goroutines that are about to exit time to do so
+func (g *WorkGroup) leftRunning() int {
+	deadline := time.Now().Add(goroutineSettleTimeout)
+
+	for {
+		running := g.numRunning()
+		if running == 0 || time.Now().After(deadline) {
+			return running
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// checkRun holds the outcome of running a single check
+type checkRun struct {
+	name     string
+	check    Check
+	stats    CheckStats
+	findings []Finding
+	err      error
+}
+
+// runChecks runs parallel checks (bounded by parallelism) followed by
+// serial checks one at a time. Outcomes are stored in provided runs
+// so that callers can process them in a deterministic order.
+func (c *Registry) runChecks(ctx context.Context, cg *ctldgraph.ChangeGraph, runs []*checkRun) {
+	var serialRuns []*checkRun
+	var wg sync.WaitGroup
+
+	sem := make(chan struct{}, c.Parallelism())
+
+	for _, run := range runs {
+		if c.opts[run.name].Concurrency != ConcurrencyClassParallel {
+			serialRuns = append(serialRuns, run)
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(run *checkRun) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			c.runCheck(ctx, cg, run)
+		}(run)
+	}
+
+	wg.Wait()
+
+	for _, run := range serialRuns {
+		c.runCheck(ctx, cg, run)
+	}
+}
+
+func (c *Registry) runCheck(ctx context.Context, cg *ctldgraph.ChangeGraph, run *checkRun) {
+	checkCtx, span := c.tracerOrNoop().Start(ctx, run.name)
+	if c.apiCallCounter != nil {
+		checkCtx = ContextWithCheckName(checkCtx, run.name)
+	}
+
+	if checkCtx == nil {
+		checkCtx = context.Background()
+	}
+
+	group := &WorkGroup{limit: c.maxGoroutines}
+	checkCtx = context.WithValue(checkCtx, workGroupCtxKey{}, group)
+
+	startTime := time.Now()
+	run.findings, run.err = SplitFindings(run.check.Run(checkCtx, cg))
+	run.stats = c.newStats(run.name, time.Since(startTime))
+
+	if leftRunning := group.leftRunning(); leftRunning > 0 {
+		run.err = errors.Join(run.err, fmt.Errorf("left %d goroutine(s) running", leftRunning))
+	}
+	if panics := group.errs(); len(panics) > 0 {
+		run.err = errors.Join(append([]error{run.err}, panics...)...)
+	}
+
+	endCheckSpan(span, run)
+}
@@ -0,0 +1,207 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+)
+
+// concurrencyTracker records maximum number of
+// checks running at the same time per class
+type concurrencyTracker struct {
+	lock    sync.Mutex
+	running map[ConcurrencyClass]int
+	max     map[ConcurrencyClass]int
+	// serialDuringParallel is set if a serial check
+	// started while parallel checks were running
+	serialDuringParallel bool
+}
+
+func (t *concurrencyTracker) newCheck(class ConcurrencyClass, msg string) Check {
+	return NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error {
+		t.lock.Lock()
+		t.running[class]++
+		t.max[class] = max(t.max[class], t.running[class])
+		if class == ConcurrencyClassSerial && t.running[ConcurrencyClassParallel] > 0 {
+			t.serialDuringParallel = true
+		}
+		t.lock.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		t.lock.Lock()
+		t.running[class]--
+		t.lock.Unlock()
+
+		return NewWarning(nil, msg)
+	}, true)
+}
+
+func TestRegistryParallelism(t *testing.T) {
+	testCases := []struct {
+		name                string
+		parallelism         int
+		expectedMaxParallel int
+	}{
+		{name: "default parallelism", expectedMaxParallel: defaultParallelism},
+		{name: "custom parallelism", parallelism: 2, expectedMaxParallel: 2},
+		{name: "parallelism of one", parallelism: 1, expectedMaxParallel: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tracker := &concurrencyTracker{running: map[ConcurrencyClass]int{}, max: map[ConcurrencyClass]int{}}
+			registry := &Registry{}
+
+			var expectedMessages []string
+
+			for i := 0; i < 8; i++ {
+				name := fmt.Sprintf("parallel%d", i)
+				registry.AddCheckWithOpts(name, tracker.newCheck(ConcurrencyClassParallel, name),
+					CheckOpts{Concurrency: ConcurrencyClassParallel})
+				expectedMessages = append(expectedMessages, name)
+			}
+			for i := 0; i < 3; i++ {
+				name := fmt.Sprintf("serial%d", i)
+				registry.AddCheck(name, tracker.newCheck(ConcurrencyClassSerial, name))
+				expectedMessages = append(expectedMessages, name)
+			}
+
+			if tc.parallelism > 0 {
+				require.NoError(t, registry.SetParallelism(tc.parallelism))
+			}
+
+			findings, err := registry.Run(context.Background(), nil)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.expectedMaxParallel, tracker.max[ConcurrencyClassParallel])
+			require.Equal(t, 1, tracker.max[ConcurrencyClassSerial])
+			require.False(t, tracker.serialDuringParallel)
+
+			// Findings and stats are ordered by check name regardless of execution order
+			var messages, statsNames []string
+			for _, finding := range findings {
+				messages = append(messages, finding.Message)
+			}
+			for _, stats := range registry.Stats() {
+				statsNames = append(statsNames, stats.Name)
+			}
+			require.Equal(t, expectedMessages, messages)
+			require.Equal(t, expectedMessages, statsNames)
+		})
+	}
+
+	t.Run("invalid parallelism, error returned", func(t *testing.T) {
+		require.EqualError(t, (&Registry{}).SetParallelism(0), "expected parallelism to be at least 1")
+	})
+}
+
+func TestRegistryMaxGoroutines(t *testing.T) {
+	// Goroutines left running are stopped once all test
+	// cases finished so that they do not block forever
+	stop := make(chan struct{})
+	defer close(stop)
+
+	// workingCheck starts goroutines via its WorkGroup and waits for
+	// them to finish unless leave is set, in which case they keep
+	// running until stop is closed
+	workingCheck := func(numGoroutines int, leave bool) Check {
+		return NewCheck(func(ctx context.Context, _ *diffgraph.ChangeGraph) error {
+			group := WorkGroupFromContext(ctx)
+			done := make(chan struct{})
+			if !leave {
+				defer group.Wait()
+				defer close(done)
+			}
+			for i := 0; i < numGoroutines; i++ {
+				err := group.Go(func() {
+					select {
+					case <-done:
+					case <-stop:
+					}
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}, true)
+	}
+
+	testCases := []struct {
+		name          string
+		maxGoroutines int
+		check         Check
+		expectedErr   string
+	}{
+		{
+			name:  "no limit, no error returned",
+			check: workingCheck(5, false),
+		},
+		{
+			name:          "goroutines within limit, no error returned",
+			maxGoroutines: 2,
+			check:         workingCheck(2, false),
+		},
+		{
+			name:          "starting goroutines over limit, error returned",
+			maxGoroutines: 2,
+			check:         workingCheck(5, false),
+			expectedErr:   `running preflight check "working": starting goroutine: 2 goroutine(s) already running, reaching limit of 2`,
+		},
+		{
+			name:          "goroutines left running, error returned",
+			maxGoroutines: 10,
+			check:         workingCheck(3, true),
+			expectedErr:   `running preflight check "working": left 3 goroutine(s) running`,
+		},
+		{
+			name:        "goroutines left running without limit, error returned",
+			check:       workingCheck(3, true),
+			expectedErr: `running preflight check "working": left 3 goroutine(s) running`,
+		},
+		{
+			name: "goroutine panicking, error returned",
+			check: NewCheck(func(ctx context.Context, _ *diffgraph.ChangeGraph) error {
+				err := WorkGroupFromContext(ctx).Go(func() { panic("boom") })
+				if err != nil {
+					return err
+				}
+				// Same WorkGroup is returned for the check's context
+				WorkGroupFromContext(ctx).Wait()
+				return nil
+			}, true),
+			expectedErr: `running preflight check "working": goroutine panicked: boom`,
+		},
+	}
+
+	for _, tc := range testCases {
+		for concurrencyName, concurrency := range map[string]ConcurrencyClass{"serial": ConcurrencyClassSerial, "parallel": ConcurrencyClassParallel} {
+			t.Run(tc.name+" ("+concurrencyName+")", func(t *testing.T) {
+				registry := &Registry{}
+				registry.AddCheckWithOpts("working", tc.check, CheckOpts{Concurrency: concurrency})
+				registry.AddCheckWithOpts("plain", workingCheck(0, false), CheckOpts{Concurrency: concurrency})
+				require.NoError(t, registry.SetMaxGoroutines(tc.maxGoroutines))
+
+				_, err := registry.Run(context.Background(), nil)
+				if len(tc.expectedErr) > 0 {
+					require.EqualError(t, err, tc.expectedErr)
+				} else {
+					require.NoError(t, err)
+				}
+			})
+		}
+	}
+
+	t.Run("negative limit, error returned", func(t *testing.T) {
+		require.EqualError(t, (&Registry{}).SetMaxGoroutines(-1), "expected maxGoroutines to be non-negative")
+	})
+}
@@ -0,0 +1,468 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	preflightConfigFlag = "preflight-config"
+
+	configChecksKey = "checks"
+	// configMaxFindingsKey is accepted at the top level (applies
+	// to each check) and within configuration of each check
+	configMaxFindingsKey     = "maxFindings"
+	configSeverityWeightsKey = "severityWeights"
+	configWeightKey          = "weight"
+	// configExtendsKey is only accepted in config files
+	configExtendsKey = "extends"
+)
+
+// CheckConfig is the configuration of a single preflight check
+// as provided by the user
+type CheckConfig map[string]interface{}
+
+// Decode converts the configuration into the provided
+// struct (via its JSON tags). Unknown keys result in an error.
+func (c CheckConfig) Decode(obj interface{}) error {
+	bs, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(bs))
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode(obj)
+}
+
+// ConfigurableCheck is a Check that accepts
+// user provided configuration
+type ConfigurableCheck interface {
+	Check
+	SetConfig(CheckConfig) error
+	// Config returns the effective configuration
+	// (including defaults) of the check
+	Config() CheckConfig
+}
+
+// SetConfig configures preflight checks in the registry.
+// The configuration is expected to be in the format of:
+//
+//	maxFindings: 100
+//	parallelism: 4
+//	maxGoroutines: 100
+//	severityWeights:
+//	  warning: 1
+//	  error: 10
+//	severityLabels:
+//	  warning: P2
+//	  error: P1
+//	context:
+//	  env: prod
+//	checks:
+//	  CheckName:
+//	    maxFindings: 10
+//	    weight: 2
+//	    key: value
+//
+// maxFindings limits number of findings reported by each check
+// (zero means no limit). Weights determine score of findings (see
+// Score). parallelism limits parallel checks running at the same
+// time (see SetParallelism) and maxGoroutines limits goroutines
+// each check may run via its WorkGroup (see SetMaxGoroutines). severityLabels
+// customize how severities are shown in output (see SeverityLabels).
+// context provides deploy context to checks (see DeployContextFromContext).
+// All are handled by the registry itself.
+// Returns an error if configuration refers to an unknown check or
+// to a check that does not accept configuration (other than
+// maxFindings and weight). Configuration is applied only if it is
+// valid as a whole, otherwise neither the registry nor checks change.
+func (c *Registry) SetConfig(config map[string]interface{}) error {
+	for key := range config {
+		switch key {
+		case configChecksKey, configMaxFindingsKey, configSeverityWeightsKey, configSeverityLabelsKey,
+			configParallelismKey, configMaxGoroutinesKey, configContextKey:
+		default:
+			return fmt.Errorf("unknown preflight config key %q", key)
+		}
+	}
+
+	var maxFindings int
+	if val, found := config[configMaxFindingsKey]; found {
+		var err error
+		maxFindings, err = parseNonNegativeInt(configMaxFindingsKey, val)
+		if err != nil {
+			return err
+		}
+	}
+
+	var parallelism int
+	if val, found := config[configParallelismKey]; found {
+		var err error
+		parallelism, err = parseNonNegativeInt(configParallelismKey, val)
+		if err != nil {
+			return err
+		}
+		if parallelism == 0 {
+			return fmt.Errorf("expected %s to be at least 1", configParallelismKey)
+		}
+	}
+
+	var maxGoroutines int
+	if val, found := config[configMaxGoroutinesKey]; found {
+		var err error
+		maxGoroutines, err = parseNonNegativeInt(configMaxGoroutinesKey, val)
+		if err != nil {
+			return err
+		}
+	}
+
+	severityWeights, err := parseSeverityWeights(config[configSeverityWeightsKey])
+	if err != nil {
+		return err
+	}
+
+	severityLabels, err := parseSeverityLabels(config[configSeverityLabelsKey])
+	if err != nil {
+		return err
+	}
+
+	configDeployContext, err := parseDeployContext(config[configContextKey])
+	if err != nil {
+		return err
+	}
+
+	checkMaxFindings := map[string]int{}
+	checkWeights := map[string]float64{}
+
+	checksConfig, ok := config[configChecksKey].(map[string]interface{})
+	if !ok && config[configChecksKey] != nil {
+		return fmt.Errorf("expected preflight config key %q to be a map", configChecksKey)
+	}
+
+	var checkNames []string
+	for name := range checksConfig {
+		checkNames = append(checkNames, name)
+	}
+	// Errors are reported for the first invalid check in order of names
+	sort.Strings(checkNames)
+
+	// Only configs of checks are collected until all entries are validated
+	checkConfigs := map[string]CheckConfig{}
+
+	for _, name := range checkNames {
+		check, found := c.peek(name)
+		if !found {
+			return fmt.Errorf("unknown preflight check %q specified in config", name)
+		}
+
+		val := checksConfig[name]
+
+		checkConfig, ok := val.(map[string]interface{})
+		if !ok && val != nil {
+			return fmt.Errorf("expected config of preflight check %q to be a map", name)
+		}
+
+		var hasRegistryKeys bool
+
+		if limit, found := checkConfig[configMaxFindingsKey]; found {
+			n, err := parseNonNegativeInt(configMaxFindingsKey, limit)
+			if err != nil {
+				return fmt.Errorf("configuring preflight check %q: %w", name, err)
+			}
+			checkMaxFindings[name] = n
+			hasRegistryKeys = true
+		}
+
+		if weight, found := checkConfig[configWeightKey]; found {
+			w, err := parseWeight(configWeightKey, weight)
+			if err != nil {
+				return fmt.Errorf("configuring preflight check %q: %w", name, err)
+			}
+			checkWeights[name] = w
+			hasRegistryKeys = true
+		}
+
+		if hasRegistryKeys {
+			checkConfig = copyWithoutKeys(checkConfig, configMaxFindingsKey, configWeightKey)
+			if len(checkConfig) == 0 {
+				continue
+			}
+		}
+
+		if _, ok := check.(ConfigurableCheck); !ok {
+			return fmt.Errorf("preflight check %q does not accept configuration", name)
+		}
+
+		checkConfigs[name] = checkConfig
+	}
+
+	err = c.setChecksConfig(checkNames, checkConfigs)
+	if err != nil {
+		return err
+	}
+
+	c.config = config
+	c.maxFindings = maxFindings
+	c.checkMaxFindings = checkMaxFindings
+	c.severityWeights = severityWeights
+	c.severityLabels = severityLabels
+	c.checkWeights = checkWeights
+	c.parallelism = parallelism
+	c.maxGoroutines = maxGoroutines
+	c.configDeployContext = configDeployContext
+
+	return nil
+}
+
+// peek is like lookup but does not register
+// a new instance of a check if name refers to one
+func (c *Registry) peek(name string) (Check, bool) {
+	if check, found := c.known[name]; found {
+		return check, true
+	}
+
+	baseName, instanceName, found := strings.Cut(name, checkInstanceSeparator)
+	if !found || len(instanceName) == 0 {
+		return nil, false
+	}
+
+	factory, found := c.factories[baseName]
+	if !found {
+		return nil, false
+	}
+
+	return factory(), true
+}
+
+// setChecksConfig applies configs to checks (in order of names). If
+// a check rejects its config, checks configured so far are reverted
+// to their previous configs (and new check instances are removed)
+// so that either all or none of configs are applied.
+func (c *Registry) setChecksConfig(names []string, configs map[string]CheckConfig) error {
+	var revertFuncs []func() error
+
+	revert := func(err error) error {
+		for i := len(revertFuncs) - 1; i >= 0; i-- {
+			if revertErr := revertFuncs[i](); revertErr != nil {
+				err = errors.Join(err, revertErr)
+			}
+		}
+		return err
+	}
+
+	for _, name := range names {
+		name := name
+
+		config, found := configs[name]
+		if !found {
+			continue
+		}
+
+		_, known := c.known[name]
+		check, _ := c.lookup(name)
+		configurableCheck := check.(ConfigurableCheck)
+		prevConfig := configurableCheck.Config()
+
+		// Checks keep their config when they reject a new one
+		err := configurableCheck.SetConfig(config)
+		if err != nil {
+			if !known {
+				delete(c.known, name)
+				delete(c.opts, name)
+			}
+			return revert(fmt.Errorf("configuring preflight check %q: %w", name, err))
+		}
+
+		if known {
+			revertFuncs = append(revertFuncs, func() error {
+				err := configurableCheck.SetConfig(prevConfig)
+				if err != nil {
+					return fmt.Errorf("reverting config of preflight check %q: %w", name, err)
+				}
+				return nil
+			})
+		} else {
+			revertFuncs = append(revertFuncs, func() error {
+				delete(c.known, name)
+				delete(c.opts, name)
+				return nil
+			})
+		}
+	}
+
+	return nil
+}
+
+// RawConfig returns configuration as provided via SetConfig (with
+// config files already merged with their base configs) before it
+// is interpreted by the registry and checks. Returned value is a copy
+// that can be modified without affecting the registry. Returns nil
+// if registry was not configured.
+func (c *Registry) RawConfig() map[string]interface{} {
+	if c.config == nil {
+		return nil
+	}
+	return deepCopyConfigValue(c.config).(map[string]interface{})
+}
+
+func deepCopyConfigValue(val interface{}) interface{} {
+	switch typedVal := val.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(typedVal))
+		for k, v := range typedVal {
+			result[k] = deepCopyConfigValue(v)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(typedVal))
+		for i, v := range typedVal {
+			result[i] = deepCopyConfigValue(v)
+		}
+		return result
+	default:
+		// Scalars parsed from YAML or JSON are immutable
+		return val
+	}
+}
+
+func parseNonNegativeInt(key string, val interface{}) (int, error) {
+	var result int
+
+	switch typedVal := val.(type) {
+	case int:
+		result = typedVal
+	case int64:
+		result = int(typedVal)
+	case float64:
+		result = int(typedVal)
+		if float64(result) != typedVal {
+			return 0, fmt.Errorf("expected %s to be an integer", key)
+		}
+	default:
+		return 0, fmt.Errorf("expected %s to be an integer", key)
+	}
+
+	if result < 0 {
+		return 0, fmt.Errorf("expected %s to be non-negative", key)
+	}
+	return result, nil
+}
+
+func copyWithoutKeys(m map[string]interface{}, keys ...string) map[string]interface{} {
+	result := map[string]interface{}{}
+	for k, v := range m {
+		result[k] = v
+	}
+	for _, key := range keys {
+		delete(result, key)
+	}
+	return result
+}
+
+// configFileFlag is a pflag.Value that loads
+// preflight checks configuration from a YAML file
+type configFileFlag struct {
+	registry *Registry
+	path     string
+}
+
+func (f *configFileFlag) String() string { return f.path }
+func (f *configFileFlag) Type() string   { return "string" }
+
+func (f *configFileFlag) Set(path string) error {
+	config, err := loadConfigFile(path, nil)
+	if err != nil {
+		return err
+	}
+
+	// Policies loaded via policy dir flag take precedence
+	err = f.registry.SetConfig(mergeConfig(config, f.registry.policyConfig))
+	if err != nil {
+		return err
+	}
+
+	f.path = path
+	return nil
+}
+
+// loadConfigFile reads preflight config from a YAML file resolving
+// its base config specified via extends key (relative paths are
+// relative to the directory of the file). Configuration in the file
+// is deeply merged over its base config. Chain includes paths of files
+// extending the file and is used to detect circular extends.
+func loadConfigFile(path string, chain []string) (map[string]interface{}, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading preflight config: %w", err)
+	}
+
+	for _, p := range chain {
+		if p == absPath {
+			return nil, fmt.Errorf("circular extends in preflight config: %s", strings.Join(append(chain, absPath), " -> "))
+		}
+	}
+
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading preflight config: %w", err)
+	}
+
+	var config map[string]interface{}
+
+	err = yaml.Unmarshal(bs, &config)
+	if err != nil {
+		return nil, fmt.Errorf("parsing preflight config %q: %w", path, err)
+	}
+
+	val, found := config[configExtendsKey]
+	if !found {
+		return config, nil
+	}
+
+	basePath, ok := val.(string)
+	if !ok || len(basePath) == 0 {
+		return nil, fmt.Errorf("expected key %q of preflight config %q to be a non-empty string", configExtendsKey, path)
+	}
+	if !filepath.IsAbs(basePath) {
+		basePath = filepath.Join(filepath.Dir(path), basePath)
+	}
+
+	baseConfig, err := loadConfigFile(basePath, append(chain, absPath))
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeConfig(baseConfig, copyWithoutKeys(config, configExtendsKey)), nil
+}
+
+// mergeConfig returns base config with values from override config.
+// Nested maps are merged recursively, other values are replaced.
+func mergeConfig(base, override map[string]interface{}) map[string]interface{} {
+	result := copyWithoutKeys(base)
+
+	for key, val := range override {
+		baseMap, baseIsMap := result[key].(map[string]interface{})
+		overrideMap, overrideIsMap := val.(map[string]interface{})
+
+		if baseIsMap && overrideIsMap {
+			result[key] = mergeConfig(baseMap, overrideMap)
+		} else {
+			result[key] = val
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,417 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+package preflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+)
+
+type configurableCheck struct {
+	Check
+	config CheckConfig
+}
+
+func (c *configurableCheck) SetConfig(config CheckConfig) error {
+	c.config = config
+	return nil
+}
+
+func (c *configurableCheck) Config() CheckConfig {
+	return c.config
+}
+
+func newConfigurableCheck() *configurableCheck {
+	return &configurableCheck{Check: NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error { return nil }, true)}
+}
+
+func TestRegistrySetConfig(t *testing.T) {
+	testCases := []struct {
+		name      string
+		config    map[string]interface{}
+		shouldErr bool
+	}{
+		{
+			name:   "no config provided, no error returned",
+			config: map[string]interface{}{},
+		},
+		{
+			name:   "config provided for configurable check, no error returned",
+			config: map[string]interface{}{"checks": map[string]interface{}{"configurable": map[string]interface{}{"key": "value"}}},
+		},
+		{
+			name:      "unknown top level key, error returned",
+			config:    map[string]interface{}{"unknown": true},
+			shouldErr: true,
+		},
+		{
+			name:      "config provided for unknown check, error returned",
+			config:    map[string]interface{}{"checks": map[string]interface{}{"nonexistent": map[string]interface{}{}}},
+			shouldErr: true,
+		},
+		{
+			name:      "config provided for non-configurable check, error returned",
+			config:    map[string]interface{}{"checks": map[string]interface{}{"plain": map[string]interface{}{}}},
+			shouldErr: true,
+		},
+		{
+			name:   "global and per check maxFindings provided, no error returned",
+			config: map[string]interface{}{"maxFindings": float64(10), "checks": map[string]interface{}{"configurable": map[string]interface{}{"maxFindings": float64(0)}}},
+		},
+		{
+			name:   "only maxFindings provided for non-configurable check, no error returned",
+			config: map[string]interface{}{"checks": map[string]interface{}{"plain": map[string]interface{}{"maxFindings": float64(5)}}},
+		},
+		{
+			name:      "other config provided along with maxFindings for non-configurable check, error returned",
+			config:    map[string]interface{}{"checks": map[string]interface{}{"plain": map[string]interface{}{"maxFindings": float64(5), "key": "value"}}},
+			shouldErr: true,
+		},
+		{
+			name:      "negative maxFindings, error returned",
+			config:    map[string]interface{}{"maxFindings": float64(-1)},
+			shouldErr: true,
+		},
+		{
+			name:      "fractional maxFindings, error returned",
+			config:    map[string]interface{}{"checks": map[string]interface{}{"configurable": map[string]interface{}{"maxFindings": 1.5}}},
+			shouldErr: true,
+		},
+		{
+			name:   "parallelism provided, no error returned",
+			config: map[string]interface{}{"parallelism": float64(2)},
+		},
+		{
+			name:      "zero parallelism, error returned",
+			config:    map[string]interface{}{"parallelism": float64(0)},
+			shouldErr: true,
+		},
+		{
+			name:      "check config is not a map, error returned",
+			config:    map[string]interface{}{"checks": map[string]interface{}{"configurable": "value"}},
+			shouldErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := NewRegistry(map[string]Check{
+				"configurable": newConfigurableCheck(),
+				"plain":        NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error { return nil }, true),
+			})
+			err := registry.SetConfig(tc.config)
+			require.Equal(t, tc.shouldErr, err != nil)
+		})
+	}
+}
+
+func TestRegistrySetConfigAtomic(t *testing.T) {
+	newRegistry := func() (*Registry, *configurableCheck) {
+		configurable := newConfigurableCheck()
+		configurable.config = CheckConfig{"key": "initial"}
+
+		registry := NewRegistry(map[string]Check{
+			"configurable": configurable,
+			"rejecting":    &rejectingCheck{newConfigurableCheck()},
+			"plain":        NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error { return nil }, true),
+		})
+		registry.AddCheckFactory("factory", func() Check { return newConfigurableCheck() }, CheckOpts{})
+
+		require.NoError(t, registry.SetConfig(map[string]interface{}{"maxFindings": float64(5)}))
+
+		return registry, configurable
+	}
+
+	testCases := []struct {
+		name        string
+		checks      map[string]interface{}
+		expectedErr string
+	}{
+		{
+			name: "check rejecting its config",
+			checks: map[string]interface{}{
+				"configurable": map[string]interface{}{"key": "changed"},
+				"factory:one":  map[string]interface{}{"key": "value"},
+				"rejecting":    map[string]interface{}{"key": "value"},
+			},
+			expectedErr: `configuring preflight check "rejecting": rejected`,
+		},
+		{
+			name: "invalid registry keys of a check",
+			checks: map[string]interface{}{
+				"configurable": map[string]interface{}{"key": "changed"},
+				"factory:one":  map[string]interface{}{"key": "value"},
+				"plain":        map[string]interface{}{"weight": "heavy"},
+			},
+			expectedErr: `configuring preflight check "plain": expected weight to be a number`,
+		},
+		{
+			name: "multiple invalid checks, first one by name reported",
+			checks: map[string]interface{}{
+				"configurable": map[string]interface{}{"key": "changed"},
+				"nonexistent":  map[string]interface{}{},
+				"plain":        map[string]interface{}{"key": "value"},
+			},
+			expectedErr: `unknown preflight check "nonexistent" specified in config`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry, configurable := newRegistry()
+
+			err := registry.SetConfig(map[string]interface{}{"maxFindings": float64(1), "checks": tc.checks})
+			require.EqualError(t, err, tc.expectedErr)
+
+			require.Equal(t, CheckConfig{"key": "initial"}, configurable.config)
+			require.Equal(t, 5, registry.maxFindings)
+			require.Equal(t, []string{"configurable", "factory", "plain", "rejecting"}, registry.names())
+		})
+	}
+}
+
+type rejectingCheck struct {
+	*configurableCheck
+}
+
+func (c *rejectingCheck) SetConfig(_ CheckConfig) error {
+	return errors.New("rejected")
+}
+
+func TestRegistryMaxFindings(t *testing.T) {
+	manyFindings := func(severity Severity) Check {
+		return NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error {
+			var findings []error
+			for i := 0; i < 5; i++ {
+				findings = append(findings, Finding{Severity: severity, Message: fmt.Sprintf("finding %d", i)})
+			}
+			return errors.Join(findings...)
+		}, true)
+	}
+
+	testCases := []struct {
+		name             string
+		config           map[string]interface{}
+		expectedMessages []string
+	}{
+		{
+			name:             "no limit, all findings returned",
+			config:           map[string]interface{}{},
+			expectedMessages: []string{"finding 0", "finding 1", "finding 2", "finding 3", "finding 4"},
+		},
+		{
+			name:             "global limit, findings truncated with a note",
+			config:           map[string]interface{}{"maxFindings": float64(2)},
+			expectedMessages: []string{"finding 0", "finding 1", "and 3 more finding(s)"},
+		},
+		{
+			name: "per check limit overrides global limit",
+			config: map[string]interface{}{
+				"maxFindings": float64(2),
+				"checks":      map[string]interface{}{"many": map[string]interface{}{"maxFindings": float64(4)}},
+			},
+			expectedMessages: []string{"finding 0", "finding 1", "finding 2", "finding 3", "and 1 more finding(s)"},
+		},
+		{
+			name: "per check limit of zero disables global limit",
+			config: map[string]interface{}{
+				"maxFindings": float64(2),
+				"checks":      map[string]interface{}{"many": map[string]interface{}{"maxFindings": float64(0)}},
+			},
+			expectedMessages: []string{"finding 0", "finding 1", "finding 2", "finding 3", "finding 4"},
+		},
+		{
+			name:             "limit above number of findings, all findings returned",
+			config:           map[string]interface{}{"maxFindings": float64(5)},
+			expectedMessages: []string{"finding 0", "finding 1", "finding 2", "finding 3", "finding 4"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := NewRegistry(map[string]Check{"many": manyFindings(SeverityWarning)})
+			require.NoError(t, registry.SetConfig(tc.config))
+
+			findings, err := registry.Run(nil, nil)
+			require.NoError(t, err)
+
+			var messages []string
+			for _, finding := range findings {
+				require.Equal(t, "many", finding.Check)
+				messages = append(messages, finding.Message)
+			}
+			require.Equal(t, tc.expectedMessages, messages)
+		})
+	}
+
+	t.Run("errors beyond the limit are still reported", func(t *testing.T) {
+		registry := NewRegistry(map[string]Check{"many": manyFindings(SeverityError)})
+		require.NoError(t, registry.SetConfig(map[string]interface{}{"maxFindings": float64(1)}))
+
+		findings, err := registry.Run(nil, nil)
+		require.ErrorContains(t, err, `preflight check "many" reported 5 error(s)`)
+		require.Len(t, findings, 2)
+		require.Equal(t, SeverityError, findings[1].Severity)
+	})
+
+	t.Run("errors reported after the limit are kept over warnings", func(t *testing.T) {
+		check := NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error {
+			var findings []error
+			for i := 0; i < 3; i++ {
+				findings = append(findings, Finding{Severity: SeverityWarning, Message: fmt.Sprintf("warning %d", i)})
+			}
+			findings = append(findings, Finding{Severity: SeverityInfo, Message: "info"})
+			findings = append(findings, Finding{Severity: SeverityError, Message: "error"})
+			return errors.Join(findings...)
+		}, true)
+
+		registry := NewRegistry(map[string]Check{"mixed": check})
+		require.NoError(t, registry.SetConfig(map[string]interface{}{"maxFindings": float64(2)}))
+
+		findings, err := registry.Run(nil, nil)
+		require.ErrorContains(t, err, `preflight check "mixed" reported 1 error(s)`)
+
+		var messages []string
+		for _, finding := range findings {
+			messages = append(messages, finding.Message)
+		}
+		require.Equal(t, []string{"error", "warning 0", "and 3 more finding(s)"}, messages)
+		// Note has highest severity of omitted findings
+		require.Equal(t, SeverityWarning, findings[2].Severity)
+	})
+}
+
+func TestConfigFileFlag(t *testing.T) {
+	check := newConfigurableCheck()
+	registry := NewRegistry(map[string]Check{"configurable": check})
+
+	path := filepath.Join(t.TempDir(), "config.yml")
+	err := os.WriteFile(path, []byte(`
+checks:
+  configurable:
+    minSeconds: 120
+`), 0600)
+	require.NoError(t, err)
+
+	flag := &configFileFlag{registry: registry}
+	require.NoError(t, flag.Set(path))
+	require.Equal(t, path, flag.String())
+
+	var config struct {
+		MinSeconds int `json:"minSeconds"`
+	}
+	require.NoError(t, check.config.Decode(&config))
+	require.Equal(t, 120, config.MinSeconds)
+
+	require.Error(t, flag.Set(filepath.Join(t.TempDir(), "nonexistent.yml")))
+}
+
+func TestConfigFileFlagExtends(t *testing.T) {
+	writeFile := func(t *testing.T, path, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+
+	t.Run("child config is merged over base configs", func(t *testing.T) {
+		dir := t.TempDir()
+
+		writeFile(t, filepath.Join(dir, "base", "base.yml"), `
+maxFindings: 10
+checks:
+  configurable:
+    minSeconds: 60
+    startupAllowanceSeconds: 30
+  plain:
+    maxFindings: 5
+`)
+		writeFile(t, filepath.Join(dir, "base", "team.yml"), `
+extends: base.yml
+maxFindings: 20
+`)
+		writeFile(t, filepath.Join(dir, "prod.yml"), `
+extends: base/team.yml
+checks:
+  configurable:
+    minSeconds: 120
+`)
+
+		check := newConfigurableCheck()
+		registry := NewRegistry(map[string]Check{
+			"configurable": check,
+			"plain":        NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error { return nil }, true),
+		})
+
+		flag := &configFileFlag{registry: registry}
+		require.NoError(t, flag.Set(filepath.Join(dir, "prod.yml")))
+
+		require.Equal(t, CheckConfig{"minSeconds": float64(120), "startupAllowanceSeconds": float64(30)}, check.config)
+		require.Equal(t, 20, registry.maxFindings)
+		require.Equal(t, map[string]int{"plain": 5}, registry.checkMaxFindings)
+	})
+
+	t.Run("circular extends, error returned", func(t *testing.T) {
+		dir := t.TempDir()
+
+		writeFile(t, filepath.Join(dir, "a.yml"), "extends: b.yml\n")
+		writeFile(t, filepath.Join(dir, "b.yml"), "extends: a.yml\n")
+
+		flag := &configFileFlag{registry: NewRegistry(map[string]Check{})}
+		err := flag.Set(filepath.Join(dir, "a.yml"))
+		require.ErrorContains(t, err, "circular extends in preflight config: ")
+		require.ErrorContains(t, err, filepath.Join(dir, "a.yml")+" -> "+filepath.Join(dir, "b.yml")+" -> "+filepath.Join(dir, "a.yml"))
+	})
+
+	t.Run("extends is not a string, error returned", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yml")
+		writeFile(t, path, "extends: [base.yml]\n")
+
+		flag := &configFileFlag{registry: NewRegistry(map[string]Check{})}
+		require.ErrorContains(t, flag.Set(path), `expected key "extends" of preflight config`)
+	})
+
+	t.Run("base config does not exist, error returned", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yml")
+		writeFile(t, path, "extends: nonexistent.yml\n")
+
+		flag := &configFileFlag{registry: NewRegistry(map[string]Check{})}
+		require.ErrorContains(t, flag.Set(path), "reading preflight config")
+	})
+}
+
+func TestRegistryRawConfig(t *testing.T) {
+	registry := NewRegistry(map[string]Check{"configurable": newConfigurableCheck()})
+	require.Nil(t, registry.RawConfig())
+
+	config := map[string]interface{}{
+		"maxFindings": float64(10),
+		"checks": map[string]interface{}{
+			"configurable": map[string]interface{}{"images": []interface{}{"busybox"}},
+		},
+	}
+	require.NoError(t, registry.SetConfig(config))
+
+	rawConfig := registry.RawConfig()
+	require.Equal(t, config, rawConfig)
+
+	// Modifying returned config (including nested values) does not affect the registry
+	rawConfig["maxFindings"] = float64(1)
+	checksConfig := rawConfig["checks"].(map[string]interface{})
+	checkConfig := checksConfig["configurable"].(map[string]interface{})
+	checkConfig["images"].([]interface{})[0] = "nginx"
+	checkConfig["key"] = "value"
+	delete(checksConfig, "configurable")
+
+	require.Equal(t, map[string]interface{}{
+		"maxFindings": float64(10),
+		"checks": map[string]interface{}{
+			"configurable": map[string]interface{}{"images": []interface{}{"busybox"}},
+		},
+	}, registry.RawConfig())
+}
@@ -0,0 +1,187 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	// redactedConfigValue replaces values of redacted config keys
+	redactedConfigValue = "<redacted>"
+)
+
+// DecisionRecord is an audit record of a single run of preflight
+// checks. Records are written as JSON lines to the decision log.
+type DecisionRecord struct {
+	Time time.Time `json:"time"`
+	// Actor identifies who ran preflight checks (see ContextWithActor)
+	Actor string `json:"actor,omitempty"`
+	Phase Phase  `json:"phase"`
+	// Outcome is passed or failed
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+	// Context is the effective deploy context (see DeployContext)
+	// with values of redacted keys replaced
+	Context DeployContext `json:"context,omitempty"`
+	// Config is the raw configuration (see RawConfig)
+	// with values of redacted keys replaced
+	Config map[string]interface{} `json:"config,omitempty"`
+	Checks []DecisionCheck        `json:"checks"`
+}
+
+// DecisionCheck is the outcome of a check within a DecisionRecord
+type DecisionCheck struct {
+	Name string `json:"name"`
+	// Status is one of CheckStatus* values
+	Status     string `json:"status"`
+	SkipReason string `json:"skipReason,omitempty"`
+	Error      string `json:"error,omitempty"`
+	// Findings include labels of their severities (see SeverityLabels)
+	Findings []Finding `json:"findings,omitempty"`
+}
+
+// DecisionLogOpts configure records written to the decision log
+type DecisionLogOpts struct {
+	// RedactConfigKeys are keys (matched case insensitively
+	// at any depth) of config and of deploy context
+	// whose values are not recorded
+	RedactConfigKeys []string
+}
+
+type decisionLog struct {
+	writer io.Writer
+	opts   DecisionLogOpts
+}
+
+// SetDecisionLog configures the registry to write a DecisionRecord
+// for each run of checks (but not for Audit) to the writer as a JSON
+// line. Failing to write a record fails the run. Writer is not used
+// concurrently. Passing nil writer disables the decision log.
+func (c *Registry) SetDecisionLog(writer io.Writer, opts DecisionLogOpts) {
+	if writer == nil {
+		c.decisionLog = nil
+		return
+	}
+	c.decisionLog = &decisionLog{writer: writer, opts: opts}
+}
+
+type actorCtxKey struct{}
+
+// ContextWithActor returns a context recording actor (e.g. user
+// name) running preflight checks in the decision log
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorCtxKey{}, actor)
+}
+
+// ActorFromContext returns actor recorded via ContextWithActor
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorCtxKey{}).(string)
+	return actor
+}
+
+// newDecisionCheck describes outcome of a check run. Findings are
+// expected to have been limited (as they are returned from Run).
+func newDecisionCheck(run *checkRun, findings []Finding) DecisionCheck {
+	result := AuditResult{Name: run.name, Skipped: run.stats.Skipped,
+		SkipReason: run.stats.SkipReason, Findings: findings, Err: run.err}
+
+	check := DecisionCheck{Name: run.name, Status: result.Status(), SkipReason: run.stats.SkipReason, Findings: findings}
+	if run.err != nil {
+		check.Error = run.err.Error()
+	}
+	return check
+}
+
+// recordDecision writes a record of a run to the decision log if configured
+func (c *Registry) recordDecision(ctx context.Context, phase Phase, checks []DecisionCheck, runErr error) error {
+	if c.decisionLog == nil {
+		return nil
+	}
+
+	record := DecisionRecord{
+		Time:    time.Now().UTC(),
+		Actor:   ActorFromContext(ctx),
+		Phase:   phase,
+		Outcome: CheckStatusPassed,
+		Context: c.redactedDeployContext(),
+		Config:  c.redactedConfig(),
+		Checks:  checks,
+	}
+	if runErr != nil {
+		record.Outcome = CheckStatusFailed
+		record.Error = runErr.Error()
+	}
+	if record.Checks == nil {
+		record.Checks = []DecisionCheck{}
+	}
+	for i, check := range record.Checks {
+		record.Checks[i].Findings = c.severityLabels.Apply(check.Findings)
+	}
+
+	bs, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("writing preflight decision log: %w", err)
+	}
+
+	_, err = c.decisionLog.writer.Write(append(bs, '\n'))
+	if err != nil {
+		return fmt.Errorf("writing preflight decision log: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Registry) redactedConfig() map[string]interface{} {
+	config := c.RawConfig()
+	if config == nil {
+		return nil
+	}
+	return redactConfigValue(config, c.decisionLog.redactKeys()).(map[string]interface{})
+}
+
+func (c *Registry) redactedDeployContext() DeployContext {
+	deployContext := c.DeployContext()
+	redactKeys := c.decisionLog.redactKeys()
+
+	for key := range deployContext {
+		if _, found := redactKeys[strings.ToLower(key)]; found {
+			deployContext[key] = redactedConfigValue
+		}
+	}
+	return deployContext
+}
+
+// redactKeys returns lower cased keys of redacted values
+func (l *decisionLog) redactKeys() map[string]struct{} {
+	redactKeys := map[string]struct{}{}
+	for _, key := range l.opts.RedactConfigKeys {
+		redactKeys[strings.ToLower(key)] = struct{}{}
+	}
+	return redactKeys
+}
+
+// redactConfigValue replaces values of redacted keys in place
+func redactConfigValue(val interface{}, redactKeys map[string]struct{}) interface{} {
+	switch typedVal := val.(type) {
+	case map[string]interface{}:
+		for k, v := range typedVal {
+			if _, found := redactKeys[strings.ToLower(k)]; found {
+				typedVal[k] = redactedConfigValue
+			} else {
+				typedVal[k] = redactConfigValue(v, redactKeys)
+			}
+		}
+	case []interface{}:
+		for i, v := range typedVal {
+			typedVal[i] = redactConfigValue(v, redactKeys)
+		}
+	}
+	return val
+}
@@ -0,0 +1,129 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/logger"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestRegistryDecisionLog(t *testing.T) {
+	graph, err := diffgraph.NewChangeGraph(nil, nil, nil, logger.NewTODOLogger())
+	require.NoError(t, err)
+
+	newCheck := func(err error) Check {
+		return NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error { return err }, true)
+	}
+
+	registry := &Registry{}
+	registry.AddCheck("failing", newCheck(errors.Join(NewError(nil, "error"))))
+	registry.AddCheck("passing", newCheck(nil))
+	registry.AddCheckWithOpts("skipped", newCheck(nil), CheckOpts{RunsIf: []schema.GroupVersionKind{{Kind: "Service"}}})
+	registry.AddCheckWithOpts("configurable", newConfigurableCheck(), CheckOpts{})
+	registry.AddCheck("disabled", NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error { return nil }, false))
+
+	require.NoError(t, registry.SetConfig(map[string]interface{}{
+		"maxFindings":    10,
+		"severityLabels": map[string]interface{}{"error": "P1"},
+		"checks": map[string]interface{}{
+			"configurable": map[string]interface{}{
+				"registries": []interface{}{map[string]interface{}{"url": "registry.example.com", "Token": "secret"}},
+			},
+		},
+	}))
+
+	var buf bytes.Buffer
+	registry.SetDecisionLog(&buf, DecisionLogOpts{RedactConfigKeys: []string{"token"}})
+
+	ctx := ContextWithActor(context.Background(), "jane")
+
+	_, err = registry.Run(ctx, graph)
+	require.Error(t, err)
+	_, err = registry.RunPhase(ctx, graph, PhasePostApply)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+
+	var record DecisionRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+
+	require.False(t, record.Time.IsZero())
+	require.Equal(t, "jane", record.Actor)
+	require.Equal(t, PhasePreApply, record.Phase)
+	require.Equal(t, CheckStatusFailed, record.Outcome)
+	require.Equal(t, `preflight check "failing" reported 1 error(s)`, record.Error)
+	require.Equal(t, map[string]interface{}{
+		"maxFindings":    float64(10),
+		"severityLabels": map[string]interface{}{"error": "P1"},
+		"checks": map[string]interface{}{
+			"configurable": map[string]interface{}{
+				"registries": []interface{}{map[string]interface{}{"url": "registry.example.com", "Token": "<redacted>"}},
+			},
+		},
+	}, record.Config)
+	require.Equal(t, []DecisionCheck{
+		{Name: "configurable", Status: CheckStatusPassed},
+		{Name: "failing", Status: CheckStatusFailed,
+			Findings: []Finding{{Check: "failing", Severity: SeverityError, SeverityLabel: "P1", Message: "error"}}},
+		{Name: "passing", Status: CheckStatusPassed},
+		{Name: "skipped", Status: CheckStatusSkipped, SkipReason: "no resources of kind Service in the change"},
+	}, record.Checks)
+
+	// Registry config is not affected by redaction
+	require.Equal(t, "secret", registry.RawConfig()["checks"].(map[string]interface{})["configurable"].(map[string]interface{})["registries"].([]interface{})[0].(map[string]interface{})["Token"])
+
+	record = DecisionRecord{}
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &record))
+	require.Equal(t, PhasePostApply, record.Phase)
+	require.Equal(t, CheckStatusPassed, record.Outcome)
+	require.Equal(t, []DecisionCheck{}, record.Checks)
+
+	t.Run("redacted keys of deploy context, values replaced", func(t *testing.T) {
+		registry := &Registry{}
+		registry.AddCheck("passing", newCheck(nil))
+
+		require.NoError(t, registry.SetConfig(map[string]interface{}{
+			"context": map[string]interface{}{"env": "prod", "apiToken": "config-secret"},
+		}))
+		require.NoError(t, registry.SetDeployContext(map[string]string{"Token": "flag-secret"}))
+
+		var buf bytes.Buffer
+		registry.SetDecisionLog(&buf, DecisionLogOpts{RedactConfigKeys: []string{"token", "apitoken"}})
+
+		_, err := registry.Run(context.Background(), graph)
+		require.NoError(t, err)
+
+		var record DecisionRecord
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+
+		require.Equal(t, DeployContext{"env": "prod", "apiToken": "<redacted>", "Token": "<redacted>"}, record.Context)
+		require.Equal(t, map[string]interface{}{"env": "prod", "apiToken": "<redacted>"}, record.Config["context"])
+
+		// Checks still see values of redacted keys
+		require.Equal(t, "flag-secret", registry.DeployContext()["Token"])
+	})
+
+	t.Run("failing to write record, error returned", func(t *testing.T) {
+		registry := &Registry{}
+		registry.AddCheck("passing", newCheck(nil))
+		registry.SetDecisionLog(failingWriter{}, DecisionLogOpts{})
+
+		_, err := registry.Run(context.Background(), graph)
+		require.EqualError(t, err, "writing preflight decision log: disk full")
+	})
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
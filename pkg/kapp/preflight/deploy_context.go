@@ -0,0 +1,139 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	preflightContextFlag = "preflight-context"
+
+	configContextKey = "context"
+)
+
+// DeployContext describes the context of a deploy beyond the
+// change graph (e.g. env, region or team) so that checks can
+// vary their decisions by it without separate configs.
+type DeployContext map[string]string
+
+type deployContextCtxKey struct{}
+
+// DeployContextFromContext returns deploy context available to the
+// check running with the context. Context is provided via 'context'
+// key of the preflight config, SetDeployContext (e.g. via
+// --preflight-context flag) or both, in which case values set via
+// SetDeployContext take precedence over config values of same keys.
+// Returns an empty (non-nil) DeployContext if none was provided.
+// Returned value is a copy that can be modified by the check.
+func DeployContextFromContext(ctx context.Context) DeployContext {
+	result := DeployContext{}
+	if deployContext, ok := ctx.Value(deployContextCtxKey{}).(DeployContext); ok {
+		for k, v := range deployContext {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// SetDeployContext sets values of deploy context available to checks
+// (see DeployContextFromContext). Values are merged with previously
+// set ones; setting a key to an empty value removes it.
+func (c *Registry) SetDeployContext(deployContext map[string]string) error {
+	for key := range deployContext {
+		if len(strings.TrimSpace(key)) == 0 {
+			return fmt.Errorf("expected preflight context keys to be non-empty")
+		}
+	}
+
+	if c.deployContext == nil {
+		c.deployContext = DeployContext{}
+	}
+	for key, val := range deployContext {
+		if len(val) == 0 {
+			delete(c.deployContext, key)
+		} else {
+			c.deployContext[key] = val
+		}
+	}
+	return nil
+}
+
+// DeployContext returns effective deploy context (config
+// values overridden by values set via SetDeployContext)
+func (c *Registry) DeployContext() DeployContext {
+	result := DeployContext{}
+	for k, v := range c.configDeployContext {
+		result[k] = v
+	}
+	for k, v := range c.deployContext {
+		result[k] = v
+	}
+	return result
+}
+
+// contextWithDeployContext records deploy context in the context
+// (context is returned unchanged if deploy context is empty)
+func (c *Registry) contextWithDeployContext(ctx context.Context) context.Context {
+	deployContext := c.DeployContext()
+	if len(deployContext) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, deployContextCtxKey{}, deployContext)
+}
+
+func parseDeployContext(val interface{}) (DeployContext, error) {
+	if val == nil {
+		return nil, nil
+	}
+
+	values, ok := val.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected preflight config key %q to be a map", configContextKey)
+	}
+
+	result := DeployContext{}
+
+	for key, val := range values {
+		switch typedVal := val.(type) {
+		case string:
+			result[key] = typedVal
+		case bool, float64, int, int64:
+			// Unquoted YAML scalars (e.g. prod: true) are converted to strings
+			result[key] = fmt.Sprintf("%v", typedVal)
+		default:
+			return nil, fmt.Errorf("expected %s.%s to be a string", configContextKey, key)
+		}
+	}
+
+	return result, nil
+}
+
+// deployContextFlag is a pflag.Value that sets
+// deploy context of the registry from key=value
+// pairs (flag may be specified multiple times)
+type deployContextFlag struct {
+	registry *Registry
+	values   []string
+}
+
+func (f *deployContextFlag) String() string { return strings.Join(f.values, ",") }
+func (f *deployContextFlag) Type() string   { return "key=value" }
+
+func (f *deployContextFlag) Set(value string) error {
+	key, val, found := strings.Cut(value, "=")
+	if !found {
+		return fmt.Errorf("expected preflight context to be in format key=value, but was %q", value)
+	}
+
+	err := f.registry.SetDeployContext(map[string]string{key: val})
+	if err != nil {
+		return err
+	}
+
+	f.values = append(f.values, value)
+	return nil
+}
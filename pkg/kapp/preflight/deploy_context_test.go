@@ -0,0 +1,63 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/logger"
+)
+
+func TestRegistryDeployContext(t *testing.T) {
+	graph, err := diffgraph.NewChangeGraph(nil, nil, nil, logger.NewTODOLogger())
+	require.NoError(t, err)
+
+	var seen DeployContext
+
+	registry := &Registry{}
+	registry.AddCheck("check", NewCheck(func(ctx context.Context, _ *diffgraph.ChangeGraph) error {
+		seen = DeployContextFromContext(ctx)
+		return nil
+	}, true))
+
+	_, err = registry.Run(context.Background(), graph)
+	require.NoError(t, err)
+	require.Equal(t, DeployContext{}, seen)
+
+	flag := &deployContextFlag{registry: registry}
+	require.NoError(t, flag.Set("env=prod"))
+	require.NoError(t, flag.Set("team=payments=core"))
+	require.Equal(t, "env=prod,team=payments=core", flag.String())
+
+	require.NoError(t, registry.SetConfig(map[string]interface{}{
+		"context": map[string]interface{}{"env": "staging", "region": "eu-west-1", "pci": true},
+	}))
+
+	_, err = registry.Run(context.Background(), graph)
+	require.NoError(t, err)
+	// Values set via flag take precedence over config
+	require.Equal(t, DeployContext{"env": "prod", "team": "payments=core", "region": "eu-west-1", "pci": "true"}, seen)
+
+	// Empty value removes key so that config value applies
+	require.NoError(t, flag.Set("env="))
+	_, err = registry.Audit(context.Background(), graph)
+	require.NoError(t, err)
+	require.Equal(t, "staging", seen["env"])
+
+	t.Run("invalid flag value, error returned", func(t *testing.T) {
+		flag := &deployContextFlag{registry: &Registry{}}
+		require.EqualError(t, flag.Set("env"), `expected preflight context to be in format key=value, but was "env"`)
+		require.EqualError(t, flag.Set("=prod"), "expected preflight context keys to be non-empty")
+	})
+
+	t.Run("invalid config value, error returned", func(t *testing.T) {
+		err := (&Registry{}).SetConfig(map[string]interface{}{
+			"context": map[string]interface{}{"regions": []interface{}{"eu"}},
+		})
+		require.EqualError(t, err, "expected context.regions to be a string")
+	})
+}
@@ -0,0 +1,71 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"fmt"
+
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+// DiffNotes associates findings with resources they are about
+// so that they can be shown next to those resources in diff output
+// (see clusterapply.ChangeSetView). Findings are matched by
+// resource description which identifies resources in the change.
+type DiffNotes struct {
+	byResource map[string][]Finding
+	unattached []Finding
+	labels     SeverityLabels
+}
+
+// NewDiffNotes groups findings by their resource. Findings
+// without a resource are available via Unattached. Findings are
+// omitted the same way HumanRenderer omits them: info findings
+// if opts.Quiet is set and all findings if opts.SummaryOnly is set.
+func NewDiffNotes(findings []Finding, opts HumanRendererOpts) DiffNotes {
+	notes := DiffNotes{byResource: map[string][]Finding{}, labels: opts.SeverityLabels}
+
+	if opts.SummaryOnly {
+		return notes
+	}
+
+	for _, finding := range findings {
+		if opts.Quiet && finding.Severity == SeverityInfo {
+			continue
+		}
+		if len(finding.Resource) == 0 {
+			notes.unattached = append(notes.unattached, finding)
+			continue
+		}
+		notes.byResource[finding.Resource] = append(notes.byResource[finding.Resource], finding)
+	}
+
+	return notes
+}
+
+// Findings returns findings about the resource in the order they were reported
+func (n DiffNotes) Findings(res ctlres.Resource) []Finding {
+	return n.byResource[res.Description()]
+}
+
+// Unattached returns findings that are not about a particular resource
+func (n DiffNotes) Unattached() []Finding {
+	return n.unattached
+}
+
+// Notes returns single line notes (severity label, message
+// and reporting check) about the resource
+func (n DiffNotes) Notes(res ctlres.Resource) []string {
+	var result []string
+
+	for _, finding := range n.Findings(res) {
+		note := fmt.Sprintf("%s: %s", n.labels.Label(finding.Severity), finding.Message)
+		if len(finding.Check) > 0 {
+			note += fmt.Sprintf(" (%s)", finding.Check)
+		}
+		result = append(result, note)
+	}
+
+	return result
+}
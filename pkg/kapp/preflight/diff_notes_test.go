@@ -0,0 +1,66 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+func TestDiffNotes(t *testing.T) {
+	deployment := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: default
+`))
+	service := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+  namespace: default
+`))
+	other := ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+  namespace: default
+`))
+
+	first := NewWarning(deployment, "first")
+	first.Check = "CheckA"
+	second := NewError(deployment, "second")
+	global := NewInfo(nil, "global")
+	svc := NewInfo(service, "svc")
+	svc.Check = "CheckB"
+
+	findings := []Finding{first, global, second, svc}
+
+	notes := NewDiffNotes(findings, HumanRendererOpts{SeverityLabels: SeverityLabels{SeverityError: "P0"}})
+
+	require.Equal(t, []Finding{first, second}, notes.Findings(deployment))
+	require.Equal(t, []string{"Warning: first (CheckA)", "P0: second"}, notes.Notes(deployment))
+	require.Equal(t, []string{"Info: svc (CheckB)"}, notes.Notes(service))
+	require.Empty(t, notes.Notes(other))
+	require.Equal(t, []Finding{global}, notes.Unattached())
+
+	t.Run("quiet, info findings omitted", func(t *testing.T) {
+		notes := NewDiffNotes(findings, HumanRendererOpts{Quiet: true})
+		require.Equal(t, []string{"Warning: first (CheckA)", "Error: second"}, notes.Notes(deployment))
+		require.Empty(t, notes.Notes(service))
+		require.Empty(t, notes.Unattached())
+	})
+
+	t.Run("summary only, all findings omitted", func(t *testing.T) {
+		notes := NewDiffNotes(findings, HumanRendererOpts{SummaryOnly: true})
+		require.Empty(t, notes.Notes(deployment))
+		require.Empty(t, notes.Notes(service))
+		require.Empty(t, notes.Unattached())
+	})
+}
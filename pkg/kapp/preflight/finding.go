@@ -0,0 +1,192 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+// Severity indicates how a Finding affects the outcome
+// of the preflight checks
+type Severity string
+
+const (
+	// SeverityInfo findings are informational notes
+	// that do not indicate a problem
+	SeverityInfo Severity = "info"
+	// SeverityWarning findings are reported to the user
+	// but do not fail the preflight checks
+	SeverityWarning Severity = "warning"
+	// SeverityError findings fail the preflight checks
+	SeverityError Severity = "error"
+)
+
+// Finding describes a single issue reported by a
+// preflight check. Findings implement the error interface
+// so that checks can return them (optionally combined
+// with errors.Join) from their Run method.
+type Finding struct {
+	// Check is the name of the preflight check that
+	// reported the finding. It is set by the Registry.
+	Check    string   `json:"check,omitempty"`
+	Severity Severity `json:"severity"`
+	// Resource is the description of the resource
+	// the finding is about, if any
+	Resource string `json:"resource,omitempty"`
+	Message  string `json:"message"`
+	// Metadata holds additional structured information for
+	// consumers such as integrations with other tools. Keys are
+	// not restricted, however Metadata* keys are recommended.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// SeverityLabel is the label of the severity shown in output.
+	// It is only set via SeverityLabels.Apply (e.g. for findings
+	// recorded in the decision log).
+	SeverityLabel string `json:"severityLabel,omitempty"`
+	// ExpiresAt is set by the Registry for findings of checks
+	// registered with CheckOpts.FindingsTTL. Consumers caching
+	// findings or comparing them with prior reports should treat
+	// expired findings as stale and re-run the check instead of
+	// reusing them. Findings without ExpiresAt remain valid as
+	// long as the change they were reported for does not change.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Recommended keys of Finding metadata
+const (
+	// MetadataCategory is the kind of problem
+	// (e.g. security, reliability, cost)
+	MetadataCategory = "category"
+	// MetadataCWE is the CWE identifier (e.g. CWE-250)
+	// of a security related finding
+	MetadataCWE = "cwe"
+	// MetadataResourceUID is the UID of the
+	// resource the finding is about
+	MetadataResourceUID = "resourceUID"
+	// MetadataDocsURL links to documentation
+	// explaining how to address the finding
+	MetadataDocsURL = "docsURL"
+)
+
+// NewInfo returns a Finding with SeverityInfo
+// for the provided resource. The resource may be nil.
+func NewInfo(res ctlres.Resource, format string, args ...interface{}) Finding {
+	return newFinding(SeverityInfo, res, format, args...)
+}
+
+// NewWarning returns a Finding with SeverityWarning
+// for the provided resource. The resource may be nil.
+func NewWarning(res ctlres.Resource, format string, args ...interface{}) Finding {
+	return newFinding(SeverityWarning, res, format, args...)
+}
+
+// NewError returns a Finding with SeverityError
+// for the provided resource. The resource may be nil.
+func NewError(res ctlres.Resource, format string, args ...interface{}) Finding {
+	return newFinding(SeverityError, res, format, args...)
+}
+
+// NewSeverity parses a severity name
+func NewSeverity(s string) (Severity, error) {
+	switch severity := Severity(s); severity {
+	case SeverityInfo, SeverityWarning, SeverityError:
+		return severity, nil
+	default:
+		return "", fmt.Errorf("Unknown severity %q (expected one of: info, warning, error)", s)
+	}
+}
+
+// AtLeast returns true if severity s is at least as severe as other
+func (s Severity) AtLeast(other Severity) bool {
+	return s.rank() >= other.rank()
+}
+
+// rank orders severities from least (info) to most severe (error)
+func (s Severity) rank() int {
+	switch s {
+	case SeverityInfo:
+		return 0
+	case SeverityError:
+		return 2
+	default:
+		return 1
+	}
+}
+
+func newFinding(severity Severity, res ctlres.Resource, format string, args ...interface{}) Finding {
+	finding := Finding{Severity: severity, Message: fmt.Sprintf(format, args...)}
+	if res != nil {
+		finding.Resource = res.Description()
+	}
+	return finding
+}
+
+// CountAtLeast returns number of findings with severity
+// at least as severe as the provided one
+func CountAtLeast(findings []Finding, severity Severity) int {
+	var result int
+	for _, finding := range findings {
+		if finding.Severity.AtLeast(severity) {
+			result++
+		}
+	}
+	return result
+}
+
+// Expired returns true if the finding is only valid until
+// a time (see ExpiresAt) that is not after now
+func (f Finding) Expired(now time.Time) bool {
+	return f.ExpiresAt != nil && !now.Before(*f.ExpiresAt)
+}
+
+// WithMetadata returns a copy of the finding with
+// the metadata key set. The finding itself is not modified.
+func (f Finding) WithMetadata(key, value string) Finding {
+	metadata := make(map[string]string, len(f.Metadata)+1)
+	for k, v := range f.Metadata {
+		metadata[k] = v
+	}
+	metadata[key] = value
+
+	f.Metadata = metadata
+	return f
+}
+
+func (f Finding) Error() string {
+	if len(f.Resource) == 0 {
+		return f.Message
+	}
+	return fmt.Sprintf("%s: %s", f.Resource, f.Message)
+}
+
+// SplitFindings separates findings from the provided error.
+// Joined errors are inspected individually. Returned error
+// is nil if err only contained findings.
+func SplitFindings(err error) ([]Finding, error) {
+	if err == nil {
+		return nil, nil
+	}
+
+	if joinedErr, ok := err.(interface{ Unwrap() []error }); ok {
+		var findings []Finding
+		var errs []error
+		for _, e := range joinedErr.Unwrap() {
+			fs, e := SplitFindings(e)
+			findings = append(findings, fs...)
+			if e != nil {
+				errs = append(errs, e)
+			}
+		}
+		return findings, errors.Join(errs...)
+	}
+
+	var finding Finding
+	if errors.As(err, &finding) {
+		return []Finding{finding}, nil
+	}
+	return nil, err
+}
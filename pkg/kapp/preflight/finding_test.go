@@ -0,0 +1,54 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSeverity(t *testing.T) {
+	for _, s := range []string{"info", "warning", "error"} {
+		severity, err := NewSeverity(s)
+		require.NoError(t, err)
+		require.Equal(t, Severity(s), severity)
+	}
+
+	_, err := NewSeverity("critical")
+	require.EqualError(t, err, `Unknown severity "critical" (expected one of: info, warning, error)`)
+}
+
+func TestCountAtLeast(t *testing.T) {
+	findings := []Finding{
+		NewInfo(nil, "note"),
+		NewWarning(nil, "first"),
+		NewWarning(nil, "second"),
+		NewError(nil, "failure"),
+	}
+
+	require.Equal(t, 4, CountAtLeast(findings, SeverityInfo))
+	require.Equal(t, 3, CountAtLeast(findings, SeverityWarning))
+	require.Equal(t, 1, CountAtLeast(findings, SeverityError))
+	require.Equal(t, 0, CountAtLeast(nil, SeverityInfo))
+}
+
+func TestFindingWithMetadata(t *testing.T) {
+	finding := NewWarning(nil, "runs as root").WithMetadata(MetadataCategory, "security")
+	withCWE := finding.WithMetadata(MetadataCWE, "CWE-250")
+
+	require.Equal(t, map[string]string{"category": "security"}, finding.Metadata)
+	require.Equal(t, map[string]string{"category": "security", "cwe": "CWE-250"}, withCWE.Metadata)
+
+	bs, err := json.Marshal(withCWE)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"severity":"warning","message":"runs as root","metadata":{"category":"security","cwe":"CWE-250"}}`, string(bs))
+
+	// Metadata is preserved when findings are returned as errors
+	findings, err := SplitFindings(errors.Join(withCWE))
+	require.NoError(t, err)
+	require.Equal(t, []Finding{withCWE}, findings)
+}
@@ -0,0 +1,71 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"fmt"
+	"os"
+
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	GraphDumpKind = "PreflightChangeGraph"
+)
+
+// GraphDump is a machine and human readable representation
+// of a ChangeGraph as seen by preflight checks
+type GraphDump struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Changes    []GraphDumpChange `json:"changes"`
+}
+
+// GraphDumpChange describes a single change (node) and
+// changes it is waiting for (edges) in a ChangeGraph.
+// Changes are identified by descriptions of their resources.
+type GraphDumpChange struct {
+	Resource   string   `json:"resource"`
+	Op         string   `json:"op"`
+	WaitingFor []string `json:"waitingFor,omitempty"`
+}
+
+// NewGraphDump returns a GraphDump of all changes in
+// the change graph (in the same order as they are in the graph)
+func NewGraphDump(changeGraph *ctldgraph.ChangeGraph) GraphDump {
+	dump := GraphDump{
+		APIVersion: CatalogAPIVersion,
+		Kind:       GraphDumpKind,
+		Changes:    []GraphDumpChange{},
+	}
+
+	for _, change := range changeGraph.All() {
+		dumpChange := GraphDumpChange{
+			Resource: change.Change.Resource().Description(),
+			Op:       string(change.Change.Op()),
+		}
+		for _, waitingFor := range change.WaitingFor {
+			dumpChange.WaitingFor = append(dumpChange.WaitingFor, waitingFor.Change.Resource().Description())
+		}
+		dump.Changes = append(dump.Changes, dumpChange)
+	}
+
+	return dump
+}
+
+// WriteGraphDump writes a GraphDump of the change graph as YAML to path
+func WriteGraphDump(path string, changeGraph *ctldgraph.ChangeGraph) error {
+	bs, err := yaml.Marshal(NewGraphDump(changeGraph))
+	if err != nil {
+		return fmt.Errorf("marshaling preflight change graph: %w", err)
+	}
+
+	err = os.WriteFile(path, bs, 0600)
+	if err != nil {
+		return fmt.Errorf("writing preflight change graph: %w", err)
+	}
+
+	return nil
+}
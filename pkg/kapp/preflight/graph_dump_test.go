@@ -0,0 +1,55 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/logger"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+func TestWriteGraphDump(t *testing.T) {
+	graph, err := diffgraph.NewChangeGraph([]diffgraph.ActualChange{
+		fakeChange{res: ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: default
+  annotations:
+    kapp.k14s.io/change-rule: "upsert after upserting config"
+`))},
+		fakeChange{res: ctlres.MustNewResourceFromBytes([]byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: default
+  annotations:
+    kapp.k14s.io/change-group: config
+`))},
+	}, nil, nil, logger.NewTODOLogger())
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "graph.yml")
+	require.NoError(t, WriteGraphDump(path, graph))
+
+	bs, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, `apiVersion: preflight.kapp.k14s.io/v1alpha1
+changes:
+- op: upsert
+  resource: 'deployment/app (apps/v1) namespace: default'
+  waitingFor:
+  - 'configmap/config (v1) namespace: default'
+- op: upsert
+  resource: 'configmap/config (v1) namespace: default'
+kind: PreflightChangeGraph
+`, string(bs))
+}
@@ -0,0 +1,194 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cppforlife/color"
+	"github.com/mitchellh/go-wordwrap"
+)
+
+// ColorMode controls whether HumanRenderer colors its output
+type ColorMode string
+
+const (
+	// ColorModeAuto colors output only when stdout is a terminal
+	// and the NO_COLOR environment variable is not set
+	ColorModeAuto   ColorMode = "auto"
+	ColorModeAlways ColorMode = "always"
+	ColorModeNever  ColorMode = "never"
+)
+
+// NewColorMode parses s into a ColorMode
+func NewColorMode(s string) (ColorMode, error) {
+	switch mode := ColorMode(s); mode {
+	case ColorModeAuto, ColorModeAlways, ColorModeNever:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("Unknown color mode %q (expected one of: auto, always, never)", s)
+	}
+}
+
+func (m ColorMode) enabled() bool {
+	switch m {
+	case ColorModeAlways:
+		return true
+	case ColorModeNever:
+		return false
+	default:
+		// See https://no-color.org
+		if len(os.Getenv("NO_COLOR")) > 0 {
+			return false
+		}
+		return !color.NoColor
+	}
+}
+
+type HumanRendererOpts struct {
+	Color ColorMode
+	// Width is the maximum line width of the output.
+	// Zero or negative value disables wrapping.
+	Width int
+	// SummaryOnly renders only number of findings
+	// by severity instead of individual findings
+	SummaryOnly bool
+	// Quiet omits findings with SeverityInfo
+	Quiet bool
+	// SeverityLabels customize labels of severities
+	// (nil means default labels)
+	SeverityLabels SeverityLabels
+}
+
+// HumanRenderer renders findings reported by preflight
+// checks in a human readable format
+type HumanRenderer struct {
+	opts HumanRendererOpts
+}
+
+func NewHumanRenderer(opts HumanRendererOpts) HumanRenderer {
+	return HumanRenderer{opts}
+}
+
+const humanRendererIndent = "  "
+
+// Render returns findings formatted one per line (wrapped
+// to the configured width) with a severity prefix
+func (r HumanRenderer) Render(findings []Finding) string {
+	findings = r.visible(findings)
+
+	if r.opts.SummaryOnly {
+		return r.RenderSummary(findings)
+	}
+
+	var lines []string
+
+	for _, finding := range findings {
+		prefix := r.severityPrefix(finding.Severity)
+		text := fmt.Sprintf("%s: %s", finding.Check, finding.Error())
+
+		if r.opts.Width > 0 {
+			// Prefix is colored last so that escape codes do not affect wrapping
+			wrapped := wordwrap.WrapString(prefix+" "+text, uint(r.opts.Width-len(humanRendererIndent)))
+			wrapped = strings.TrimPrefix(wrapped, prefix)
+			text = strings.TrimPrefix(strings.ReplaceAll(wrapped, "\n", "\n"+humanRendererIndent), " ")
+		}
+
+		lines = append(lines, r.colored(finding.Severity, prefix)+" "+text)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// RenderSummary returns a single line with the overall
+// status and number of findings by severity
+func (r HumanRenderer) RenderSummary(findings []Finding) string {
+	var numErrors, numWarnings, numInfos int
+
+	for _, finding := range r.visible(findings) {
+		switch finding.Severity {
+		case SeverityError:
+			numErrors++
+		case SeverityInfo:
+			numInfos++
+		default:
+			numWarnings++
+		}
+	}
+
+	status := color.New(color.FgGreen)
+	statusText := "passed"
+	if numErrors > 0 {
+		status = color.New(color.FgRed)
+		statusText = "failed"
+	}
+
+	if len(r.opts.SeverityLabels) > 0 {
+		counts := []string{
+			fmt.Sprintf("%d %s", numErrors, r.opts.SeverityLabels.Label(SeverityError)),
+			fmt.Sprintf("%d %s", numWarnings, r.opts.SeverityLabels.Label(SeverityWarning)),
+		}
+		if numInfos > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", numInfos, r.opts.SeverityLabels.Label(SeverityInfo)))
+		}
+		return fmt.Sprintf("Preflight checks %s: %s", r.colorize(status, statusText), strings.Join(counts, ", "))
+	}
+
+	summary := fmt.Sprintf("Preflight checks %s: %d error(s), %d warning(s)",
+		r.colorize(status, statusText), numErrors, numWarnings)
+	if numInfos > 0 {
+		summary += fmt.Sprintf(", %d info", numInfos)
+	}
+	return summary
+}
+
+func (r HumanRenderer) visible(findings []Finding) []Finding {
+	if !r.opts.Quiet {
+		return findings
+	}
+
+	var result []Finding
+	for _, finding := range findings {
+		if finding.Severity != SeverityInfo {
+			result = append(result, finding)
+		}
+	}
+	return result
+}
+
+func (r HumanRenderer) severityPrefix(severity Severity) string {
+	switch severity {
+	case SeverityError, SeverityInfo:
+		return r.opts.SeverityLabels.Label(severity) + ":"
+	default:
+		return r.opts.SeverityLabels.Label(SeverityWarning) + ":"
+	}
+}
+
+func (r HumanRenderer) colored(severity Severity, str string) string {
+	var c *color.Color
+
+	switch severity {
+	case SeverityError:
+		c = color.New(color.FgRed)
+	case SeverityInfo:
+		c = color.New(color.FgCyan)
+	default:
+		c = color.New(color.FgYellow)
+	}
+
+	return r.colorize(c, str)
+}
+
+func (r HumanRenderer) colorize(c *color.Color, str string) string {
+	if r.opts.Color.enabled() {
+		c.EnableColor()
+	} else {
+		c.DisableColor()
+	}
+
+	return c.Sprint(str)
+}
@@ -0,0 +1,168 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+package preflight
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHumanRendererRender(t *testing.T) {
+	findings := []Finding{
+		{Check: "someCheck", Severity: SeverityWarning, Resource: "deployment/app (apps/v1) namespace: default", Message: "something might go wrong here"},
+		{Check: "otherCheck", Severity: SeverityError, Message: "something went wrong"},
+	}
+
+	testCases := []struct {
+		name     string
+		opts     HumanRendererOpts
+		expected string
+	}{
+		{
+			name: "color disabled, no wrapping",
+			opts: HumanRendererOpts{Color: ColorModeNever},
+			expected: `
+Warning: someCheck: deployment/app (apps/v1) namespace: default: something might go wrong here
+Error: otherCheck: something went wrong`,
+		},
+		{
+			name: "color disabled, wrapped to width",
+			opts: HumanRendererOpts{Color: ColorModeNever, Width: 40},
+			expected: `
+Warning: someCheck: deployment/app
+  (apps/v1) namespace: default:
+  something might go wrong here
+Error: otherCheck: something went
+  wrong`,
+		},
+		{
+			name: "color enabled, wrapping ignores escape codes",
+			opts: HumanRendererOpts{Color: ColorModeAlways, Width: 40},
+			expected: `
+` + "\x1b[33mWarning:\x1b[0m" + ` someCheck: deployment/app
+  (apps/v1) namespace: default:
+  something might go wrong here
+` + "\x1b[31mError:\x1b[0m" + ` otherCheck: something went
+  wrong`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output := NewHumanRenderer(tc.opts).Render(findings)
+			require.Equal(t, strings.TrimPrefix(tc.expected, "\n"), output)
+		})
+	}
+}
+
+func TestHumanRendererInfo(t *testing.T) {
+	findings := []Finding{
+		{Check: "someCheck", Severity: SeverityInfo, Message: "this change creates 3 namespaces"},
+		{Check: "otherCheck", Severity: SeverityWarning, Message: "something might go wrong"},
+	}
+
+	testCases := []struct {
+		name     string
+		opts     HumanRendererOpts
+		expected string
+	}{
+		{
+			name: "info findings are rendered with their own prefix",
+			opts: HumanRendererOpts{Color: ColorModeAlways},
+			expected: "\x1b[36mInfo:\x1b[0m someCheck: this change creates 3 namespaces\n" +
+				"\x1b[33mWarning:\x1b[0m otherCheck: something might go wrong",
+		},
+		{
+			name:     "info findings are omitted in quiet mode",
+			opts:     HumanRendererOpts{Color: ColorModeNever, Quiet: true},
+			expected: "Warning: otherCheck: something might go wrong",
+		},
+		{
+			name:     "info findings are counted in summary",
+			opts:     HumanRendererOpts{Color: ColorModeNever, SummaryOnly: true},
+			expected: "Preflight checks passed: 0 error(s), 1 warning(s), 1 info",
+		},
+		{
+			name:     "info findings are not counted in quiet summary",
+			opts:     HumanRendererOpts{Color: ColorModeNever, SummaryOnly: true, Quiet: true},
+			expected: "Preflight checks passed: 0 error(s), 1 warning(s)",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, NewHumanRenderer(tc.opts).Render(findings))
+		})
+	}
+}
+
+func TestHumanRendererSummaryOnly(t *testing.T) {
+	testCases := []struct {
+		name     string
+		findings []Finding
+		opts     HumanRendererOpts
+		expected string
+	}{
+		{
+			name:     "no findings",
+			opts:     HumanRendererOpts{Color: ColorModeNever, SummaryOnly: true},
+			expected: "Preflight checks passed: 0 error(s), 0 warning(s)",
+		},
+		{
+			name: "only warnings, individual findings are not rendered",
+			findings: []Finding{
+				{Check: "someCheck", Severity: SeverityWarning, Message: "first"},
+				{Check: "someCheck", Severity: SeverityWarning, Message: "second"},
+			},
+			opts:     HumanRendererOpts{Color: ColorModeNever, SummaryOnly: true, Width: 10},
+			expected: "Preflight checks passed: 0 error(s), 2 warning(s)",
+		},
+		{
+			name: "errors and warnings, colored",
+			findings: []Finding{
+				{Check: "someCheck", Severity: SeverityWarning, Message: "warning"},
+				{Check: "otherCheck", Severity: SeverityError, Message: "error"},
+			},
+			opts:     HumanRendererOpts{Color: ColorModeAlways, SummaryOnly: true},
+			expected: "Preflight checks \x1b[31mfailed\x1b[0m: 1 error(s), 1 warning(s)",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, NewHumanRenderer(tc.opts).Render(tc.findings))
+		})
+	}
+}
+
+func TestHumanRendererSeverityLabels(t *testing.T) {
+	findings := []Finding{
+		{Check: "someCheck", Severity: SeverityError, Message: "error"},
+		{Check: "otherCheck", Severity: SeverityWarning, Message: "warning"},
+		{Check: "otherCheck", Severity: SeverityInfo, Message: "info"},
+	}
+	labels := SeverityLabels{SeverityError: "P0", SeverityWarning: "P1"}
+
+	require.Equal(t, "P0: someCheck: error\nP1: otherCheck: warning\nInfo: otherCheck: info",
+		NewHumanRenderer(HumanRendererOpts{Color: ColorModeNever, SeverityLabels: labels}).Render(findings))
+	require.Equal(t, "Preflight checks failed: 1 P0, 1 P1, 1 Info",
+		NewHumanRenderer(HumanRendererOpts{Color: ColorModeNever, SummaryOnly: true, SeverityLabels: labels}).Render(findings))
+}
+
+func TestNewColorMode(t *testing.T) {
+	for _, valid := range []string{"auto", "always", "never"} {
+		mode, err := NewColorMode(valid)
+		require.NoError(t, err)
+		require.Equal(t, ColorMode(valid), mode)
+	}
+
+	_, err := NewColorMode("sometimes")
+	require.Error(t, err)
+}
+
+func TestColorModeAutoHonorsNoColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	require.False(t, ColorModeAuto.enabled())
+}
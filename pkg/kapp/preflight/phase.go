@@ -0,0 +1,38 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"context"
+)
+
+// Phase determines when preflight checks run relative to applying changes
+type Phase string
+
+const (
+	// PhasePreApply checks run before changes are applied
+	// and can prevent them from being applied
+	PhasePreApply Phase = "preApply"
+	// PhasePostApply checks run after changes were applied
+	// successfully to verify conditions that can only be observed
+	// in the cluster (e.g. resources becoming ready). Since changes
+	// are already applied, failures cannot prevent them and are
+	// reported for the user to act on.
+	PhasePostApply Phase = "postApply"
+)
+
+type phaseCtxKey struct{}
+
+// PhaseFromContext returns phase in which the preflight check
+// running with the context is executed (PhasePreApply by default)
+func PhaseFromContext(ctx context.Context) Phase {
+	if phase, ok := ctx.Value(phaseCtxKey{}).(Phase); ok {
+		return phase
+	}
+	return PhasePreApply
+}
+
+func contextWithPhase(ctx context.Context, phase Phase) context.Context {
+	return context.WithValue(ctx, phaseCtxKey{}, phase)
+}
@@ -0,0 +1,55 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+)
+
+func TestRegistryRunPhase(t *testing.T) {
+	var ran []string
+	newCheck := func(name string) Check {
+		return NewCheck(func(ctx context.Context, _ *diffgraph.ChangeGraph) error {
+			phase := PhaseFromContext(ctx)
+			ran = append(ran, name+"@"+string(phase))
+			if phase == PhasePostApply {
+				return errors.Join(NewError(nil, "not ready"))
+			}
+			return nil
+		}, true)
+	}
+
+	registry := &Registry{}
+	registry.AddCheck("pre", newCheck("pre"))
+	registry.AddCheckWithOpts("post", newCheck("post"), CheckOpts{PostApply: true})
+	registry.AddCheckWithOpts("disabled", NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error {
+		return errors.New("unexpected run")
+	}, false), CheckOpts{PostApply: true})
+
+	require.True(t, registry.HasPhaseChecks(PhasePostApply))
+
+	_, err := registry.Run(context.Background(), nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"post@preApply", "pre@preApply"}, ran)
+
+	ran = nil
+
+	findings, err := registry.RunPhase(context.Background(), nil, PhasePostApply)
+	require.EqualError(t, err, `preflight check "post" reported 1 error(s)`)
+	require.Equal(t, []string{"post@postApply"}, ran)
+	require.Equal(t, []Finding{{Check: "post", Severity: SeverityError, Message: "not ready"}}, findings)
+	require.Len(t, registry.Stats(), 1)
+
+	_, err = registry.RunPhase(context.Background(), nil, Phase("unknown"))
+	require.EqualError(t, err, `unknown preflight phase "unknown"`)
+
+	registry.known["post"].SetEnabled(false)
+	require.False(t, registry.HasPhaseChecks(PhasePostApply))
+	require.True(t, registry.HasPhaseChecks(PhasePreApply))
+}
@@ -0,0 +1,189 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	preflightPolicyDirFlag = "preflight-policy-dir"
+
+	policyCheckKey   = "check"
+	policyEnabledKey = "enabled"
+	policyConfigKey  = "config"
+)
+
+// policyFileExts are extensions of files loaded from a policy directory
+var policyFileExts = []string{".yml", ".yaml", ".json"}
+
+// policyDirFlag is a pflag.Value that loads policies from
+// files in a directory. Each file adds an instance of a check
+// registered via AddCheckFactory:
+//
+//	check: AllowedRegistries
+//	enabled: true
+//	config:
+//	  registries: [registry.example.com]
+//
+// The instance is named after the check and the file name without
+// extension (e.g. AllowedRegistries:prod for prod.yml) and is enabled
+// unless enabled is false. Only regular files with YAML or JSON
+// extensions directly in the directory are loaded (in order of
+// their names), hidden files are ignored. Rego policies are not
+// supported and result in an error rather than being ignored.
+type policyDirFlag struct {
+	registry *Registry
+	path     string
+}
+
+func (f *policyDirFlag) String() string { return f.path }
+func (f *policyDirFlag) Type() string   { return "string" }
+
+func (f *policyDirFlag) Set(path string) error {
+	policies, err := loadPolicyDir(path)
+	if err != nil {
+		return err
+	}
+
+	config := f.registry.RawConfig()
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+
+	err = f.registry.SetConfig(mergeConfig(config, policiesConfig(policies)))
+	if err != nil {
+		return fmt.Errorf("loading preflight policies from %q: %w", path, err)
+	}
+
+	if f.registry.policies == nil {
+		f.registry.policies = map[string]struct{}{}
+	}
+	for _, policy := range policies {
+		check, _ := f.registry.lookup(policy.name)
+		check.SetEnabled(policy.enabled)
+		f.registry.policies[policy.name] = struct{}{}
+	}
+	f.registry.policyConfig = mergeConfig(f.registry.policyConfig, policiesConfig(policies))
+
+	f.path = path
+	return nil
+}
+
+type policy struct {
+	// name is the name of the check instance
+	name    string
+	enabled bool
+	config  map[string]interface{}
+}
+
+// loadPolicyDir reads policies from files in the directory
+func loadPolicyDir(dir string) ([]policy, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading preflight policy dir: %w", err)
+	}
+
+	var policies []policy
+	files := map[string]string{}
+
+	// Entries are sorted by file name
+	for _, entry := range entries {
+		fileName := entry.Name()
+		ext := filepath.Ext(fileName)
+
+		if strings.HasPrefix(fileName, ".") || !entry.Type().IsRegular() {
+			continue
+		}
+		if ext == ".rego" {
+			return nil, fmt.Errorf("preflight policy %q: Rego policies are not supported", filepath.Join(dir, fileName))
+		}
+		if !isPolicyFileExt(ext) {
+			continue
+		}
+
+		policy, err := loadPolicyFile(filepath.Join(dir, fileName))
+		if err != nil {
+			return nil, err
+		}
+
+		if otherFileName, found := files[policy.name]; found {
+			return nil, fmt.Errorf("preflight policies %q and %q in %q define the same check instance %q",
+				otherFileName, fileName, dir, policy.name)
+		}
+		files[policy.name] = fileName
+
+		policies = append(policies, policy)
+	}
+
+	return policies, nil
+}
+
+func loadPolicyFile(path string) (policy, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return policy{}, fmt.Errorf("reading preflight policy: %w", err)
+	}
+
+	var doc map[string]interface{}
+
+	err = yaml.Unmarshal(bs, &doc)
+	if err != nil {
+		return policy{}, fmt.Errorf("parsing preflight policy %q: %w", path, err)
+	}
+
+	for key := range doc {
+		switch key {
+		case policyCheckKey, policyEnabledKey, policyConfigKey:
+		default:
+			return policy{}, fmt.Errorf("unknown key %q in preflight policy %q", key, path)
+		}
+	}
+
+	checkName, ok := doc[policyCheckKey].(string)
+	if !ok || len(checkName) == 0 || strings.Contains(checkName, checkInstanceSeparator) {
+		return policy{}, fmt.Errorf("expected key %q of preflight policy %q to be a name of a check", policyCheckKey, path)
+	}
+
+	enabled := true
+	if val, found := doc[policyEnabledKey]; found {
+		enabled, ok = val.(bool)
+		if !ok {
+			return policy{}, fmt.Errorf("expected key %q of preflight policy %q to be a boolean", policyEnabledKey, path)
+		}
+	}
+
+	config, ok := doc[policyConfigKey].(map[string]interface{})
+	if !ok && doc[policyConfigKey] != nil {
+		return policy{}, fmt.Errorf("expected key %q of preflight policy %q to be a map", policyConfigKey, path)
+	}
+
+	fileName := filepath.Base(path)
+	instanceName := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+
+	return policy{name: checkName + checkInstanceSeparator + instanceName, enabled: enabled, config: config}, nil
+}
+
+// policiesConfig returns registry configuration of policies
+func policiesConfig(policies []policy) map[string]interface{} {
+	checksConfig := map[string]interface{}{}
+	for _, policy := range policies {
+		checksConfig[policy.name] = copyWithoutKeys(policy.config)
+	}
+	return map[string]interface{}{configChecksKey: checksConfig}
+}
+
+func isPolicyFileExt(ext string) bool {
+	for _, policyExt := range policyFileExts {
+		if ext == policyExt {
+			return true
+		}
+	}
+	return false
+}
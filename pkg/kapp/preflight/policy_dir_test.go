@@ -0,0 +1,125 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyDirFlag(t *testing.T) {
+	writeFile := func(t *testing.T, path, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+
+	newPolicyRegistry := func() *Registry {
+		registry := NewRegistry(map[string]Check{})
+		registry.AddCheckFactory("Policy", func() Check {
+			check := newConfigurableCheck()
+			check.SetEnabled(false)
+			return check
+		}, CheckOpts{})
+		return registry
+	}
+
+	t.Run("policies are loaded as enabled and configured instances", func(t *testing.T) {
+		dir := t.TempDir()
+
+		writeFile(t, filepath.Join(dir, "prod.yml"), `
+check: Policy
+config:
+  minSeconds: 120
+`)
+		writeFile(t, filepath.Join(dir, "staging.json"), `{"check": "Policy", "enabled": false}`)
+		writeFile(t, filepath.Join(dir, ".hidden.yml"), "invalid: [")
+		writeFile(t, filepath.Join(dir, "README.md"), "# Policies")
+		writeFile(t, filepath.Join(dir, "nested", "dev.yml"), "check: Policy\n")
+
+		registry := newPolicyRegistry()
+
+		flag := &policyDirFlag{registry: registry}
+		require.NoError(t, flag.Set(dir))
+		require.Equal(t, dir, flag.String())
+
+		require.ElementsMatch(t, []string{"Policy", "Policy:prod", "Policy:staging"}, registry.names())
+		require.True(t, registry.known["Policy:prod"].Enabled())
+		require.False(t, registry.known["Policy:staging"].Enabled())
+		require.Equal(t, CheckConfig{"minSeconds": float64(120)}, registry.known["Policy:prod"].(*configurableCheck).config)
+
+		// Policies are not disabled when other checks are selected
+		require.NoError(t, registry.Set("Policy"))
+		require.True(t, registry.known["Policy"].Enabled())
+		require.True(t, registry.known["Policy:prod"].Enabled())
+
+		// Config file does not override policies
+		path := filepath.Join(t.TempDir(), "config.yml")
+		writeFile(t, path, `
+maxFindings: 5
+checks:
+  Policy:prod:
+    minSeconds: 60
+`)
+		require.NoError(t, (&configFileFlag{registry: registry}).Set(path))
+		require.Equal(t, 5, registry.maxFindings)
+		require.Equal(t, CheckConfig{"minSeconds": float64(120)}, registry.known["Policy:prod"].(*configurableCheck).config)
+	})
+
+	testCases := []struct {
+		name        string
+		files       map[string]string
+		expectedErr string
+	}{
+		{
+			name:        "malformed policy",
+			files:       map[string]string{"prod.yml": "check: ["},
+			expectedErr: "parsing preflight policy",
+		},
+		{
+			name:        "unknown key",
+			files:       map[string]string{"prod.yml": "check: Policy\nrules: []\n"},
+			expectedErr: `unknown key "rules" in preflight policy`,
+		},
+		{
+			name:        "missing check",
+			files:       map[string]string{"prod.yml": "config: {}\n"},
+			expectedErr: `expected key "check" of preflight policy`,
+		},
+		{
+			name:        "unknown check",
+			files:       map[string]string{"prod.yml": "check: Unknown\n"},
+			expectedErr: `unknown preflight check "Unknown:prod" specified in config`,
+		},
+		{
+			name:        "duplicate instance",
+			files:       map[string]string{"prod.yml": "check: Policy\n", "prod.yaml": "check: Policy\n"},
+			expectedErr: `preflight policies "prod.yaml" and "prod.yml"`,
+		},
+		{
+			name:        "rego policy",
+			files:       map[string]string{"prod.rego": "package kapp\n"},
+			expectedErr: "Rego policies are not supported",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name+", error returned", func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tc.files {
+				writeFile(t, filepath.Join(dir, name), content)
+			}
+
+			flag := &policyDirFlag{registry: newPolicyRegistry()}
+			require.ErrorContains(t, flag.Set(dir), tc.expectedErr)
+		})
+	}
+
+	t.Run("directory does not exist, error returned", func(t *testing.T) {
+		flag := &policyDirFlag{registry: newPolicyRegistry()}
+		require.ErrorContains(t, flag.Set(filepath.Join(t.TempDir(), "nonexistent")), "reading preflight policy dir")
+	})
+}
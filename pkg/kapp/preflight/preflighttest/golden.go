@@ -0,0 +1,82 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflighttest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+)
+
+// UpdateGoldenEnvVar is the environment variable that when set
+// to a non-empty value makes AssertGolden (re)write golden files
+const UpdateGoldenEnvVar = "PREFLIGHT_UPDATE_GOLDEN"
+
+// Snapshot returns a stable, human readable serialization of findings
+// suitable for golden files. Findings are sorted by check, resource,
+// severity and message. Each finding is rendered on a single line
+// (continuation lines of multi-line messages are indented).
+func Snapshot(findings []preflight.Finding) string {
+	sorted := append([]preflight.Finding{}, findings...)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		switch {
+		case a.Check != b.Check:
+			return a.Check < b.Check
+		case a.Resource != b.Resource:
+			return a.Resource < b.Resource
+		case a.Severity != b.Severity:
+			return a.Severity < b.Severity
+		default:
+			return a.Message < b.Message
+		}
+	})
+
+	var result strings.Builder
+
+	for _, finding := range sorted {
+		line := fmt.Sprintf("[%s]", finding.Severity)
+		if len(finding.Check) > 0 {
+			line += " " + finding.Check + ":"
+		}
+		line += " " + finding.Error()
+
+		result.WriteString(strings.ReplaceAll(strings.TrimRight(line, "\n"), "\n", "\n    "))
+		result.WriteString("\n")
+	}
+
+	return result.String()
+}
+
+// AssertGolden compares actual with contents of the golden file
+// at path and fails the test with a diff on mismatch. Golden file
+// is (re)written instead when UpdateGoldenEnvVar is set.
+func AssertGolden(t *testing.T, path string, actual string) {
+	t.Helper()
+
+	if len(os.Getenv(UpdateGoldenEnvVar)) > 0 {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		require.NoError(t, os.WriteFile(path, []byte(actual), 0600))
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	require.NoError(t, err, "Reading golden file (set %s=1 to create it)", UpdateGoldenEnvVar)
+
+	require.Equal(t, string(expected), actual,
+		"Golden file %s does not match (set %s=1 to update it)", path, UpdateGoldenEnvVar)
+}
+
+// AssertFindingsGolden compares Snapshot of findings with the golden file at path
+func AssertFindingsGolden(t *testing.T, path string, findings []preflight.Finding) {
+	t.Helper()
+	AssertGolden(t, path, Snapshot(findings))
+}
@@ -0,0 +1,44 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflighttest_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight/preflighttest"
+)
+
+func TestSnapshot(t *testing.T) {
+	findings := []preflight.Finding{
+		{Check: "b", Severity: preflight.SeverityWarning, Resource: "deployment/app", Message: "second"},
+		{Check: "b", Severity: preflight.SeverityWarning, Resource: "deployment/app", Message: "first"},
+		{Check: "a", Severity: preflight.SeverityError, Message: "multi\nline"},
+		{Severity: preflight.SeverityInfo, Resource: "service/app", Message: "note"},
+	}
+
+	require.Equal(t, `[info] service/app: note
+[error] a: multi
+    line
+[warning] b: deployment/app: first
+[warning] b: deployment/app: second
+`, preflighttest.Snapshot(findings))
+}
+
+func TestAssertGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "testdata", "findings.golden")
+
+	t.Setenv(preflighttest.UpdateGoldenEnvVar, "1")
+	preflighttest.AssertGolden(t, path, "content\n")
+
+	bs, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "content\n", string(bs))
+
+	t.Setenv(preflighttest.UpdateGoldenEnvVar, "")
+	preflighttest.AssertGolden(t, path, "content\n")
+}
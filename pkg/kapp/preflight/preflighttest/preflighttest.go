@@ -0,0 +1,76 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package preflighttest provides helpers for testing preflight checks.
+package preflighttest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/logger"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/preflight"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+)
+
+// Change describes a single change in a ChangeGraph built for tests
+type Change struct {
+	Res ctlres.Resource
+	// ExistingRes is the resource as currently found
+	// in the cluster (nil if resource does not exist)
+	ExistingRes ctlres.Resource
+	ChangeOp    ctldgraph.ActualChangeOp
+}
+
+var _ ctldgraph.ActualChange = Change{}
+
+func (c Change) Resource() ctlres.Resource                { return c.Res }
+func (c Change) ClusterOriginalResource() ctlres.Resource { return c.ExistingRes }
+func (c Change) Op() ctldgraph.ActualChangeOp             { return c.ChangeOp }
+
+// NewChangeGraph builds a ChangeGraph from provided changes
+func NewChangeGraph(t *testing.T, changes ...Change) *ctldgraph.ChangeGraph {
+	var actualChanges []ctldgraph.ActualChange
+	for _, change := range changes {
+		actualChanges = append(actualChanges, change)
+	}
+
+	graph, err := ctldgraph.NewChangeGraph(actualChanges, nil, nil, logger.NewTODOLogger())
+	require.NoError(t, err)
+
+	return graph
+}
+
+// RunCheckOnResources runs a single check against a ChangeGraph
+// in which all provided resources are upserted, and returns reported
+// findings. Test fails if check returns errors that are not findings.
+func RunCheckOnResources(t *testing.T, check preflight.Check, resources []ctlres.Resource) []preflight.Finding {
+	var changes []Change
+	for _, res := range resources {
+		changes = append(changes, Change{Res: res, ChangeOp: ctldgraph.ActualChangeOpUpsert})
+	}
+	return RunCheckOnChanges(t, check, changes...)
+}
+
+// RunCheckOnChanges is like RunCheckOnResources but allows
+// to specify operation and existing resource for each change.
+// Check field of returned findings is not set since it is
+// populated by the Registry.
+func RunCheckOnChanges(t *testing.T, check preflight.Check, changes ...Change) []preflight.Finding {
+	findings, err := preflight.SplitFindings(check.Run(context.Background(), NewChangeGraph(t, changes...)))
+	require.NoError(t, err)
+
+	return findings
+}
+
+// Messages returns descriptions (as returned by Error)
+// of provided findings for easier assertions
+func Messages(findings []preflight.Finding) []string {
+	var result []string
+	for _, finding := range findings {
+		result = append(result, finding.Error())
+	}
+	return result
+}
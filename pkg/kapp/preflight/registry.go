@@ -5,18 +5,71 @@ package preflight
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 	ctldgraph "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
-const preflightFlag = "preflight"
+const (
+	preflightFlag = "preflight"
+
+	// checkInstanceSeparator separates name of a check registered
+	// via AddCheckFactory from name of its instance (e.g. Check:instance)
+	checkInstanceSeparator = ":"
+)
 
 // Registry is a collection of preflight checks
 type Registry struct {
-	known map[string]Check
+	known     map[string]Check
+	opts      map[string]CheckOpts
+	factories map[string]CheckFactory
+	config    map[string]interface{}
+	// locked maps names of checks that cannot be
+	// disabled to the reason they are locked
+	locked map[string]string
+	// policies are names of check instances loaded from a policy
+	// directory, they keep enabled value from their policy file
+	// and are not disabled by Set when they are not listed
+	policies     map[string]struct{}
+	policyConfig map[string]interface{}
+
+	apiCallCounter *APICallCounter
+	stats          []CheckStats
+	tracer         Tracer
+	decisionLog    *decisionLog
+
+	// maxFindings limits findings of each check unless
+	// overridden in checkMaxFindings (zero means no limit)
+	maxFindings      int
+	checkMaxFindings map[string]int
+
+	severityWeights map[Severity]float64
+	severityLabels  SeverityLabels
+	checkWeights    map[string]float64
+	maxScore        *float64
+	scores          []CheckScore
+
+	// parallelism limits parallel checks running
+	// at the same time (zero means default)
+	parallelism int
+	// maxGoroutines limits goroutines run by each
+	// check via its WorkGroup (zero means no limit)
+	maxGoroutines int
+
+	// deployContext is set via SetDeployContext and
+	// takes precedence over configDeployContext
+	deployContext       DeployContext
+	configDeployContext DeployContext
+
+	// targetVersion overrides discovered Kubernetes version
+	// of version dependent checks if set
+	targetVersion *KubernetesVersion
 }
 
 // NewRegistry will return a new *Registry with the
@@ -55,8 +108,10 @@ func (c *Registry) Type() string {
 // CheckName,...
 // and sets the specified preflight check
 // as enabled if listed, otherwise, sets as
-// disabled if not listed.
-// Returns an error if there is a problem
+// disabled if not listed. Checks loaded from
+// a policy directory keep enabled value from
+// their policy file when not listed (listing
+// them enables them). Returns an error if there is a problem
 // parsing the preflight checks
 func (c *Registry) Set(s string) error {
 	if c.known == nil {
@@ -67,14 +122,24 @@ func (c *Registry) Set(s string) error {
 	// enable those specified
 	mappings := strings.Split(s, ",")
 	for _, key := range mappings {
-		if _, ok := c.known[key]; !ok {
+		if _, ok := c.lookup(key); !ok {
 			return fmt.Errorf("unknown preflight check %q specified", key)
 		}
-		c.known[key].SetEnabled(true)
 		enabled[key] = struct{}{}
 	}
+	for _, key := range sortedLockedNames(c.locked) {
+		if _, ok := enabled[key]; !ok {
+			return fmt.Errorf("preflight check %q is locked and cannot be disabled: %s", key, c.locked[key])
+		}
+	}
+	for key := range enabled {
+		c.known[key].SetEnabled(true)
+	}
 	// disable unspecified validators
 	for key := range c.known {
+		if _, ok := c.policies[key]; ok {
+			continue
+		}
 		if _, ok := enabled[key]; !ok {
 			c.known[key].SetEnabled(false)
 		}
@@ -82,39 +147,436 @@ func (c *Registry) Set(s string) error {
 	return nil
 }
 
-// AddFlags adds the --preflight flag to a
+// AddFlags adds the --preflight, --preflight-config and
+// --preflight-policy-dir flags (among others) to a
 // pflag.FlagSet and configures the preflight
 // checks in the registry based on the user provided
 // values. If no values are provided by a user the
 // default values are used.
 func (c *Registry) AddFlags(flags *pflag.FlagSet) {
-	knownChecks := []string{}
-	for name := range c.known {
-		knownChecks = append(knownChecks, name)
-	}
-	flags.Var(c, preflightFlag, fmt.Sprintf("preflight checks to run. Available preflight checks are [%s]", strings.Join(knownChecks, ",")))
+	flags.Var(c, preflightFlag, fmt.Sprintf("preflight checks to run. Available preflight checks are [%s]. "+
+		"Additional instances of checks that support them can be specified as CheckName%sinstance", strings.Join(c.names(), ","), checkInstanceSeparator))
+	flags.Var(&configFileFlag{registry: c}, preflightConfigFlag, "path to a YAML file with configuration of preflight checks (may extend another file via 'extends: path')")
+	flags.Var(&policyDirFlag{registry: c}, preflightPolicyDirFlag,
+		"path to a directory with YAML files of policies, each adding an enabled instance of a policy check (e.g. AllowedRegistries)")
+	flags.Var(&deployContextFlag{registry: c}, preflightContextFlag,
+		"set deploy context (e.g. env=prod) available to preflight checks, overrides 'context' of --preflight-config (can be specified multiple times)")
+	flags.Var(&targetVersionFlag{registry: c}, preflightTargetVersionFlag,
+		"simulate running preflight checks against a Kubernetes version (e.g. 1.30) instead of the cluster version")
+	flags.Var(&maxScoreFlag{registry: c}, preflightMaxScoreFlag,
+		"fail preflight checks when weighted score of findings exceeds this value (weights are set via --preflight-config)")
+}
+
+// CheckOpts are registration options of a preflight check
+type CheckOpts struct {
+	// RunsIf lists kinds of resources of which at least one has
+	// to be part of the change for the check to run. Empty version
+	// matches all versions. Check always runs if RunsIf is empty.
+	RunsIf []schema.GroupVersionKind
+	// VersionDependent marks checks whose results depend on the Kubernetes
+	// version of the cluster. Unless such a check implements VersionAwareCheck,
+	// it is skipped when a target version is set.
+	VersionDependent bool
+	// Concurrency determines whether the check may run
+	// at the same time as other checks (serial by default)
+	Concurrency ConcurrencyClass
+	// PostApply marks checks that are re-run after changes are
+	// applied (see PhasePostApply) in addition to running before
+	PostApply bool
+	// FindingsTTL marks checks whose findings are observations of
+	// volatile cluster state (e.g. reachability) that are only valid
+	// for the duration (see Finding.ExpiresAt). Findings of checks
+	// without TTL are derived from the change and its stable context.
+	FindingsTTL time.Duration
 }
 
 // AddCheck adds a new preflight check to the registry.
 // The name provided will map to the provided Check.
 func (c *Registry) AddCheck(name string, check Check) {
+	c.AddCheckWithOpts(name, check, CheckOpts{})
+}
+
+// AddCheckWithOpts adds a new preflight check to the
+// registry with provided registration options
+func (c *Registry) AddCheckWithOpts(name string, check Check, opts CheckOpts) {
 	if c.known == nil {
 		c.known = make(map[string]Check)
 	}
+	if c.opts == nil {
+		c.opts = make(map[string]CheckOpts)
+	}
 	c.known[name] = check
+	c.opts[name] = opts
+}
+
+// CheckFactory returns a new (disabled) instance of a check
+type CheckFactory func() Check
+
+// AddCheckFactory adds a new preflight check to the registry
+// that supports multiple instances. Additional instances are
+// created on first use of a name in format Name:instance (e.g. via
+// Set or SetConfig) and are enabled and configured independently
+// of each other. Instances share registration options.
+func (c *Registry) AddCheckFactory(name string, factory CheckFactory, opts CheckOpts) {
+	c.AddCheckWithOpts(name, factory(), opts)
+
+	if c.factories == nil {
+		c.factories = make(map[string]CheckFactory)
+	}
+	c.factories[name] = factory
+}
+
+// lookup returns a known preflight check, creating
+// a new instance of a check if name refers to one
+func (c *Registry) lookup(name string) (Check, bool) {
+	if check, found := c.known[name]; found {
+		return check, true
+	}
+
+	check, found := c.peek(name)
+	if !found {
+		return nil, false
+	}
+
+	baseName, _, _ := strings.Cut(name, checkInstanceSeparator)
+	c.AddCheckWithOpts(name, check, c.opts[baseName])
+
+	return check, true
+}
+
+// Lock enables the preflight check and prevents it from being
+// disabled via Set. It is meant to be used by embedders to enforce
+// a baseline set of checks. Reason is included in the error
+// returned when user attempts to disable the check.
+func (c *Registry) Lock(name, reason string) error {
+	check, found := c.lookup(name)
+	if !found {
+		return fmt.Errorf("unknown preflight check %q cannot be locked", name)
+	}
+
+	if c.locked == nil {
+		c.locked = make(map[string]string)
+	}
+	c.locked[name] = reason
+
+	check.SetEnabled(true)
+	return nil
+}
+
+// IsLocked returns true if the preflight check cannot be disabled
+func (c *Registry) IsLocked(name string) bool {
+	_, found := c.locked[name]
+	return found
 }
 
 // Run will execute any enabled preflight checks. The provided
 // Context and ChangeGraph will be passed to the preflight checks
-// that are being executed.
-func (c *Registry) Run(ctx context.Context, cg *ctldgraph.ChangeGraph) error {
+// that are being executed. Findings reported by the checks are
+// returned. An error is returned if a check fails to run,
+// reports a finding with SeverityError or if score of
+// findings exceeds maximum score. Checks registered as parallel
+// run concurrently (see SetParallelism); findings are always
+// returned in order of check names. Run executes checks of
+// PhasePreApply (i.e. all enabled checks).
+func (c *Registry) Run(ctx context.Context, cg *ctldgraph.ChangeGraph) ([]Finding, error) {
+	return c.RunPhase(ctx, cg, PhasePreApply)
+}
+
+// RunPhase is like Run but only executes enabled checks of provided
+// phase. Checks registered with PostApply are executed in PhasePostApply.
+// Checks can determine the phase via PhaseFromContext.
+func (c *Registry) RunPhase(ctx context.Context, cg *ctldgraph.ChangeGraph, phase Phase) ([]Finding, error) {
+	switch phase {
+	case PhasePreApply, PhasePostApply:
+	default:
+		return nil, fmt.Errorf("unknown preflight phase %q", phase)
+	}
+
+	// Pre-apply is the default phase and is not recorded in context
+	if phase != PhasePreApply {
+		ctx = contextWithPhase(ctx, phase)
+	}
+	ctx = c.contextWithDeployContext(ctx)
+
+	ctx, span := c.tracerOrNoop().Start(ctx, tracingSpanName)
+	defer span.End()
+
+	var findings []Finding
+	var errs []error
+	var decisionChecks []DecisionCheck
+
+	c.stats = nil
+	c.scores = nil
+
+	runs, pendingRuns := c.prepareRuns(cg, func(name string, check Check) bool {
+		return c.runsInPhase(name, check, phase)
+	})
+
+	c.runChecks(ctx, cg, pendingRuns)
+
+	for _, run := range runs {
+		name := run.name
+		c.stats = append(c.stats, run.stats)
+
+		if run.stats.Skipped {
+			c.traceSkippedCheck(ctx, run)
+			decisionChecks = append(decisionChecks, newDecisionCheck(run, nil))
+			continue
+		}
+
+		if run.err != nil {
+			errs = append(errs, fmt.Errorf("running preflight check %q: %w", name, run.err))
+		}
+
+		checkFindings := run.findings
+
+		expiresAt := c.findingsExpiry(name)
+
+		var numErrors int
+		for i, finding := range checkFindings {
+			checkFindings[i].Check = name
+			checkFindings[i].ExpiresAt = expiresAt
+			if finding.Severity == SeverityError {
+				numErrors++
+			}
+		}
+		c.recordScore(name, checkFindings)
+		limitedFindings := c.limitFindings(name, checkFindings)
+		findings = append(findings, limitedFindings...)
+		decisionChecks = append(decisionChecks, newDecisionCheck(run, limitedFindings))
+		if numErrors > 0 {
+			errs = append(errs, fmt.Errorf("preflight check %q reported %d error(s)", name, numErrors))
+		}
+	}
+
+	if score := c.Score(); score.Exceeded() {
+		errs = append(errs, fmt.Errorf("preflight score %g exceeds maximum score %g", score.Total, *score.MaxScore))
+	}
+
+	err := errors.Join(errs...)
+
+	if logErr := c.recordDecision(ctx, phase, decisionChecks, err); logErr != nil {
+		err = errors.Join(err, logErr)
+	}
+
+	status := CheckStatusPassed
+	if err != nil {
+		status = CheckStatusFailed
+		span.RecordError(err)
+	}
+	span.SetAttributes(
+		Attribute{Key: TracingAttrPhase, Value: string(phase)},
+		Attribute{Key: TracingAttrStatus, Value: status},
+		Attribute{Key: TracingAttrChecks, Value: int64(len(runs))},
+		Attribute{Key: TracingAttrFindings, Value: int64(len(findings))},
+	)
+
+	return findings, err
+}
+
+// HasPhaseChecks returns true if any enabled check runs in provided
+// phase so that callers can skip running phases without checks
+func (c *Registry) HasPhaseChecks(phase Phase) bool {
 	for name, check := range c.known {
-		if check.Enabled() {
-			err := check.Run(ctx, cg)
-			if err != nil {
-				return fmt.Errorf("running preflight check %q: %w", name, err)
+		if c.runsInPhase(name, check, phase) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Registry) runsInPhase(name string, check Check, phase Phase) bool {
+	return check.Enabled() && (phase == PhasePreApply || c.opts[name].PostApply)
+}
+
+// prepareRuns returns runs of included checks in order of their names
+// (including skipped checks so that their stats are recorded) and
+// runs of checks that are not skipped and are to be executed
+func (c *Registry) prepareRuns(cg *ctldgraph.ChangeGraph, include func(string, Check) bool) ([]*checkRun, []*checkRun) {
+	var runs, pendingRuns []*checkRun
+
+	for _, name := range c.names() {
+		check := c.known[name]
+		if !include(name, check) {
+			continue
+		}
+
+		if skipReason, skip := c.skipReason(name, cg); skip {
+			runs = append(runs, &checkRun{name: name, stats: CheckStats{Name: name, Skipped: true, SkipReason: skipReason}})
+			continue
+		}
+
+		if versionAwareCheck, ok := check.(VersionAwareCheck); ok && c.targetVersion != nil {
+			versionAwareCheck.SetTargetVersion(*c.targetVersion)
+		}
+
+		run := &checkRun{name: name, check: check}
+		runs = append(runs, run)
+		pendingRuns = append(pendingRuns, run)
+	}
+
+	return runs, pendingRuns
+}
+
+// findingsExpiry returns time until which findings of
+// the check reported now are valid (nil if they do not expire)
+func (c *Registry) findingsExpiry(name string) *time.Time {
+	ttl := c.opts[name].FindingsTTL
+	if ttl <= 0 {
+		return nil
+	}
+	expiresAt := time.Now().Add(ttl)
+	return &expiresAt
+}
+
+// limitFindings returns at most limit findings followed by a finding
+// noting how many were omitted (with highest severity of omitted findings).
+// Most severe findings are kept (in order reported within the same severity)
+// so that errors are not hidden behind warnings reported before them.
+func (c *Registry) limitFindings(name string, findings []Finding) []Finding {
+	limit := c.maxFindings
+	if checkLimit, found := c.checkMaxFindings[name]; found {
+		limit = checkLimit
+	}
+	if limit == 0 || len(findings) <= limit {
+		return findings
+	}
+
+	findings = append([]Finding{}, findings...)
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Severity.rank() > findings[j].Severity.rank()
+	})
+
+	note := Finding{
+		Check:    name,
+		Severity: SeverityInfo,
+		Message:  fmt.Sprintf("and %d more finding(s)", len(findings)-limit),
+	}
+	for _, finding := range findings[limit:] {
+		if finding.Severity.rank() > note.Severity.rank() {
+			note.Severity = finding.Severity
+		}
+	}
+
+	return append(findings[:limit:limit], note)
+}
+
+// skipReason returns true if registration options of
+// the check prevent it from running against the change graph
+func (c *Registry) skipReason(name string, cg *ctldgraph.ChangeGraph) (string, bool) {
+	if skipReason, skip := c.targetVersionSkipReason(name); skip {
+		return skipReason, true
+	}
+
+	runsIf := c.opts[name].RunsIf
+	if len(runsIf) == 0 {
+		return "", false
+	}
+
+	for _, change := range cg.All() {
+		res := change.Change.Resource()
+		for _, gvk := range runsIf {
+			if gvk.Kind == res.Kind() && gvk.Group == res.GroupVersion().Group &&
+				(len(gvk.Version) == 0 || gvk.Version == res.GroupVersion().Version) {
+				return "", false
 			}
 		}
 	}
-	return nil
+
+	return fmt.Sprintf("no resources of kind %s in the change", formatGVKs(runsIf)), true
+}
+
+func formatGVKs(gvks []schema.GroupVersionKind) string {
+	var result []string
+	for _, gvk := range gvks {
+		result = append(result, formatGVK(gvk))
+	}
+	return strings.Join(result, ", ")
+}
+
+// formatGVK formats kind similarly to resource descriptions,
+// e.g. Deployment (apps/v1), Deployment (apps) or Service
+func formatGVK(gvk schema.GroupVersionKind) string {
+	groupVersion := gvk.GroupVersion().String()
+	if len(gvk.Version) == 0 {
+		groupVersion = gvk.Group
+	}
+	if len(groupVersion) == 0 {
+		return gvk.Kind
+	}
+	return fmt.Sprintf("%s (%s)", gvk.Kind, groupVersion)
+}
+
+// SetAPICallCounter configures the registry to report API
+// requests made by each check in Stats. Counter is expected
+// to be wrapping transport of clients used by the checks.
+func (c *Registry) SetAPICallCounter(counter *APICallCounter) {
+	c.apiCallCounter = counter
+}
+
+// Stats returns statistics of checks executed
+// by the most recent Run sorted by check name
+func (c *Registry) Stats() []CheckStats {
+	return c.stats
+}
+
+func (c *Registry) newStats(name string, duration time.Duration) CheckStats {
+	stats := CheckStats{Name: name, Duration: duration}
+	if c.apiCallCounter != nil {
+		stats.APICalls = c.apiCallCounter.Take(name)
+	}
+	return stats
+}
+
+// Validate checks that the registry is internally consistent.
+// It is meant to be called once all checks are added so that
+// misconfigured registries fail early. All problems found are
+// returned as a single error.
+func (c *Registry) Validate() error {
+	var errs []error
+
+	for _, name := range c.names() {
+		switch {
+		case len(name) == 0:
+			errs = append(errs, fmt.Errorf("preflight check with an empty name is registered"))
+		case strings.ContainsAny(name, ", \t\n"):
+			errs = append(errs, fmt.Errorf("preflight check name %q must not contain commas or whitespace", name))
+		}
+		if c.known[name] == nil {
+			errs = append(errs, fmt.Errorf("preflight check %q is nil", name))
+		}
+		switch c.opts[name].Concurrency {
+		case ConcurrencyClassSerial, ConcurrencyClassParallel:
+		default:
+			errs = append(errs, fmt.Errorf("preflight check %q has unknown concurrency class %q", name, c.opts[name].Concurrency))
+		}
+		for _, gvk := range c.opts[name].RunsIf {
+			if len(gvk.Kind) == 0 {
+				errs = append(errs, fmt.Errorf("preflight check %q has a runs-if condition without a kind", name))
+			}
+		}
+		if c.opts[name].FindingsTTL < 0 {
+			errs = append(errs, fmt.Errorf("preflight check %q has a negative findings TTL", name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func sortedLockedNames(locked map[string]string) []string {
+	names := []string{}
+	for name := range locked {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *Registry) names() []string {
+	names := []string{}
+	for name := range c.known {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
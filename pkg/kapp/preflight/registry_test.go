@@ -6,9 +6,13 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/logger"
+	ctlres "github.com/vmware-tanzu/carvel-kapp/pkg/kapp/resources"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 func TestRegistrySet(t *testing.T) {
@@ -64,9 +68,10 @@ func TestRegistrySet(t *testing.T) {
 
 func TestRegistryRun(t *testing.T) {
 	testCases := []struct {
-		name      string
-		registry  *Registry
-		shouldErr bool
+		name        string
+		registry    *Registry
+		shouldErr   bool
+		numFindings int
 	}{
 		{
 			name:     "no preflight checks registered, no error returned",
@@ -89,6 +94,40 @@ func TestRegistryRun(t *testing.T) {
 			},
 			shouldErr: true,
 		},
+		{
+			name: "preflight checks registered, enabled check returns only warnings, findings returned and no error returned",
+			registry: &Registry{
+				known: map[string]Check{
+					"warningCheck": NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error {
+						return errors.Join(NewWarning(nil, "first"), NewWarning(nil, "second"))
+					}, true),
+				},
+			},
+			numFindings: 2,
+		},
+		{
+			name: "preflight checks registered, enabled check returns only info findings, findings returned and no error returned",
+			registry: &Registry{
+				known: map[string]Check{
+					"infoCheck": NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error {
+						return errors.Join(NewInfo(nil, "note"))
+					}, true),
+				},
+			},
+			numFindings: 1,
+		},
+		{
+			name: "preflight checks registered, enabled check returns warnings and errors, findings and error returned",
+			registry: &Registry{
+				known: map[string]Check{
+					"mixedCheck": NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error {
+						return errors.Join(NewWarning(nil, "warning"), NewError(nil, "error"))
+					}, true),
+				},
+			},
+			shouldErr:   true,
+			numFindings: 2,
+		},
 		{
 			name: "preflight checks registered, enabled checks successful, no error returned",
 			registry: &Registry{
@@ -101,8 +140,229 @@ func TestRegistryRun(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			err := tc.registry.Run(nil, nil)
+			findings, err := tc.registry.Run(nil, nil)
 			require.Equal(t, tc.shouldErr, err != nil)
+			require.Len(t, findings, tc.numFindings)
 		})
 	}
 }
+
+func TestRegistryValidate(t *testing.T) {
+	someCheck := NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error { return nil }, true)
+
+	testCases := []struct {
+		name        string
+		registry    *Registry
+		expectedErr string
+	}{
+		{
+			name:     "no preflight checks registered, no error returned",
+			registry: &Registry{},
+		},
+		{
+			name:     "valid preflight checks registered, no error returned",
+			registry: NewRegistry(map[string]Check{"someCheck": someCheck, "otherCheck": someCheck}),
+		},
+		{
+			name: "invalid preflight checks registered, all problems returned",
+			registry: NewRegistry(map[string]Check{
+				"":          someCheck,
+				"some,name": someCheck,
+				"nilCheck":  nil,
+			}),
+			expectedErr: "preflight check with an empty name is registered\n" +
+				"preflight check \"nilCheck\" is nil\n" +
+				"preflight check name \"some,name\" must not contain commas or whitespace",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.registry.Validate()
+			if len(tc.expectedErr) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestRegistryLock(t *testing.T) {
+	newRegistry := func() *Registry {
+		registry := NewRegistry(map[string]Check{
+			"lockedCheck": NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error { return nil }, false),
+			"otherCheck":  NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error { return nil }, false),
+		})
+		require.NoError(t, registry.Lock("lockedCheck", "required by cluster policy"))
+		return registry
+	}
+
+	t.Run("locking enables the check", func(t *testing.T) {
+		registry := newRegistry()
+		require.True(t, registry.known["lockedCheck"].Enabled())
+		require.True(t, registry.IsLocked("lockedCheck"))
+		require.False(t, registry.IsLocked("otherCheck"))
+	})
+
+	t.Run("locked check cannot be disabled, nothing is changed", func(t *testing.T) {
+		registry := newRegistry()
+		err := registry.Set("otherCheck")
+		require.EqualError(t, err, `preflight check "lockedCheck" is locked and cannot be disabled: required by cluster policy`)
+		require.True(t, registry.known["lockedCheck"].Enabled())
+		require.False(t, registry.known["otherCheck"].Enabled())
+	})
+
+	t.Run("other checks can be enabled along with locked check", func(t *testing.T) {
+		registry := newRegistry()
+		require.NoError(t, registry.Set("lockedCheck,otherCheck"))
+		require.True(t, registry.known["lockedCheck"].Enabled())
+		require.True(t, registry.known["otherCheck"].Enabled())
+	})
+
+	t.Run("unknown check cannot be locked", func(t *testing.T) {
+		err := newRegistry().Lock("nonexistent", "reason")
+		require.EqualError(t, err, `unknown preflight check "nonexistent" cannot be locked`)
+	})
+}
+
+type fakeChange struct {
+	res ctlres.Resource
+}
+
+func (c fakeChange) Resource() ctlres.Resource    { return c.res }
+func (c fakeChange) Op() diffgraph.ActualChangeOp { return diffgraph.ActualChangeOpUpsert }
+
+func TestRegistryRunsIf(t *testing.T) {
+	graph, err := diffgraph.NewChangeGraph([]diffgraph.ActualChange{
+		fakeChange{res: ctlres.MustNewResourceFromBytes([]byte(`{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "app"}}`))},
+	}, nil, nil, logger.NewTODOLogger())
+	require.NoError(t, err)
+
+	var ran []string
+	newCheck := func(name string) Check {
+		return NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error {
+			ran = append(ran, name)
+			return nil
+		}, true)
+	}
+
+	registry := &Registry{}
+	registry.AddCheck("always", newCheck("always"))
+	registry.AddCheckWithOpts("deployments", newCheck("deployments"),
+		CheckOpts{RunsIf: []schema.GroupVersionKind{{Group: "apps", Kind: "Deployment"}}})
+	registry.AddCheckWithOpts("deploymentsV1beta1", newCheck("deploymentsV1beta1"),
+		CheckOpts{RunsIf: []schema.GroupVersionKind{{Group: "apps", Version: "v1beta1", Kind: "Deployment"}}})
+	registry.AddCheckWithOpts("ingresses", newCheck("ingresses"),
+		CheckOpts{RunsIf: []schema.GroupVersionKind{{Group: "networking.k8s.io", Kind: "Ingress"}, {Kind: "Service"}}})
+	require.NoError(t, registry.Validate())
+
+	_, err = registry.Run(context.Background(), graph)
+	require.NoError(t, err)
+	require.Equal(t, []string{"always", "deployments"}, ran)
+
+	var skipReasons []string
+	for _, stats := range registry.Stats() {
+		if stats.Skipped {
+			skipReasons = append(skipReasons, stats.Name+": "+stats.SkipReason)
+		}
+	}
+	require.Equal(t, []string{
+		"deploymentsV1beta1: no resources of kind Deployment (apps/v1beta1) in the change",
+		"ingresses: no resources of kind Ingress (networking.k8s.io), Service in the change",
+	}, skipReasons)
+
+	registry.AddCheckWithOpts("invalid", newCheck("invalid"), CheckOpts{RunsIf: []schema.GroupVersionKind{{Group: "apps"}}})
+	require.EqualError(t, registry.Validate(), `preflight check "invalid" has a runs-if condition without a kind`)
+}
+
+func TestRegistryCheckInstances(t *testing.T) {
+	newRegistry := func() *Registry {
+		registry := &Registry{}
+		registry.AddCheck("plain", NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error { return nil }, false))
+		registry.AddCheckFactory("policy", func() Check {
+			return &configurableCheck{Check: NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error { return nil }, false)}
+		}, CheckOpts{})
+		return registry
+	}
+
+	t.Run("instances are enabled and configured independently", func(t *testing.T) {
+		registry := newRegistry()
+
+		require.NoError(t, registry.SetConfig(map[string]interface{}{"checks": map[string]interface{}{
+			"policy":      map[string]interface{}{"key": "base"},
+			"policy:a":    map[string]interface{}{"key": "a"},
+			"policy:b":    map[string]interface{}{"key": "b"},
+			"policy:none": nil,
+		}}))
+		require.NoError(t, registry.Set("policy:a,policy:b"))
+
+		var enabled []string
+		configs := map[string]interface{}{}
+		for _, desc := range registry.Describe().Checks {
+			if desc.Enabled {
+				enabled = append(enabled, desc.Name)
+			}
+			if desc.Configurable {
+				configs[desc.Name] = desc.Config["key"]
+			}
+		}
+		require.Equal(t, []string{"policy:a", "policy:b"}, enabled)
+		require.Equal(t, map[string]interface{}{"policy": "base", "policy:a": "a", "policy:b": "b", "policy:none": nil}, configs)
+	})
+
+	t.Run("instances of checks registered without factory are unknown", func(t *testing.T) {
+		registry := newRegistry()
+		require.EqualError(t, registry.Set("plain:a"), `unknown preflight check "plain:a" specified`)
+		require.EqualError(t, registry.Set("policy:"), `unknown preflight check "policy:" specified`)
+	})
+}
+
+func TestRegistryFindingsTTL(t *testing.T) {
+	graph, err := diffgraph.NewChangeGraph(nil, nil, nil, logger.NewTODOLogger())
+	require.NoError(t, err)
+
+	newCheck := func(msg string) Check {
+		return NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error {
+			return NewWarning(nil, msg)
+		}, true)
+	}
+
+	registry := &Registry{}
+	registry.AddCheck("stable", newCheck("derived from the change"))
+	registry.AddCheckWithOpts("volatile", newCheck("observed in the cluster"), CheckOpts{FindingsTTL: 5 * time.Minute})
+	require.NoError(t, registry.Validate())
+
+	before := time.Now()
+
+	findings, err := registry.Run(context.Background(), graph)
+	require.NoError(t, err)
+	require.Len(t, findings, 2)
+
+	for _, finding := range findings {
+		switch finding.Check {
+		case "stable":
+			require.Nil(t, finding.ExpiresAt)
+			require.False(t, finding.Expired(before.Add(24*time.Hour)))
+		case "volatile":
+			require.NotNil(t, finding.ExpiresAt)
+			require.False(t, finding.ExpiresAt.Before(before.Add(5*time.Minute)))
+			require.False(t, finding.Expired(before))
+			require.True(t, finding.Expired(finding.ExpiresAt.Add(time.Second)))
+		default:
+			t.Fatalf("unexpected finding of check %q", finding.Check)
+		}
+	}
+
+	results, err := registry.Audit(context.Background(), graph)
+	require.NoError(t, err)
+	for _, result := range results {
+		require.Len(t, result.Findings, 1)
+		require.Equal(t, result.Name == "volatile", result.Findings[0].ExpiresAt != nil)
+	}
+
+	require.Equal(t, "5m0s", registry.Describe().Checks[1].FindingsTTL)
+
+	registry.AddCheckWithOpts("invalid", newCheck("invalid"), CheckOpts{FindingsTTL: -time.Minute})
+	require.EqualError(t, registry.Validate(), `preflight check "invalid" has a negative findings TTL`)
+}
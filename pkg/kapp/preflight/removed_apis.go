@@ -0,0 +1,88 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// removedAPIs maps versions of built-in resources to Kubernetes versions
+// that no longer serve them (based on the Kubernetes deprecated API
+// migration guide). Only removals are listed, so simulating a version
+// older than the cluster does not hide resources added since then.
+var removedAPIs = map[schema.GroupVersionResource]KubernetesVersion{}
+
+func init() {
+	for version, gvrs := range map[KubernetesVersion][]string{
+		{Major: 1, Minor: 16}: {
+			"extensions/v1beta1/daemonsets", "extensions/v1beta1/deployments", "extensions/v1beta1/replicasets",
+			"extensions/v1beta1/networkpolicies", "extensions/v1beta1/podsecuritypolicies",
+			"apps/v1beta1/deployments", "apps/v1beta1/statefulsets", "apps/v1beta1/controllerrevisions",
+			"apps/v1beta2/deployments", "apps/v1beta2/statefulsets", "apps/v1beta2/daemonsets",
+			"apps/v1beta2/replicasets", "apps/v1beta2/controllerrevisions",
+		},
+		{Major: 1, Minor: 22}: {
+			"extensions/v1beta1/ingresses",
+			"networking.k8s.io/v1beta1/ingresses", "networking.k8s.io/v1beta1/ingressclasses",
+			"apiextensions.k8s.io/v1beta1/customresourcedefinitions",
+			"admissionregistration.k8s.io/v1beta1/mutatingwebhookconfigurations",
+			"admissionregistration.k8s.io/v1beta1/validatingwebhookconfigurations",
+			"apiregistration.k8s.io/v1beta1/apiservices",
+			"authentication.k8s.io/v1beta1/tokenreviews",
+			"authorization.k8s.io/v1beta1/subjectaccessreviews", "authorization.k8s.io/v1beta1/localsubjectaccessreviews",
+			"authorization.k8s.io/v1beta1/selfsubjectaccessreviews", "authorization.k8s.io/v1beta1/selfsubjectrulesreviews",
+			"certificates.k8s.io/v1beta1/certificatesigningrequests",
+			"coordination.k8s.io/v1beta1/leases",
+			"rbac.authorization.k8s.io/v1beta1/clusterroles", "rbac.authorization.k8s.io/v1beta1/clusterrolebindings",
+			"rbac.authorization.k8s.io/v1beta1/roles", "rbac.authorization.k8s.io/v1beta1/rolebindings",
+			"scheduling.k8s.io/v1beta1/priorityclasses",
+			"storage.k8s.io/v1beta1/csidrivers", "storage.k8s.io/v1beta1/csinodes",
+			"storage.k8s.io/v1beta1/storageclasses", "storage.k8s.io/v1beta1/volumeattachments",
+		},
+		{Major: 1, Minor: 25}: {
+			"batch/v1beta1/cronjobs",
+			"discovery.k8s.io/v1beta1/endpointslices",
+			"events.k8s.io/v1beta1/events",
+			"autoscaling/v2beta1/horizontalpodautoscalers",
+			"policy/v1beta1/poddisruptionbudgets", "policy/v1beta1/podsecuritypolicies",
+			"node.k8s.io/v1beta1/runtimeclasses",
+		},
+		{Major: 1, Minor: 26}: {
+			"flowcontrol.apiserver.k8s.io/v1beta1/flowschemas", "flowcontrol.apiserver.k8s.io/v1beta1/prioritylevelconfigurations",
+			"autoscaling/v2beta2/horizontalpodautoscalers",
+		},
+		{Major: 1, Minor: 27}: {
+			"storage.k8s.io/v1beta1/csistoragecapacities",
+		},
+		{Major: 1, Minor: 29}: {
+			"flowcontrol.apiserver.k8s.io/v1beta2/flowschemas", "flowcontrol.apiserver.k8s.io/v1beta2/prioritylevelconfigurations",
+		},
+		{Major: 1, Minor: 32}: {
+			"flowcontrol.apiserver.k8s.io/v1beta3/flowschemas", "flowcontrol.apiserver.k8s.io/v1beta3/prioritylevelconfigurations",
+		},
+	} {
+		for _, gvr := range gvrs {
+			pieces := strings.Split(gvr, "/")
+			removedAPIs[schema.GroupVersionResource{Group: pieces[0], Version: pieces[1], Resource: pieces[2]}] = version
+		}
+	}
+}
+
+// APIRemovedIn returns Kubernetes version that no longer serves the
+// version of a built-in resource. Subresources (e.g. deployments/scale)
+// are removed together with their resource.
+func APIRemovedIn(gvr schema.GroupVersionResource) (KubernetesVersion, bool) {
+	gvr.Resource, _, _ = strings.Cut(gvr.Resource, "/")
+	version, found := removedAPIs[gvr]
+	return version, found
+}
+
+// ServesAPI returns false if the version of a built-in
+// resource is removed in Kubernetes version v
+func (v KubernetesVersion) ServesAPI(gvr schema.GroupVersionResource) bool {
+	removedIn, found := APIRemovedIn(gvr)
+	return !found || !v.AtLeast(removedIn)
+}
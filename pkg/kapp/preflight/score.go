@@ -0,0 +1,148 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	preflightMaxScoreFlag = "preflight-max-score"
+)
+
+var (
+	// defaultSeverityWeights are used for severities
+	// not specified in severityWeights configuration
+	defaultSeverityWeights = map[Severity]float64{
+		SeverityInfo:    0,
+		SeverityWarning: 1,
+		SeverityError:   10,
+	}
+)
+
+// CheckScore is the score of findings reported by a single check.
+// Score of each finding is the weight of its severity multiplied
+// by the weight of the check (1 unless configured).
+type CheckScore struct {
+	Name     string
+	Weight   float64
+	Findings int
+	Score    float64
+}
+
+// Score is the weighted score of findings reported during the last Run
+type Score struct {
+	Checks []CheckScore
+	Total  float64
+	// MaxScore is the threshold above which preflight checks fail (nil if not set)
+	MaxScore *float64
+}
+
+// Exceeded returns true if total score is above the maximum score
+func (s Score) Exceeded() bool {
+	return s.MaxScore != nil && s.Total > *s.MaxScore
+}
+
+// Score returns weighted score of findings of the last Run
+func (c *Registry) Score() Score {
+	score := Score{MaxScore: c.maxScore}
+	for _, checkScore := range c.scores {
+		score.Checks = append(score.Checks, checkScore)
+		score.Total += checkScore.Score
+	}
+	return score
+}
+
+// SetMaxScore makes Run fail when total score of
+// findings exceeds maxScore regardless of their severities
+func (c *Registry) SetMaxScore(maxScore float64) {
+	c.maxScore = &maxScore
+}
+
+func (c *Registry) recordScore(name string, findings []Finding) {
+	weight := 1.0
+	if checkWeight, found := c.checkWeights[name]; found {
+		weight = checkWeight
+	}
+
+	checkScore := CheckScore{Name: name, Weight: weight, Findings: len(findings)}
+
+	for _, finding := range findings {
+		severityWeight, found := c.severityWeights[finding.Severity]
+		if !found {
+			severityWeight = defaultSeverityWeights[finding.Severity]
+		}
+		checkScore.Score += weight * severityWeight
+	}
+
+	c.scores = append(c.scores, checkScore)
+}
+
+func parseSeverityWeights(val interface{}) (map[Severity]float64, error) {
+	result := map[Severity]float64{}
+
+	if val == nil {
+		return result, nil
+	}
+
+	weights, ok := val.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected preflight config key %q to be a map", configSeverityWeightsKey)
+	}
+
+	for key, weight := range weights {
+		severity := Severity(key)
+		if _, found := defaultSeverityWeights[severity]; !found {
+			return nil, fmt.Errorf("unknown severity %q specified in %s", key, configSeverityWeightsKey)
+		}
+
+		w, err := parseWeight(configSeverityWeightsKey+"."+key, weight)
+		if err != nil {
+			return nil, err
+		}
+		result[severity] = w
+	}
+
+	return result, nil
+}
+
+func parseWeight(key string, val interface{}) (float64, error) {
+	var result float64
+
+	switch typedVal := val.(type) {
+	case int:
+		result = float64(typedVal)
+	case float64:
+		result = typedVal
+	default:
+		return 0, fmt.Errorf("expected %s to be a number", key)
+	}
+
+	if result < 0 {
+		return 0, fmt.Errorf("expected %s to be non-negative", key)
+	}
+	return result, nil
+}
+
+// maxScoreFlag is a pflag.Value that sets
+// maximum score of the registry
+type maxScoreFlag struct {
+	registry *Registry
+	value    string
+}
+
+func (f *maxScoreFlag) String() string { return f.value }
+func (f *maxScoreFlag) Type() string   { return "float" }
+
+func (f *maxScoreFlag) Set(value string) error {
+	maxScore, err := strconv.ParseFloat(value, 64)
+	if err != nil || maxScore < 0 {
+		return fmt.Errorf("Expected maximum preflight score to be a non-negative number, but was '%s'", value)
+	}
+
+	f.registry.SetMaxScore(maxScore)
+	f.value = value
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+)
+
+func TestRegistryScore(t *testing.T) {
+	newRegistry := func() *Registry {
+		return NewRegistry(map[string]Check{
+			"minor": NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error {
+				return errors.Join(NewWarning(nil, "first"), NewWarning(nil, "second"), NewInfo(nil, "note"))
+			}, true),
+			"major": NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error {
+				return NewWarning(nil, "warning")
+			}, true),
+		})
+	}
+
+	t.Run("default weights", func(t *testing.T) {
+		registry := newRegistry()
+
+		_, err := registry.Run(nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, Score{
+			Checks: []CheckScore{
+				{Name: "major", Weight: 1, Findings: 1, Score: 1},
+				{Name: "minor", Weight: 1, Findings: 3, Score: 2},
+			},
+			Total: 3,
+		}, registry.Score())
+	})
+
+	t.Run("configured weights and exceeded maximum score", func(t *testing.T) {
+		registry := newRegistry()
+		require.NoError(t, registry.SetConfig(map[string]interface{}{
+			"severityWeights": map[string]interface{}{"warning": 2, "info": 0.5},
+			"checks":          map[string]interface{}{"major": map[string]interface{}{"weight": float64(5)}},
+		}))
+		require.NoError(t, (&maxScoreFlag{registry: registry}).Set("14"))
+
+		_, err := registry.Run(nil, nil)
+		require.EqualError(t, err, "preflight score 14.5 exceeds maximum score 14")
+
+		score := registry.Score()
+		require.Equal(t, []CheckScore{
+			{Name: "major", Weight: 5, Findings: 1, Score: 10},
+			{Name: "minor", Weight: 1, Findings: 3, Score: 4.5},
+		}, score.Checks)
+		require.True(t, score.Exceeded())
+	})
+
+	t.Run("score within maximum score", func(t *testing.T) {
+		registry := newRegistry()
+		registry.SetMaxScore(3)
+
+		_, err := registry.Run(nil, nil)
+		require.NoError(t, err)
+		require.False(t, registry.Score().Exceeded())
+	})
+
+	t.Run("invalid weights", func(t *testing.T) {
+		for _, config := range []map[string]interface{}{
+			{"severityWeights": map[string]interface{}{"critical": 1}},
+			{"severityWeights": map[string]interface{}{"warning": -1}},
+			{"severityWeights": "high"},
+			{"checks": map[string]interface{}{"major": map[string]interface{}{"weight": "high"}}},
+		} {
+			require.Error(t, newRegistry().SetConfig(config))
+		}
+		require.Error(t, (&maxScoreFlag{registry: newRegistry()}).Set("-1"))
+	})
+}
@@ -0,0 +1,123 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	configSeverityLabelsKey = "severityLabels"
+)
+
+var (
+	// defaultSeverityLabels are used for severities
+	// not specified in severityLabels configuration
+	defaultSeverityLabels = SeverityLabels{
+		SeverityInfo:    "Info",
+		SeverityWarning: "Warning",
+		SeverityError:   "Error",
+	}
+)
+
+// SeverityLabels maps severities to labels shown in output
+// (e.g. to match severity taxonomy of an organization such as
+// P0/P1 or Critical/High). Severities without a label are
+// shown with their default label.
+type SeverityLabels map[Severity]string
+
+// NewSeverityLabels validates labels by severity name. Only known
+// severities may be specified and labels must be non-empty,
+// single line and unique (case insensitive) across severities.
+func NewSeverityLabels(labels map[string]string) (SeverityLabels, error) {
+	result := SeverityLabels{}
+
+	for _, key := range sortedStringKeys(labels) {
+		label := labels[key]
+		severity := Severity(key)
+		if _, found := defaultSeverityLabels[severity]; !found {
+			return nil, fmt.Errorf("unknown severity %q specified in %s", key, configSeverityLabelsKey)
+		}
+		if len(strings.TrimSpace(label)) == 0 || strings.ContainsAny(label, "\r\n") {
+			return nil, fmt.Errorf("expected %s.%s to be a non-empty single line label", configSeverityLabelsKey, key)
+		}
+		result[severity] = label
+	}
+
+	seen := map[string]Severity{}
+
+	for _, severity := range []Severity{SeverityError, SeverityWarning, SeverityInfo} {
+		key := strings.ToLower(result.Label(severity))
+		if otherSeverity, found := seen[key]; found {
+			return nil, fmt.Errorf("expected %s to be unique, label %q is used for severities %s and %s",
+				configSeverityLabelsKey, result.Label(severity), otherSeverity, severity)
+		}
+		seen[key] = severity
+	}
+
+	return result, nil
+}
+
+// Label returns label of the severity (falling back to default label)
+func (l SeverityLabels) Label(severity Severity) string {
+	if label, found := l[severity]; found {
+		return label
+	}
+	if label, found := defaultSeverityLabels[severity]; found {
+		return label
+	}
+	return string(severity)
+}
+
+// Apply returns copies of findings with SeverityLabel set
+// so that structured (e.g. JSON) output includes labels
+func (l SeverityLabels) Apply(findings []Finding) []Finding {
+	var result []Finding
+	for _, finding := range findings {
+		finding.SeverityLabel = l.Label(finding.Severity)
+		result = append(result, finding)
+	}
+	return result
+}
+
+// SeverityLabels returns configured labels of severities
+// (see SetConfig); severities that are not configured
+// are not included
+func (c *Registry) SeverityLabels() SeverityLabels {
+	return c.severityLabels
+}
+
+func parseSeverityLabels(val interface{}) (SeverityLabels, error) {
+	if val == nil {
+		return nil, nil
+	}
+
+	labels, ok := val.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected preflight config key %q to be a map", configSeverityLabelsKey)
+	}
+
+	stringLabels := map[string]string{}
+
+	for key, label := range labels {
+		stringLabel, ok := label.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected %s.%s to be a string", configSeverityLabelsKey, key)
+		}
+		stringLabels[key] = stringLabel
+	}
+
+	return NewSeverityLabels(stringLabels)
+}
+
+func sortedStringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
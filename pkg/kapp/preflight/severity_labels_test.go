@@ -0,0 +1,95 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSeverityLabels(t *testing.T) {
+	testCases := []struct {
+		name        string
+		labels      map[string]string
+		expected    SeverityLabels
+		expectedErr string
+	}{
+		{
+			name:     "no labels, defaults are used",
+			expected: SeverityLabels{},
+		},
+		{
+			name:     "custom labels",
+			labels:   map[string]string{"error": "Critical", "warning": "Medium"},
+			expected: SeverityLabels{SeverityError: "Critical", SeverityWarning: "Medium"},
+		},
+		{
+			name:        "unknown severity, error returned",
+			labels:      map[string]string{"fatal": "P0"},
+			expectedErr: `unknown severity "fatal" specified in severityLabels`,
+		},
+		{
+			name:        "empty label, error returned",
+			labels:      map[string]string{"error": " "},
+			expectedErr: "expected severityLabels.error to be a non-empty single line label",
+		},
+		{
+			name:        "duplicate labels, error returned",
+			labels:      map[string]string{"error": "High", "warning": "high"},
+			expectedErr: `expected severityLabels to be unique, label "high" is used for severities error and warning`,
+		},
+		{
+			name:        "label same as default label of another severity, error returned",
+			labels:      map[string]string{"warning": "Error"},
+			expectedErr: `expected severityLabels to be unique, label "Error" is used for severities error and warning`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			labels, err := NewSeverityLabels(tc.labels)
+			if len(tc.expectedErr) > 0 {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, labels)
+		})
+	}
+}
+
+func TestSeverityLabelsApply(t *testing.T) {
+	findings := []Finding{
+		{Check: "someCheck", Severity: SeverityError, Message: "error"},
+		{Check: "someCheck", Severity: SeverityInfo, Message: "info"},
+	}
+
+	labeled := SeverityLabels{SeverityError: "P0"}.Apply(findings)
+	require.Equal(t, "", findings[0].SeverityLabel, "expected findings to be copied")
+
+	bs, err := json.Marshal(labeled)
+	require.NoError(t, err)
+	require.JSONEq(t, `[
+		{"check": "someCheck", "severity": "error", "severityLabel": "P0", "message": "error"},
+		{"check": "someCheck", "severity": "info", "severityLabel": "Info", "message": "info"}
+	]`, string(bs))
+}
+
+func TestRegistrySeverityLabelsConfig(t *testing.T) {
+	registry := &Registry{}
+
+	require.NoError(t, registry.SetConfig(map[string]interface{}{
+		"severityLabels": map[string]interface{}{"error": "P0"},
+	}))
+	require.Equal(t, SeverityLabels{SeverityError: "P0"}, registry.SeverityLabels())
+
+	require.EqualError(t, registry.SetConfig(map[string]interface{}{
+		"severityLabels": map[string]interface{}{"error": 0},
+	}), "expected severityLabels.error to be a string")
+	require.EqualError(t, registry.SetConfig(map[string]interface{}{
+		"severityLabels": []interface{}{"P0"},
+	}), `expected preflight config key "severityLabels" to be a map`)
+}
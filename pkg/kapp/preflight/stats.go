@@ -0,0 +1,190 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CheckStats describes a single preflight check execution
+type CheckStats struct {
+	Name string
+	// Skipped is true if check did not run
+	// because its runs-if conditions were not met
+	Skipped    bool
+	SkipReason string
+	Duration   time.Duration
+	// APICalls is only populated when Registry
+	// is configured with an APICallCounter
+	APICalls APICalls
+}
+
+// APICall identifies a type of Kubernetes API request
+type APICall struct {
+	Verb string
+	// Resource may include subresource (e.g. pods/log)
+	Resource string
+}
+
+func (c APICall) String() string {
+	return fmt.Sprintf("%s %s", c.Verb, c.Resource)
+}
+
+// APICalls maps API requests to number of times they were made
+type APICalls map[APICall]int
+
+// Total returns number of all API requests
+func (c APICalls) Total() int {
+	var total int
+	for _, count := range c {
+		total += count
+	}
+	return total
+}
+
+// Sorted returns API request types sorted by verb and resource
+func (c APICalls) Sorted() []APICall {
+	var result []APICall
+	for call := range c {
+		result = append(result, call)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].String() < result[j].String()
+	})
+	return result
+}
+
+type checkNameCtxKey struct{}
+
+// ContextWithCheckName returns a context that attributes
+// API requests made with it to the named preflight check
+func ContextWithCheckName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, checkNameCtxKey{}, name)
+}
+
+// CheckNameFromContext returns name of the preflight
+// check that is running with the context, if any
+func CheckNameFromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(checkNameCtxKey{}).(string)
+	return name, ok
+}
+
+// APICallCounter counts Kubernetes API requests per preflight check.
+// Requests are attributed to a check based on their context
+// (see ContextWithCheckName); other requests are not counted.
+type APICallCounter struct {
+	lock   sync.Mutex
+	counts map[string]APICalls
+}
+
+func NewAPICallCounter() *APICallCounter {
+	return &APICallCounter{counts: map[string]APICalls{}}
+}
+
+// WrapTransport is meant to be used as a rest.Config transport wrapper
+func (c *APICallCounter) WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	return countingRoundTripper{counter: c, delegate: rt}
+}
+
+// Take returns API calls attributed to the named check
+// and resets them so that consecutive runs are counted separately
+func (c *APICallCounter) Take(name string) APICalls {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	calls := c.counts[name]
+	delete(c.counts, name)
+
+	if calls == nil {
+		return APICalls{}
+	}
+	return calls
+}
+
+func (c *APICallCounter) record(name string, call APICall) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.counts[name] == nil {
+		c.counts[name] = APICalls{}
+	}
+	c.counts[name][call]++
+}
+
+type countingRoundTripper struct {
+	counter  *APICallCounter
+	delegate http.RoundTripper
+}
+
+func (rt countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if name, ok := CheckNameFromContext(req.Context()); ok {
+		rt.counter.record(name, newAPICall(req.Method, req.URL))
+	}
+	return rt.delegate.RoundTrip(req)
+}
+
+// newAPICall determines verb and resource of a request based on
+// Kubernetes REST path conventions, for example:
+// /api/v1/namespaces/{ns}/pods/{name}/log or /apis/{group}/{version}/nodes
+func newAPICall(method string, u *url.URL) APICall {
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+
+	switch {
+	case len(segments) >= 2 && segments[0] == "api":
+		segments = segments[2:]
+	case len(segments) >= 3 && segments[0] == "apis":
+		segments = segments[3:]
+	default:
+		return APICall{Verb: strings.ToLower(method), Resource: "discovery"}
+	}
+
+	if len(segments) == 0 {
+		return APICall{Verb: strings.ToLower(method), Resource: "discovery"}
+	}
+	if len(segments) >= 3 && segments[0] == "namespaces" {
+		segments = segments[2:]
+	}
+
+	resource := segments[0]
+	hasName := len(segments) > 1
+	if len(segments) > 2 {
+		resource += "/" + segments[2]
+	}
+
+	return APICall{Verb: apiCallVerb(method, hasName, u.Query().Get("watch") == "true"), Resource: resource}
+}
+
+func apiCallVerb(method string, hasName, watch bool) string {
+	switch method {
+	case http.MethodGet:
+		switch {
+		case watch:
+			return "watch"
+		case hasName:
+			return "get"
+		default:
+			return "list"
+		}
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut:
+		return "update"
+	case http.MethodPatch:
+		return "patch"
+	case http.MethodDelete:
+		if hasName {
+			return "delete"
+		}
+		return "deletecollection"
+	default:
+		return strings.ToLower(method)
+	}
+}
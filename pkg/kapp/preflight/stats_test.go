@@ -0,0 +1,75 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+)
+
+type noopRoundTripper struct{}
+
+func (noopRoundTripper) RoundTrip(_ *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusOK}, nil
+}
+
+func TestRegistryStatsAPICalls(t *testing.T) {
+	counter := NewAPICallCounter()
+	transport := counter.WrapTransport(noopRoundTripper{})
+
+	request := func(ctx context.Context, method, url string) {
+		req, err := http.NewRequestWithContext(ctx, method, url, nil)
+		require.NoError(t, err)
+		_, err = transport.RoundTrip(req)
+		require.NoError(t, err)
+	}
+
+	registry := NewRegistry(map[string]Check{
+		"apiCheck": NewCheck(func(ctx context.Context, _ *diffgraph.ChangeGraph) error {
+			request(ctx, http.MethodGet, "https://cluster/api/v1/nodes")
+			request(ctx, http.MethodGet, "https://cluster/api/v1/nodes")
+			request(ctx, http.MethodGet, "https://cluster/api/v1/namespaces/default/services/app")
+			request(ctx, http.MethodGet, "https://cluster/api/v1/namespaces/default/pods/app/log")
+			request(ctx, http.MethodPatch, "https://cluster/apis/apps/v1/namespaces/default/deployments/app?dryRun=All")
+			request(ctx, http.MethodGet, "https://cluster/apis/apps/v1/deployments?watch=true")
+			request(ctx, http.MethodGet, "https://cluster/apis")
+			return nil
+		}, true),
+		"quietCheck": NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error {
+			// Not attributed to any check
+			request(context.Background(), http.MethodGet, "https://cluster/api/v1/nodes")
+			return nil
+		}, true),
+	})
+	registry.SetAPICallCounter(counter)
+
+	_, err := registry.Run(context.Background(), nil)
+	require.NoError(t, err)
+
+	stats := registry.Stats()
+	require.Len(t, stats, 2)
+
+	require.Equal(t, "apiCheck", stats[0].Name)
+	require.Equal(t, APICalls{
+		{Verb: "list", Resource: "nodes"}:        2,
+		{Verb: "get", Resource: "services"}:      1,
+		{Verb: "get", Resource: "pods/log"}:      1,
+		{Verb: "patch", Resource: "deployments"}: 1,
+		{Verb: "watch", Resource: "deployments"}: 1,
+		{Verb: "get", Resource: "discovery"}:     1,
+	}, stats[0].APICalls)
+	require.Equal(t, 7, stats[0].APICalls.Total())
+
+	require.Equal(t, "quietCheck", stats[1].Name)
+	require.Equal(t, APICalls{}, stats[1].APICalls)
+
+	// Consecutive runs are counted separately
+	_, err = registry.Run(context.Background(), nil)
+	require.NoError(t, err)
+	require.Equal(t, 7, registry.Stats()[0].APICalls.Total())
+}
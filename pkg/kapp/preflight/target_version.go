@@ -0,0 +1,105 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+const (
+	preflightTargetVersionFlag = "preflight-target-version"
+)
+
+var (
+	kubernetesVersionRegexp = regexp.MustCompile(`^v?(\d+)\.(\d+)(\.\d+)?([-+].*)?$`)
+)
+
+// KubernetesVersion is a major.minor version of Kubernetes
+type KubernetesVersion struct {
+	Major int
+	Minor int
+}
+
+// ParseKubernetesVersion parses versions such as 1.29, v1.29.3 or v1.29.3-gke.1
+// (patch version and suffixes are ignored)
+func ParseKubernetesVersion(s string) (KubernetesVersion, error) {
+	matches := kubernetesVersionRegexp.FindStringSubmatch(s)
+	if matches == nil {
+		return KubernetesVersion{}, fmt.Errorf("Expected Kubernetes version to have format 'major.minor' (e.g. 1.29), but was '%s'", s)
+	}
+
+	major, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return KubernetesVersion{}, err
+	}
+	minor, err := strconv.Atoi(matches[2])
+	if err != nil {
+		return KubernetesVersion{}, err
+	}
+
+	return KubernetesVersion{Major: major, Minor: minor}, nil
+}
+
+func (v KubernetesVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// AtLeast returns true if v is the same or newer than other
+func (v KubernetesVersion) AtLeast(other KubernetesVersion) bool {
+	if v.Major != other.Major {
+		return v.Major > other.Major
+	}
+	return v.Minor >= other.Minor
+}
+
+// VersionAwareCheck is a Check whose results depend on the
+// Kubernetes version of the cluster and that can simulate
+// running against a different (e.g. upgrade target) version
+type VersionAwareCheck interface {
+	Check
+	// SetTargetVersion overrides the discovered Kubernetes version
+	SetTargetVersion(KubernetesVersion)
+}
+
+// SetTargetVersion makes version aware checks evaluate the change
+// as if the cluster was running the target version. Version dependent
+// checks that are not version aware are skipped.
+func (c *Registry) SetTargetVersion(version KubernetesVersion) {
+	c.targetVersion = &version
+}
+
+// targetVersionSkipReason returns true if the check cannot
+// simulate running against the target version
+func (c *Registry) targetVersionSkipReason(name string) (string, bool) {
+	if c.targetVersion == nil || !c.opts[name].VersionDependent {
+		return "", false
+	}
+	if _, ok := c.known[name].(VersionAwareCheck); ok {
+		return "", false
+	}
+	return fmt.Sprintf("cannot simulate Kubernetes version %s", c.targetVersion), true
+}
+
+// targetVersionFlag is a pflag.Value that sets
+// target Kubernetes version of the registry
+type targetVersionFlag struct {
+	registry *Registry
+	value    string
+}
+
+func (f *targetVersionFlag) String() string { return f.value }
+func (f *targetVersionFlag) Type() string   { return "string" }
+
+func (f *targetVersionFlag) Set(value string) error {
+	version, err := ParseKubernetesVersion(value)
+	if err != nil {
+		return err
+	}
+
+	f.registry.SetTargetVersion(version)
+	f.value = value
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/logger"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestParseKubernetesVersion(t *testing.T) {
+	for input, expected := range map[string]KubernetesVersion{
+		"1.29":          {Major: 1, Minor: 29},
+		"v1.30.2":       {Major: 1, Minor: 30},
+		"v1.28.5-gke.1": {Major: 1, Minor: 28},
+	} {
+		version, err := ParseKubernetesVersion(input)
+		require.NoError(t, err)
+		require.Equal(t, expected, version)
+	}
+
+	for _, input := range []string{"", "1", "latest", "1.x"} {
+		_, err := ParseKubernetesVersion(input)
+		require.Error(t, err, input)
+	}
+
+	require.True(t, KubernetesVersion{Major: 1, Minor: 30}.AtLeast(KubernetesVersion{Major: 1, Minor: 29}))
+	require.False(t, KubernetesVersion{Major: 1, Minor: 9}.AtLeast(KubernetesVersion{Major: 1, Minor: 29}))
+}
+
+type versionAwareCheck struct {
+	Check
+	version *KubernetesVersion
+}
+
+func (c *versionAwareCheck) SetTargetVersion(version KubernetesVersion) {
+	c.version = &version
+}
+
+func TestRegistryTargetVersion(t *testing.T) {
+	graph, err := diffgraph.NewChangeGraph(nil, nil, nil, logger.NewTODOLogger())
+	require.NoError(t, err)
+
+	var ran []string
+	newCheck := func(name string) Check {
+		return NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error {
+			ran = append(ran, name)
+			return nil
+		}, true)
+	}
+
+	aware := &versionAwareCheck{Check: newCheck("aware")}
+
+	registry := &Registry{}
+	registry.AddCheck("independent", newCheck("independent"))
+	registry.AddCheckWithOpts("dependent", newCheck("dependent"), CheckOpts{VersionDependent: true})
+	registry.AddCheckWithOpts("aware", aware, CheckOpts{VersionDependent: true})
+
+	_, err = registry.Run(context.Background(), graph)
+	require.NoError(t, err)
+	require.Equal(t, []string{"aware", "dependent", "independent"}, ran)
+	require.Nil(t, aware.version)
+
+	ran = nil
+	flag := &targetVersionFlag{registry: registry}
+	require.NoError(t, flag.Set("v1.31.0"))
+
+	_, err = registry.Run(context.Background(), graph)
+	require.NoError(t, err)
+	require.Equal(t, []string{"aware", "independent"}, ran)
+	require.Equal(t, &KubernetesVersion{Major: 1, Minor: 31}, aware.version)
+
+	var skipReasons []string
+	for _, stats := range registry.Stats() {
+		if stats.Skipped {
+			skipReasons = append(skipReasons, stats.Name+": "+stats.SkipReason)
+		}
+	}
+	require.Equal(t, []string{"dependent: cannot simulate Kubernetes version 1.31"}, skipReasons)
+}
+
+func TestKubernetesVersionServesAPI(t *testing.T) {
+	v122 := KubernetesVersion{Major: 1, Minor: 22}
+	v121 := KubernetesVersion{Major: 1, Minor: 21}
+
+	ingressesV1beta1 := schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1beta1", Resource: "ingresses"}
+	ingressesV1 := schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"}
+	scaleV1beta2 := schema.GroupVersionResource{Group: "apps", Version: "v1beta2", Resource: "deployments/scale"}
+
+	removedIn, found := APIRemovedIn(ingressesV1beta1)
+	require.True(t, found)
+	require.Equal(t, v122, removedIn)
+
+	require.True(t, v121.ServesAPI(ingressesV1beta1))
+	require.False(t, v122.ServesAPI(ingressesV1beta1))
+	require.True(t, v122.ServesAPI(ingressesV1))
+	require.False(t, v122.ServesAPI(scaleV1beta2))
+	require.True(t, v122.ServesAPI(schema.GroupVersionResource{Group: "example.com", Version: "v1beta1", Resource: "widgets"}))
+}
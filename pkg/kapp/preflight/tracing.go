@@ -0,0 +1,117 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"context"
+)
+
+const (
+	// tracingSpanName is the name of the span
+	// that is a parent of spans of each check
+	tracingSpanName = "preflight"
+
+	TracingAttrPhase      = "preflight.phase"
+	TracingAttrCheck      = "preflight.check"
+	TracingAttrStatus     = "preflight.status"
+	TracingAttrSkipReason = "preflight.skip_reason"
+	TracingAttrDurationMs = "preflight.duration_ms"
+	TracingAttrFindings   = "preflight.findings"
+	TracingAttrChecks     = "preflight.checks"
+)
+
+// Outcomes of checks (values of TracingAttrStatus
+// attribute of spans and of AuditResult.Status)
+const (
+	CheckStatusPassed  = "passed"
+	CheckStatusFailed  = "failed"
+	CheckStatusSkipped = "skipped"
+	CheckStatusError   = "error"
+)
+
+// Tracer starts spans of preflight checks. It mirrors a subset of
+// OpenTelemetry's trace.Tracer so that embedders can export spans
+// to their tracing pipelines via a small adapter without kapp
+// depending on OpenTelemetry.
+type Tracer interface {
+	// Start starts a span as a child of span in the context (if any)
+	// and returns a context containing the started span
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a single traced operation started by Tracer
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	// RecordError marks span as failed
+	RecordError(err error)
+	End()
+}
+
+// Attribute is a key value pair attached to a Span.
+// Value is a string, bool, int64 or float64.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// NoopTracer is a Tracer that starts spans that are not recorded
+type NoopTracer struct{}
+
+var _ Tracer = NoopTracer{}
+
+func (NoopTracer) Start(ctx context.Context, _ string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+func (noopSpan) RecordError(error)          {}
+func (noopSpan) End()                       {}
+
+// SetTracer configures the registry to start a "preflight" span
+// for each Run with a child span for each check (including
+// skipped ones) carrying their status, duration and number of
+// findings. By default a NoopTracer is used.
+func (c *Registry) SetTracer(tracer Tracer) {
+	c.tracer = tracer
+}
+
+func (c *Registry) tracerOrNoop() Tracer {
+	if c.tracer == nil {
+		return NoopTracer{}
+	}
+	return c.tracer
+}
+
+// traceSkippedCheck records a span of a check that did not run
+func (c *Registry) traceSkippedCheck(ctx context.Context, run *checkRun) {
+	_, span := c.tracerOrNoop().Start(ctx, run.name)
+	span.SetAttributes(
+		Attribute{Key: TracingAttrCheck, Value: run.name},
+		Attribute{Key: TracingAttrStatus, Value: CheckStatusSkipped},
+		Attribute{Key: TracingAttrSkipReason, Value: run.stats.SkipReason},
+	)
+	span.End()
+}
+
+// endCheckSpan records outcome of a check run in its span
+func endCheckSpan(span Span, run *checkRun) {
+	status := CheckStatusPassed
+	if CountAtLeast(run.findings, SeverityError) > 0 {
+		status = CheckStatusFailed
+	}
+	if run.err != nil {
+		status = CheckStatusError
+		span.RecordError(run.err)
+	}
+
+	span.SetAttributes(
+		Attribute{Key: TracingAttrCheck, Value: run.name},
+		Attribute{Key: TracingAttrStatus, Value: status},
+		Attribute{Key: TracingAttrDurationMs, Value: run.stats.Duration.Milliseconds()},
+		Attribute{Key: TracingAttrFindings, Value: int64(len(run.findings))},
+	)
+	span.End()
+}
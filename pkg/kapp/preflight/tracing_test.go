@@ -0,0 +1,126 @@
+// Copyright 2024 The Carvel Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package preflight
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/diffgraph"
+	"github.com/vmware-tanzu/carvel-kapp/pkg/kapp/logger"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type recordedSpan struct {
+	Name   string
+	Parent string
+	Attrs  map[string]interface{}
+	Err    error
+	Ended  bool
+}
+
+type spanNameCtxKey struct{}
+
+// recordingTracer records spans along with names of their parents
+type recordingTracer struct {
+	lock  sync.Mutex
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	parent, _ := ctx.Value(spanNameCtxKey{}).(string)
+	span := &recordedSpan{Name: name, Parent: parent, Attrs: map[string]interface{}{}}
+	t.spans = append(t.spans, span)
+
+	return context.WithValue(ctx, spanNameCtxKey{}, name), &recordingSpan{tracer: t, span: span}
+}
+
+type recordingSpan struct {
+	tracer *recordingTracer
+	span   *recordedSpan
+}
+
+func (s *recordingSpan) SetAttributes(attrs ...Attribute) {
+	s.tracer.lock.Lock()
+	defer s.tracer.lock.Unlock()
+	for _, attr := range attrs {
+		s.span.Attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordingSpan) RecordError(err error) { s.span.Err = err }
+func (s *recordingSpan) End()                  { s.span.Ended = true }
+
+func TestRegistryTracer(t *testing.T) {
+	newCheck := func(err error) Check {
+		return NewCheck(func(_ context.Context, _ *diffgraph.ChangeGraph) error { return err }, true)
+	}
+
+	registry := &Registry{}
+	registry.AddCheck("passing", newCheck(errors.Join(NewWarning(nil, "warning"))))
+	registry.AddCheckWithOpts("failing", newCheck(errors.Join(NewError(nil, "error"))),
+		CheckOpts{Concurrency: ConcurrencyClassParallel})
+	registry.AddCheck("erroring", newCheck(errors.New("cannot run")))
+	registry.AddCheckWithOpts("skipped", newCheck(nil), CheckOpts{RunsIf: []schema.GroupVersionKind{{Kind: "Service"}}})
+
+	tracer := &recordingTracer{}
+	registry.SetTracer(tracer)
+
+	graph, err := diffgraph.NewChangeGraph(nil, nil, nil, logger.NewTODOLogger())
+	require.NoError(t, err)
+
+	_, err = registry.Run(context.Background(), graph)
+	require.Error(t, err)
+
+	spans := map[string]*recordedSpan{}
+	for _, span := range tracer.spans {
+		require.True(t, span.Ended, "span %s not ended", span.Name)
+		// Durations are not deterministic
+		delete(span.Attrs, TracingAttrDurationMs)
+		spans[span.Name] = span
+	}
+
+	var names []string
+	for name := range spans {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	require.Equal(t, []string{"erroring", "failing", "passing", "preflight", "skipped"}, names)
+
+	require.Equal(t, "", spans["preflight"].Parent)
+	require.Equal(t, err, spans["preflight"].Err)
+	require.Equal(t, map[string]interface{}{
+		TracingAttrPhase:    "preApply",
+		TracingAttrStatus:   CheckStatusFailed,
+		TracingAttrChecks:   int64(4),
+		TracingAttrFindings: int64(2),
+	}, spans["preflight"].Attrs)
+
+	require.Equal(t, "preflight", spans["passing"].Parent)
+	require.Equal(t, map[string]interface{}{
+		TracingAttrCheck:    "passing",
+		TracingAttrStatus:   CheckStatusPassed,
+		TracingAttrFindings: int64(1),
+	}, spans["passing"].Attrs)
+
+	require.Equal(t, "preflight", spans["failing"].Parent)
+	require.Equal(t, CheckStatusFailed, spans["failing"].Attrs[TracingAttrStatus])
+
+	require.EqualError(t, spans["erroring"].Err, "cannot run")
+	require.Equal(t, CheckStatusError, spans["erroring"].Attrs[TracingAttrStatus])
+
+	require.Equal(t, "preflight", spans["skipped"].Parent)
+	require.Equal(t, map[string]interface{}{
+		TracingAttrCheck:      "skipped",
+		TracingAttrStatus:     CheckStatusSkipped,
+		TracingAttrSkipReason: "no resources of kind Service in the change",
+	}, spans["skipped"].Attrs)
+}